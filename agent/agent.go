@@ -99,6 +99,7 @@ type AgentStatus struct {
 	Analyzers      map[string]AnalyzerConnStatus
 	TopologyProbes []string
 	FlowProbes     []string
+	FlowTables     flow.TableAllocatorStatus
 }
 
 // GetStatus returns the status of an agent
@@ -122,6 +123,7 @@ func (a *Agent) GetStatus() interface{} {
 		Analyzers:      analyzers,
 		TopologyProbes: a.topologyProbeBundle.ActiveProbes(),
 		FlowProbes:     a.flowProbeBundle.ActiveProbes(),
+		FlowTables:     a.flowTableAllocator.Status(),
 	}
 }
 
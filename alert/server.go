@@ -27,16 +27,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"net/url"
 	"os/exec"
 	"reflect"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	api "github.com/skydive-project/skydive/api/server"
 	"github.com/skydive-project/skydive/api/types"
 	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/etcd"
+	"github.com/skydive-project/skydive/flow/storage"
 	shttp "github.com/skydive-project/skydive/http"
 	"github.com/skydive-project/skydive/js"
 	"github.com/skydive-project/skydive/logging"
@@ -52,16 +56,41 @@ const (
 const (
 	actionWebHook = 1 + iota
 	actionScript
+	actionEmail
+	actionSyslog
+)
+
+// States of an alert. An alert whose condition is met is pending until its
+// For duration elapsed, it then fires until its condition is no longer met.
+const (
+	StatePending  = "pending"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
 )
 
 // GreminAlert represents an alert that will be triggered if its associated
 // Gremlin expression returns a non empty result.
 type GremlinAlert struct {
+	common.RWMutex
 	*types.Alert
 	graph             *graph.Graph
 	lastEval          interface{}
+	lastKey           interface{}
+	state             string
+	muted             bool
+	mutedFrom         string
+	pendingSince      time.Time
+	forDuration       time.Duration
+	forTimer          *time.Timer
+	reevaluate        func()
 	kind              int
 	data              string
+	webhookTimeout    time.Duration
+	webhookRetries    int
+	webhookBackoff    time.Duration
+	mailer            *mailer
+	syslogger         *syslogger
+	template          *template.Template
 	traversalSequence *traversal.GremlinTraversalSequence
 	gremlinParser     *traversal.GremlinTraversalParser
 }
@@ -71,7 +100,11 @@ func (ga *GremlinAlert) evaluate(server *api.Server, vm *js.JSRE, lockGraph bool
 	// converting to JavaScript
 	if ga.traversalSequence != nil {
 		result, err := ga.traversalSequence.Exec(ga.graph, lockGraph)
-		if err != nil {
+		switch err {
+		case nil:
+		case traversal.ErrQueryTimeout, traversal.ErrTooManyElements:
+			return nil, fmt.Errorf("Gremlin expression '%s' of alert %s aborted: %s", ga.Expression, ga.UUID, err)
+		default:
 			return nil, err
 		}
 
@@ -124,20 +157,70 @@ func (ga *GremlinAlert) evaluate(server *api.Server, vm *js.JSRE, lockGraph bool
 	return nil, nil
 }
 
-func (ga *GremlinAlert) trigger(payload []byte) error {
-	switch ga.kind {
-	case actionWebHook:
-		client := &http.Client{}
+// render returns the body of the notification of the message, rendered with
+// the template of the alert if any, the JSON encoded message otherwise
+func (ga *GremlinAlert) render(msg *Message) ([]byte, error) {
+	if ga.template == nil {
+		return json.Marshal(msg)
+	}
 
-		req, err := http.NewRequest("POST", ga.data, bytes.NewReader(payload))
-		if err != nil {
-			return fmt.Errorf("Failed to post alert to %s: %s", ga.data, err.Error())
+	data := &types.AlertTemplateData{
+		Alert:         ga.Alert.Redacted(),
+		Time:          msg.Timestamp,
+		State:         msg.State,
+		PreviousState: msg.PreviousState,
+		ReasonData:    msg.ReasonData,
+	}
+
+	// give the template the decoded reason data so that it can walk through it
+	if raw, ok := msg.ReasonData.(json.RawMessage); ok {
+		var reasonData interface{}
+		if err := json.Unmarshal(raw, &reasonData); err != nil {
+			return nil, err
 		}
+		data.ReasonData = reasonData
+	}
 
-		req.Close = true
-		_, err = client.Do(req)
-		if err != nil {
-			return fmt.Errorf("Error while posting alert to %s: %s", ga.data, err.Error())
+	var buf bytes.Buffer
+	if err := ga.template.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newDelivery returns the webhook request of a notification
+func (ga *GremlinAlert) newDelivery(payload []byte) *delivery {
+	headers := make(map[string]string)
+	if webhook := ga.Webhook; webhook != nil {
+		for name, value := range webhook.Headers {
+			headers[name] = value
+		}
+		if webhook.Secret != "" {
+			headers[SignatureHeader] = sign(webhook.Secret, payload)
+		}
+	}
+
+	return &delivery{
+		AlertUUID: ga.UUID,
+		URL:       ga.data,
+		Headers:   headers,
+		Payload:   payload,
+		Timeout:   ga.webhookTimeout,
+	}
+}
+
+// trigger sends the notification through the action of the alert. The
+// webhook deliveries failing after their retries are added to the queue.
+func (ga *GremlinAlert) trigger(msg *Message, payload []byte, queue *deliveryQueue) error {
+	switch ga.kind {
+	case actionWebHook:
+		d := ga.newDelivery(payload)
+		if err := d.sendWithRetries(ga.webhookRetries, ga.webhookBackoff); err != nil {
+			if queue == nil {
+				return err
+			}
+			queue.push(d)
+			return fmt.Errorf("%s, queued for a later delivery", err)
 		}
 	case actionScript:
 		logging.GetLogger().Debugf("Executing command '%s'", ga.data)
@@ -160,20 +243,205 @@ func (ga *GremlinAlert) trigger(payload []byte) error {
 
 		logging.GetLogger().Infof("Command successfully executed '%s': %s", cmd.Path, output)
 		stdin.Close()
+	case actionEmail:
+		name := ga.Name
+		if name == "" {
+			name = ga.UUID
+		}
+		return ga.mailer.send(fmt.Sprintf("Skydive alert %s %s", name, msg.State), payload)
+	case actionSyslog:
+		return ga.syslogger.send(payload)
 	}
 
 	return nil
 }
 
+// evalKey returns a comparison-safe representation of the result of an
+// evaluation. The graph elements of a Gremlin result are replaced by their
+// sorted identifiers, as the result holds the context of its execution and
+// the backends return the elements in no particular order.
+func evalKey(data interface{}) interface{} {
+	step, ok := data.(traversal.GraphTraversalStep)
+	if !ok {
+		return data
+	}
+
+	var ids []string
+	var values []interface{}
+	for _, value := range step.Values() {
+		switch value := value.(type) {
+		case *graph.Node:
+			ids = append(ids, string(value.ID))
+		case *graph.Edge:
+			ids = append(ids, string(value.ID))
+		default:
+			values = append(values, value)
+		}
+	}
+	sort.Strings(ids)
+
+	return []interface{}{ids, values}
+}
+
+// update moves the alert to its next state according to the result of its
+// evaluation and returns the message to notify, if any. The key of the
+// result, given by evalKey, is compared to the one of the previous result.
+// The transitions muted by a silence are notified again until they are sent.
+func (ga *GremlinAlert) update(data interface{}, key interface{}, now time.Time) *Message {
+	ga.Lock()
+	defer ga.Unlock()
+
+	// the messages are relative to the last notified state
+	previous := ga.state
+	if ga.muted {
+		previous = ga.mutedFrom
+	}
+
+	if data == nil {
+		// Gremlin query returned no datas, or Javascript expression was unsuccessful.
+		// Reset the lastEval to be able to trigger the alert next time
+		data, ga.lastEval, ga.lastKey = ga.lastEval, nil, nil
+
+		switch ga.state {
+		case StateFiring:
+			if previous != StateFiring {
+				// the firing was muted and never notified, nothing to resolve
+				ga.state, ga.muted = previous, false
+				return nil
+			}
+			ga.state, ga.muted = StateResolved, false
+			return &Message{UUID: ga.UUID, Timestamp: now, State: StateResolved, PreviousState: previous, ReasonData: data}
+		case StatePending:
+			// the condition cleared before the alert fired, nothing to notify
+			ga.stopTimer()
+			ga.state = ""
+		}
+
+		if previous == StateFiring {
+			// the resolution was muted, the silence may have ended since
+			ga.state, ga.muted = StateResolved, false
+			return &Message{UUID: ga.UUID, Timestamp: now, State: StateResolved, PreviousState: previous, ReasonData: data, renotify: true}
+		}
+		return nil
+	}
+
+	var renotify bool
+	switch ga.state {
+	case StateFiring:
+		// Gremlin query/Javascript expression returned datas.
+		// Alert must but sent if those datas differ from the one that trigger
+		// the previous alert.
+		if reflect.DeepEqual(key, ga.lastKey) {
+			if !ga.muted {
+				return nil
+			}
+			// the firing was muted, the silence may have ended since
+			renotify = true
+		}
+	case StatePending:
+		if now.Sub(ga.pendingSince) < ga.forDuration {
+			ga.lastEval, ga.lastKey = data, key
+			return nil
+		}
+		ga.stopTimer()
+	default:
+		if ga.forDuration > 0 {
+			ga.state = StatePending
+			ga.pendingSince = now
+			ga.lastEval, ga.lastKey = data, key
+
+			// alerts triggered by graph events may not be evaluated again
+			// once the For duration elapsed
+			if ga.reevaluate != nil {
+				ga.forTimer = time.AfterFunc(ga.forDuration, ga.reevaluate)
+			}
+			return nil
+		}
+	}
+
+	ga.state, ga.muted = StateFiring, false
+	ga.lastEval, ga.lastKey = data, key
+	return &Message{UUID: ga.UUID, Timestamp: now, State: StateFiring, PreviousState: previous, ReasonData: data, renotify: renotify}
+}
+
+// mute records that the notification of the message was muted by a silence,
+// the alert being still considered in the state it was last notified in
+func (ga *GremlinAlert) mute(msg *Message) {
+	ga.Lock()
+	defer ga.Unlock()
+
+	// the alert may have moved to another state in the meantime
+	if ga.state != msg.State {
+		return
+	}
+
+	ga.muted, ga.mutedFrom = true, msg.PreviousState
+	if ga.mutedFrom == StatePending {
+		// pending alerts are not notified
+		ga.mutedFrom = ""
+	}
+}
+
+func (ga *GremlinAlert) stopTimer() {
+	if ga.forTimer != nil {
+		ga.forTimer.Stop()
+		ga.forTimer = nil
+	}
+}
+
+func (ga *GremlinAlert) stop() {
+	ga.Lock()
+	ga.reevaluate = nil
+	ga.stopTimer()
+	ga.Unlock()
+}
+
 // NewGremlinAlert returns a new gremlin based alert
 func NewGremlinAlert(alert *types.Alert, g *graph.Graph, p *traversal.GremlinTraversalParser) (*GremlinAlert, error) {
 	ts, _ := p.Parse(strings.NewReader(alert.Expression))
+	if ts != nil {
+		var err error
+		if ts, err = ts.Bind(alert.Bindings); err != nil {
+			return nil, fmt.Errorf("Invalid bindings for alert %s: %s", alert.UUID, err)
+		}
+	}
+
+	var forDuration time.Duration
+	if alert.For != "" {
+		var err error
+		if forDuration, err = time.ParseDuration(alert.For); err != nil {
+			return nil, fmt.Errorf("Invalid For duration for alert %s: %s", alert.UUID, err)
+		}
+	}
 
 	ga := &GremlinAlert{
 		Alert:             alert,
+		forDuration:       forDuration,
 		traversalSequence: ts,
 		gremlinParser:     p,
 		graph:             g,
+		webhookTimeout:    time.Duration(config.GetInt("analyzer.alert.webhook_timeout")) * time.Second,
+		webhookBackoff:    defaultBackoff,
+	}
+
+	if webhook := alert.Webhook; webhook != nil {
+		var err error
+		if webhook.Timeout != "" {
+			if ga.webhookTimeout, err = time.ParseDuration(webhook.Timeout); err != nil {
+				return nil, fmt.Errorf("Invalid webhook timeout for alert %s: %s", alert.UUID, err)
+			}
+		}
+		if webhook.Backoff != "" {
+			if ga.webhookBackoff, err = time.ParseDuration(webhook.Backoff); err != nil {
+				return nil, fmt.Errorf("Invalid webhook backoff for alert %s: %s", alert.UUID, err)
+			}
+		}
+		ga.webhookRetries = webhook.Retries
+	}
+
+	var err error
+	if ga.template, err = alert.ParseTemplate(); err != nil {
+		return nil, fmt.Errorf("Invalid template for alert %s: %s", alert.UUID, err)
 	}
 
 	if strings.HasPrefix(alert.Action, "http://") || strings.HasPrefix(alert.Action, "https://") {
@@ -182,6 +450,22 @@ func NewGremlinAlert(alert *types.Alert, g *graph.Graph, p *traversal.GremlinTra
 	} else if strings.HasPrefix(alert.Action, "file://") {
 		ga.kind = actionScript
 		ga.data = alert.Action[7:]
+	} else if strings.HasPrefix(alert.Action, "smtp://") || strings.HasPrefix(alert.Action, "syslog://") {
+		u, err := url.Parse(alert.Action)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid action for alert %s: %s", alert.UUID, err)
+		}
+
+		if u.Scheme == "smtp" {
+			ga.kind = actionEmail
+			ga.mailer, err = newMailer(u)
+		} else {
+			ga.kind = actionSyslog
+			ga.syslogger, err = newSyslogger(u)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid action for alert %s: %s", alert.UUID, err)
+		}
 	}
 
 	return ga, nil
@@ -193,43 +477,99 @@ func NewGremlinAlert(alert *types.Alert, g *graph.Graph, p *traversal.GremlinTra
 type Server struct {
 	common.RWMutex
 	*etcd.MasterElector
-	Graph         *graph.Graph
-	Pool          shttp.WSStructSpeakerPool
-	AlertHandler  api.Handler
-	apiServer     *api.Server
-	watcher       api.StoppableWatcher
-	graphAlerts   map[string]*GremlinAlert
-	alertTimers   map[string]chan bool
-	gremlinParser *traversal.GremlinTraversalParser
-	jsre          *js.JSRE
+	Graph          *graph.Graph
+	Pool           shttp.WSStructSpeakerPool
+	AlertHandler   api.Handler
+	SilenceHandler api.Handler
+	apiServer      *api.Server
+	watcher        api.StoppableWatcher
+	silenceWatcher api.StoppableWatcher
+	graphAlerts    map[string]*GremlinAlert
+	alertTimers    map[string]chan bool
+	silences       map[string]*silence
+	silencesLock   common.RWMutex
+	gremlinParser  *traversal.GremlinTraversalParser
+	jsre           *js.JSRE
+	storage        storage.Storage
+	queue          *deliveryQueue
 }
 
 // Message describes a websocket message that is sent by the alerting
-// server when an alert was triggered
+// server when an alert fired or was resolved. State is the new state of the
+// alert, PreviousState the one it transitioned from. renotify is set when
+// the transition was muted by a silence and is notified again.
 type Message struct {
-	UUID       string
-	Timestamp  time.Time
-	ReasonData interface{}
+	UUID          string
+	Timestamp     time.Time
+	State         string
+	PreviousState string `json:",omitempty"`
+	ReasonData    interface{}
+	renotify      bool
 }
 
-func (a *Server) triggerAlert(al *GremlinAlert, data interface{}) error {
-	msg := Message{
-		UUID:       al.UUID,
-		Timestamp:  time.Now().UTC(),
-		ReasonData: data,
+// recordEvent stores the event in the alert history, if a storage is configured
+func (a *Server) recordEvent(event *types.AlertEvent) {
+	if a.storage == nil {
+		return
 	}
 
-	logging.GetLogger().Infof("Triggering alert %s of type %s", al.UUID, al.Action)
+	if err := a.storage.StoreAlertEvent(event); err != nil {
+		logging.GetLogger().Errorf("Failed to record alert %s: %s", event.AlertUUID, err.Error())
+	}
+}
 
-	payload, err := json.Marshal(msg)
+func (a *Server) triggerAlert(al *GremlinAlert, msg *Message, lockGraph bool) error {
+	// the silence is looked up before the reason data are serialized as the
+	// node selectors are matched against its nodes
+	si := a.silencedBy(al, msg.ReasonData, msg.Timestamp, lockGraph)
+
+	// serialize the reason data once, as the notification, its history record
+	// and the websocket message are sent asynchronously
+	reasonData, err := json.Marshal(msg.ReasonData)
 	if err != nil {
 		return fmt.Errorf("Failed to marshal alert to JSON: %s", err.Error())
 	}
+	msg.ReasonData = json.RawMessage(reasonData)
+
+	event := &types.AlertEvent{
+		AlertUUID:  al.UUID,
+		Timestamp:  common.UnixMillis(msg.Timestamp),
+		State:      msg.State,
+		ReasonData: msg.ReasonData,
+	}
+
+	// silenced alerts are only recorded in the history, once
+	if si != nil {
+		al.mute(msg)
+		if msg.renotify {
+			return nil
+		}
+
+		logging.GetLogger().Infof("Alert %s is silenced by %s, %s", al.UUID, si.UUID, msg.State)
+		event.SilenceUUID = si.UUID
+		go a.recordEvent(event)
+		return nil
+	}
+
+	logging.GetLogger().Infof("Triggering alert %s of type %s, %s", al.UUID, al.Action, msg.State)
+
+	payload, err := al.render(msg)
+	if err != nil {
+		return fmt.Errorf("Failed to render alert %s: %s", al.UUID, err.Error())
+	}
 
 	go func() {
-		if err := al.trigger(payload); err != nil {
-			logging.GetLogger().Infof("Failed to trigger alert: %s", err.Error())
+		if al.kind != 0 {
+			delivery := types.AlertDelivery{Action: al.Action, Success: true}
+			if err := al.trigger(msg, payload, a.queue); err != nil {
+				logging.GetLogger().Errorf("Failed to trigger alert %s: %s", al.UUID, err.Error())
+				delivery.Success = false
+				delivery.Error = err.Error()
+			}
+			event.Deliveries = append(event.Deliveries, delivery)
 		}
+
+		a.recordEvent(event)
 	}()
 
 	wsMsg := shttp.NewWSStructMessage(Namespace, "Alert", msg)
@@ -249,19 +589,9 @@ func (a *Server) evaluateAlert(al *GremlinAlert, lockGraph bool) error {
 		return err
 	}
 
-	if data != nil {
-		// Gremlin query/Javascript expression returned datas.
-		// Alert must but sent if those datas differ from the one that trigger
-		// the previous alert.
-		equal := reflect.DeepEqual(reflect.ValueOf(data).Interface(), al.lastEval)
-		if !equal {
-			al.lastEval = data
-			return a.triggerAlert(al, data)
-		}
-	} else {
-		// Gremlin query returned no datas, or Javascript expression was unsuccessful
-		// Reset the lastEval to be able to trigger the alert next time
-		al.lastEval = nil
+	// the key is computed before the alert is locked as it may lock the graph
+	if msg := al.update(data, evalKey(data), time.Now().UTC()); msg != nil {
+		return a.triggerAlert(al, msg, lockGraph)
 	}
 
 	return nil
@@ -325,8 +655,6 @@ func (a *Server) registerAlert(apiAlert *types.Alert) error {
 
 	logging.GetLogger().Debugf("Registering new alert: %+v", alert)
 
-	a.evaluateAlert(alert, true)
-
 	trigger, data := parseTrigger(apiAlert.Trigger)
 	switch trigger {
 	case "duration":
@@ -357,11 +685,22 @@ func (a *Server) registerAlert(apiAlert *types.Alert) error {
 	case "graph":
 		fallthrough
 	default:
+		alert.reevaluate = func() {
+			if err := a.evaluateAlert(alert, true); err != nil {
+				logging.GetLogger().Warning(err.Error())
+			}
+		}
+
 		a.Lock()
+		if previous, found := a.graphAlerts[apiAlert.UUID]; found {
+			previous.stop()
+		}
 		a.graphAlerts[apiAlert.UUID] = alert
 		a.Unlock()
 	}
 
+	a.evaluateAlert(alert, true)
+
 	return nil
 }
 
@@ -374,7 +713,8 @@ func (a *Server) unregisterAlert(id string) {
 	if ch, found := a.alertTimers[id]; found {
 		close(ch)
 		delete(a.alertTimers, id)
-	} else {
+	} else if alert, found := a.graphAlerts[id]; found {
+		alert.stop()
 		delete(a.graphAlerts, id)
 	}
 }
@@ -394,17 +734,28 @@ func (a *Server) onAPIWatcherEvent(action string, id string, resource types.Reso
 func (a *Server) Start() {
 	a.StartAndWait()
 
+	// silences are watched first so that the alerts evaluated at registration
+	// are muted
+	a.silenceWatcher = a.SilenceHandler.AsyncWatch(a.onSilenceWatcherEvent)
 	a.watcher = a.AlertHandler.AsyncWatch(a.onAPIWatcherEvent)
 	a.Graph.AddEventListener(a)
+
+	if a.queue != nil {
+		go a.queue.run()
+	}
 }
 
 // Stop the alerting server
 func (a *Server) Stop() {
 	a.MasterElector.Stop()
+
+	if a.queue != nil {
+		a.queue.stop()
+	}
 }
 
 // Returns a new alerting server
-func NewServer(apiServer *api.Server, pool shttp.WSStructSpeakerPool, graph *graph.Graph, parser *traversal.GremlinTraversalParser, etcdClient *etcd.Client) (*Server, error) {
+func NewServer(apiServer *api.Server, pool shttp.WSStructSpeakerPool, graph *graph.Graph, parser *traversal.GremlinTraversalParser, etcdClient *etcd.Client, store storage.Storage) (*Server, error) {
 	elector := etcd.NewMasterElectorFromConfig(common.AnalyzerService, "alert-server", etcdClient)
 
 	jsre, err := js.NewJSRE()
@@ -416,15 +767,22 @@ func NewServer(apiServer *api.Server, pool shttp.WSStructSpeakerPool, graph *gra
 	jsre.RegisterAPIServer(graph, parser, apiServer)
 
 	as := &Server{
-		MasterElector: elector,
-		Pool:          pool,
-		AlertHandler:  apiServer.GetHandler("alert"),
-		Graph:         graph,
-		graphAlerts:   make(map[string]*GremlinAlert),
-		alertTimers:   make(map[string]chan bool),
-		gremlinParser: parser,
-		apiServer:     apiServer,
-		jsre:          jsre,
+		MasterElector:  elector,
+		Pool:           pool,
+		AlertHandler:   apiServer.GetHandler("alert"),
+		SilenceHandler: apiServer.GetHandler("silence"),
+		Graph:          graph,
+		graphAlerts:    make(map[string]*GremlinAlert),
+		alertTimers:    make(map[string]chan bool),
+		silences:       make(map[string]*silence),
+		gremlinParser:  parser,
+		apiServer:      apiServer,
+		jsre:           jsre,
+		storage:        store,
+	}
+
+	if size := config.GetInt("analyzer.alert.retry_queue.size"); size > 0 {
+		as.queue = newDeliveryQueue(config.GetString("analyzer.alert.retry_queue.path"), size)
 	}
 
 	return as, nil
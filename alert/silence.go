@@ -0,0 +1,197 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package alert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/topology/graph"
+	"github.com/skydive-project/skydive/topology/graph/traversal"
+)
+
+// silence mutes the notifications of the alerts it matches
+type silence struct {
+	*types.Silence
+	namePattern  *regexp.Regexp
+	nodeSelector *traversal.GremlinTraversalSequence
+	expireTimer  *time.Timer
+}
+
+// reasonNodes returns the identifiers of the nodes of the reason data of an
+// alert, either a Gremlin result or the result of a JavaScript expression
+func reasonNodes(data interface{}) map[graph.Identifier]bool {
+	var values []interface{}
+	switch data := data.(type) {
+	case traversal.GraphTraversalStep:
+		values = data.Values()
+	case []interface{}:
+		values = data
+	case []map[string]interface{}:
+		for _, value := range data {
+			values = append(values, value)
+		}
+	}
+
+	ids := make(map[graph.Identifier]bool)
+	for _, value := range values {
+		switch value := value.(type) {
+		case *graph.Node:
+			ids[value.ID] = true
+		case map[string]interface{}:
+			if id, ok := value["ID"].(string); ok {
+				ids[graph.Identifier(id)] = true
+			}
+		}
+	}
+	return ids
+}
+
+// matches returns whether the silence mutes the alert triggered with the
+// given reason data
+func (s *silence) matches(al *GremlinAlert, data interface{}, g *graph.Graph, lockGraph bool) (bool, error) {
+	if s.AlertUUID != "" && s.AlertUUID == al.UUID {
+		return true, nil
+	}
+
+	if s.namePattern != nil && s.namePattern.MatchString(al.Name) {
+		return true, nil
+	}
+
+	if s.nodeSelector != nil {
+		ids := reasonNodes(data)
+		if len(ids) == 0 {
+			return false, nil
+		}
+
+		result, err := s.nodeSelector.Exec(g, lockGraph)
+		if err != nil {
+			return false, fmt.Errorf("Failed to evaluate node selector of silence %s: %s", s.UUID, err)
+		}
+
+		for _, value := range result.Values() {
+			if node, ok := value.(*graph.Node); ok && ids[node.ID] {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func newSilence(s *types.Silence, p *traversal.GremlinTraversalParser) (*silence, error) {
+	si := &silence{Silence: s}
+
+	if s.NamePattern != "" {
+		var err error
+		if si.namePattern, err = regexp.Compile(s.NamePattern); err != nil {
+			return nil, fmt.Errorf("Invalid name pattern for silence %s: %s", s.UUID, err)
+		}
+	}
+
+	if s.NodeSelector != "" {
+		var err error
+		if si.nodeSelector, err = p.Parse(strings.NewReader(s.NodeSelector)); err != nil {
+			return nil, fmt.Errorf("Invalid node selector for silence %s: %s", s.UUID, err)
+		}
+	}
+
+	return si, nil
+}
+
+// silencedBy returns the active silence muting the alert, if any
+func (a *Server) silencedBy(al *GremlinAlert, data interface{}, now time.Time, lockGraph bool) *silence {
+	a.silencesLock.RLock()
+	defer a.silencesLock.RUnlock()
+
+	for _, s := range a.silences {
+		if !s.Active(now) {
+			continue
+		}
+
+		matched, err := s.matches(al, data, a.Graph, lockGraph)
+		if err != nil {
+			logging.GetLogger().Warning(err.Error())
+			continue
+		}
+
+		if matched {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// removeSilence deletes the silence once it ended. Only the master removes it
+// but every server schedules the removal in case it gets elected.
+func (a *Server) removeSilence(id string) {
+	if !a.IsMaster() {
+		return
+	}
+
+	logging.GetLogger().Debugf("Removing expired silence: %s", id)
+
+	if err := a.SilenceHandler.Delete(id); err != nil {
+		logging.GetLogger().Errorf("Failed to remove expired silence %s: %s", id, err.Error())
+	}
+}
+
+func (a *Server) onSilenceWatcherEvent(action string, id string, resource types.Resource) {
+	switch action {
+	case "init", "create", "set", "update":
+		s, err := newSilence(resource.(*types.Silence), a.gremlinParser)
+		if err != nil {
+			logging.GetLogger().Errorf("Failed to register silence: %s", err.Error())
+			return
+		}
+
+		logging.GetLogger().Debugf("Registering silence: %+v", s.Silence)
+
+		s.expireTimer = time.AfterFunc(s.EndsAt.Sub(time.Now()), func() { a.removeSilence(id) })
+
+		a.silencesLock.Lock()
+		if previous, found := a.silences[id]; found {
+			previous.expireTimer.Stop()
+		}
+		a.silences[id] = s
+		a.silencesLock.Unlock()
+	case "expire", "delete":
+		logging.GetLogger().Debugf("Unregistering silence: %s", id)
+
+		a.silencesLock.Lock()
+		if s, found := a.silences[id]; found {
+			s.expireTimer.Stop()
+			delete(a.silences, id)
+		}
+		a.silencesLock.Unlock()
+
+		// notify the transitions the silence muted, the alerts evaluated
+		// periodically are notified at their next evaluation
+		a.evaluateAlerts(a.graphAlerts, true)
+	}
+}
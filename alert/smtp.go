@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package alert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/skydive-project/skydive/api/types"
+)
+
+const defaultSMTPPort = "25"
+
+// mailer sends the notifications of an alert by email, its action being in
+// the form smtp://[user:password@]host[:port]?from=sender&to=recipient[,recipient][&subject=subject]
+type mailer struct {
+	addr    string
+	auth    smtp.Auth
+	from    string
+	to      []string
+	subject string
+}
+
+func (m *mailer) send(subject string, payload []byte) error {
+	if m.subject != "" {
+		subject = m.subject
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("\r\n")
+	msg.Write(payload)
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, m.to, msg.Bytes()); err != nil {
+		return fmt.Errorf("Failed to send alert email through %s: %s", m.addr, err.Error())
+	}
+	return nil
+}
+
+func newMailer(u *url.URL) (*mailer, error) {
+	values := u.Query()
+
+	m := &mailer{
+		addr:    u.Host,
+		from:    values.Get("from"),
+		subject: values.Get("subject"),
+	}
+
+	if u.Hostname() == "" {
+		return nil, errors.New("no SMTP server specified")
+	}
+	if u.Port() == "" {
+		m.addr = net.JoinHostPort(u.Hostname(), defaultSMTPPort)
+	}
+
+	if m.from == "" {
+		return nil, errors.New("no sender specified")
+	}
+
+	if m.to = types.AlertMailRecipients(values.Get("to")); len(m.to) == 0 {
+		return nil, errors.New("no recipient specified")
+	}
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		m.auth = smtp.PlainAuth("", u.User.Username(), password, u.Hostname())
+	}
+
+	return m, nil
+}
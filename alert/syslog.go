@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package alert
+
+import (
+	"fmt"
+	"log/syslog"
+	"net"
+	"net/url"
+
+	"github.com/skydive-project/skydive/api/types"
+)
+
+const defaultSyslogPort = "514"
+
+// syslogger sends the notifications of an alert to syslog, its action being
+// in the form syslog://[host[:port]][?network=udp|tcp&facility=local0&severity=warning&tag=skydive].
+// The local syslog daemon is used when no host is given.
+type syslogger struct {
+	network  string
+	addr     string
+	priority syslog.Priority
+	tag      string
+}
+
+func (s *syslogger) send(payload []byte) error {
+	w, err := syslog.Dial(s.network, s.addr, s.priority, s.tag)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to syslog %s: %s", s.addr, err.Error())
+	}
+	defer w.Close()
+
+	if _, err = w.Write(payload); err != nil {
+		return fmt.Errorf("Failed to write alert to syslog %s: %s", s.addr, err.Error())
+	}
+	return nil
+}
+
+func newSyslogger(u *url.URL) (*syslogger, error) {
+	values := u.Query()
+
+	s := &syslogger{tag: values.Get("tag")}
+	if s.tag == "" {
+		s.tag = "skydive"
+	}
+
+	if u.Host != "" {
+		s.network, s.addr = values.Get("network"), u.Host
+		if s.network == "" {
+			s.network = "udp"
+		}
+		if u.Port() == "" {
+			s.addr = net.JoinHostPort(u.Hostname(), defaultSyslogPort)
+		}
+	}
+
+	facility, severity := syslog.LOG_DAEMON, syslog.LOG_WARNING
+	if name := values.Get("facility"); name != "" {
+		code, ok := types.AlertSyslogFacilities[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %s", name)
+		}
+		facility = syslog.Priority(code << 3)
+	}
+	if name := values.Get("severity"); name != "" {
+		code, ok := types.AlertSyslogSeverities[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog severity %s", name)
+		}
+		severity = syslog.Priority(code)
+	}
+	s.priority = facility | severity
+
+	return s, nil
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package alert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+const (
+	// SignatureHeader is the header holding the HMAC-SHA256 signature of
+	// the payload of a webhook, when the alert has a secret
+	SignatureHeader = "X-Skydive-Signature"
+
+	defaultBackoff   = time.Second
+	queueRetryDelay  = 30 * time.Second
+	queueMaxAttempts = 10
+	queueTick        = 5 * time.Second
+)
+
+// delivery describes a webhook request of an alert notification
+type delivery struct {
+	AlertUUID   string
+	URL         string
+	Headers     map[string]string
+	Payload     []byte
+	Timeout     time.Duration
+	Attempts    int
+	NextAttempt time.Time
+}
+
+// sign returns the HMAC-SHA256 signature of the payload
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *delivery) send() error {
+	client := &http.Client{Timeout: d.Timeout}
+
+	req, err := http.NewRequest("POST", d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return fmt.Errorf("Failed to post alert to %s: %s", d.URL, err.Error())
+	}
+
+	req.Close = true
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range d.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error while posting alert to %s: %s", d.URL, err.Error())
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Error while posting alert to %s: %s", d.URL, resp.Status)
+	}
+
+	return nil
+}
+
+// sendWithRetries sends the request, retrying it with an exponential backoff
+func (d *delivery) sendWithRetries(retries int, backoff time.Duration) error {
+	for attempt := 0; ; attempt++ {
+		err := d.send()
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		logging.GetLogger().Warningf("%s, retrying in %s", err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// deliveryQueue is a bounded queue of the webhook deliveries that failed. It
+// is saved on disk so that the deliveries are retried after a restart.
+type deliveryQueue struct {
+	sync.Mutex
+	path       string
+	size       int
+	deliveries []*delivery
+	quit       chan bool
+}
+
+func (q *deliveryQueue) load() {
+	if q.path == "" {
+		return
+	}
+
+	data, err := ioutil.ReadFile(q.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.GetLogger().Errorf("Failed to read alert retry queue %s: %s", q.path, err.Error())
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, &q.deliveries); err != nil {
+		logging.GetLogger().Errorf("Failed to decode alert retry queue %s: %s", q.path, err.Error())
+	}
+}
+
+// save writes the queue to a temporary file then renamed so that an
+// interrupted write doesn't corrupt it
+func (q *deliveryQueue) save() {
+	if q.path == "" {
+		return
+	}
+
+	data, err := json.Marshal(q.deliveries)
+	if err != nil {
+		logging.GetLogger().Errorf("Failed to encode alert retry queue: %s", err.Error())
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(q.path), 0700); err == nil {
+		tmp := q.path + ".tmp"
+		if err = ioutil.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, q.path)
+		}
+	}
+
+	if err != nil {
+		logging.GetLogger().Errorf("Failed to write alert retry queue %s: %s", q.path, err.Error())
+	}
+}
+
+// add queues the deliveries, the oldest ones being dropped when the queue is full
+func (q *deliveryQueue) add(deliveries ...*delivery) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.deliveries = append(q.deliveries, deliveries...)
+	if dropped := len(q.deliveries) - q.size; dropped > 0 {
+		logging.GetLogger().Warningf("Alert retry queue is full, dropping %d deliveries", dropped)
+		q.deliveries = q.deliveries[dropped:]
+	}
+
+	q.save()
+}
+
+func (q *deliveryQueue) push(d *delivery) {
+	d.NextAttempt = time.Now().Add(queueRetryDelay)
+	q.add(d)
+}
+
+// retry sends the deliveries whose next attempt is due. The ones failing
+// again are postponed with an exponential backoff, until they reach the
+// maximum number of attempts.
+func (q *deliveryQueue) retry(now time.Time) {
+	var due []*delivery
+
+	q.Lock()
+	var pending []*delivery
+	for _, d := range q.deliveries {
+		if now.Before(d.NextAttempt) {
+			pending = append(pending, d)
+		} else {
+			due = append(due, d)
+		}
+	}
+	q.deliveries = pending
+	q.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	var failed []*delivery
+	for _, d := range due {
+		d.Attempts++
+		if err := d.send(); err != nil {
+			if d.Attempts >= queueMaxAttempts {
+				logging.GetLogger().Errorf("Giving up delivery of alert %s: %s", d.AlertUUID, err.Error())
+				continue
+			}
+
+			logging.GetLogger().Warningf("Failed to deliver queued alert %s: %s", d.AlertUUID, err.Error())
+			d.NextAttempt = now.Add(queueRetryDelay << uint(d.Attempts))
+			failed = append(failed, d)
+		}
+	}
+
+	q.add(failed...)
+}
+
+func (q *deliveryQueue) run() {
+	ticker := time.NewTicker(queueTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			q.retry(now)
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+func (q *deliveryQueue) stop() {
+	close(q.quit)
+}
+
+// checkPath makes sure that the queue can be written in its directory
+func (q *deliveryQueue) checkPath() error {
+	dir := filepath.Dir(q.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, filepath.Base(q.path))
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	return os.Remove(f.Name())
+}
+
+// newDeliveryQueue returns a queue of at most size deliveries, saved in the
+// file at the given path if not empty. The queue is kept in memory only if
+// the file can't be written.
+func newDeliveryQueue(path string, size int) *deliveryQueue {
+	q := &deliveryQueue{
+		path: path,
+		size: size,
+		quit: make(chan bool),
+	}
+
+	if q.path != "" {
+		if err := q.checkPath(); err != nil {
+			logging.GetLogger().Warningf("Alert retry queue %s can't be written, keeping it in memory only: %s", q.path, err.Error())
+			q.path = ""
+		}
+	}
+	q.load()
+
+	return q
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package alert
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newWebhookServer returns a server answering with the given status codes,
+// the last one being repeated, and the number of requests received
+func newWebhookServer(codes ...int) (*httptest.Server, *int64) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt64(&requests, 1))
+		if n > len(codes) {
+			n = len(codes)
+		}
+		w.WriteHeader(codes[n-1])
+	}))
+	return server, &requests
+}
+
+func TestSign(t *testing.T) {
+	// RFC 4231 test case 2
+	expected := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if signature := sign("Jefe", []byte("what do ya want for nothing?")); signature != expected {
+		t.Errorf("Expected signature %s, got %s", expected, signature)
+	}
+}
+
+func TestDeliverySend(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := &delivery{URL: server.URL, Headers: map[string]string{"X-Token": "abc"}, Timeout: time.Second}
+	if err := d.send(); err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("X-Token") != "abc" || headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Wrong request headers: %v", headers)
+	}
+}
+
+func TestDeliveryNon2xx(t *testing.T) {
+	for _, code := range []int{http.StatusMovedPermanently, http.StatusNotFound, http.StatusInternalServerError} {
+		server, _ := newWebhookServer(code)
+
+		d := &delivery{URL: server.URL, Timeout: time.Second}
+		if err := d.send(); err == nil {
+			t.Errorf("Status code %d should be an error", code)
+		}
+		server.Close()
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	server, requests := newWebhookServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	defer server.Close()
+
+	d := &delivery{URL: server.URL, Timeout: time.Second}
+	if err := d.sendWithRetries(1, time.Millisecond); err == nil || *requests != 2 {
+		t.Fatalf("Delivery should fail after 2 requests, got %d (%v)", *requests, err)
+	}
+
+	if err := d.sendWithRetries(1, time.Millisecond); err != nil || *requests != 3 {
+		t.Fatalf("Delivery should succeed after 3 requests, got %d (%v)", *requests, err)
+	}
+}
+
+func TestDeliveryQueueSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-alert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "queue", "retry_queue.json")
+
+	// the oldest deliveries are dropped when the queue is full
+	q := newDeliveryQueue(path, 2)
+	for _, uuid := range []string{"a", "b", "c"} {
+		q.push(&delivery{AlertUUID: uuid, Payload: []byte(`{}`)})
+	}
+
+	q = newDeliveryQueue(path, 2)
+	if len(q.deliveries) != 2 || q.deliveries[0].AlertUUID != "b" || q.deliveries[1].AlertUUID != "c" {
+		t.Fatalf("Expected deliveries b and c to be loaded, got %+v", q.deliveries)
+	}
+
+	if string(q.deliveries[0].Payload) != `{}` || q.deliveries[0].NextAttempt.IsZero() {
+		t.Errorf("Delivery not restored: %+v", q.deliveries[0])
+	}
+}
+
+func TestDeliveryQueueNotWritable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-alert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a file is in the way of the queue directory
+	file := filepath.Join(dir, "queue")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	q := newDeliveryQueue(filepath.Join(file, "retry_queue.json"), 2)
+	if q.path != "" {
+		t.Fatalf("Queue should be kept in memory, got path %s", q.path)
+	}
+
+	q.push(&delivery{AlertUUID: "a", Payload: []byte(`{}`)})
+	if len(q.deliveries) != 1 {
+		t.Errorf("Delivery should be queued in memory, got %+v", q.deliveries)
+	}
+}
+
+func TestDeliveryQueueRetry(t *testing.T) {
+	server, requests := newWebhookServer(http.StatusInternalServerError, http.StatusOK)
+	defer server.Close()
+
+	q := newDeliveryQueue("", 10)
+	q.push(&delivery{AlertUUID: "a", URL: server.URL, Timeout: time.Second})
+
+	// not due yet
+	now := q.deliveries[0].NextAttempt
+	q.retry(now.Add(-time.Second))
+	if *requests != 0 || len(q.deliveries) != 1 {
+		t.Fatalf("Delivery should not be retried yet, got %d requests", *requests)
+	}
+
+	// failing, postponed with a backoff
+	q.retry(now)
+	if *requests != 1 || len(q.deliveries) != 1 {
+		t.Fatalf("Delivery should be queued again, got %d requests", *requests)
+	}
+
+	d := q.deliveries[0]
+	if d.Attempts != 1 || !d.NextAttempt.Equal(now.Add(2*queueRetryDelay)) {
+		t.Errorf("Wrong next attempt: %+v", d)
+	}
+
+	// delivered
+	q.retry(d.NextAttempt)
+	if *requests != 2 || len(q.deliveries) != 0 {
+		t.Fatalf("Delivery should be done, got %d requests and %d queued", *requests, len(q.deliveries))
+	}
+}
+
+func TestDeliveryQueueGiveUp(t *testing.T) {
+	server, _ := newWebhookServer(http.StatusInternalServerError)
+	defer server.Close()
+
+	q := newDeliveryQueue("", 10)
+	q.add(&delivery{AlertUUID: "a", URL: server.URL, Timeout: time.Second, Attempts: queueMaxAttempts - 1})
+
+	q.retry(time.Now())
+	if len(q.deliveries) != 0 {
+		t.Errorf("Delivery should be dropped after %d attempts", queueMaxAttempts)
+	}
+}
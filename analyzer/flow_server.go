@@ -39,6 +39,7 @@ import (
 	"github.com/skydive-project/skydive/flow/storage"
 	shttp "github.com/skydive-project/skydive/http"
 	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/netflow"
 	"github.com/skydive-project/skydive/probe"
 	"github.com/skydive-project/skydive/topology/graph"
 )
@@ -88,6 +89,7 @@ type FlowServerWebSocketConn struct {
 // FlowServer describes a flow server with pipeline enhancers mechanism
 type FlowServer struct {
 	storage                storage.Storage
+	exporter               *netflow.Exporter
 	enhancerPipeline       *flow.EnhancerPipeline
 	enhancerPipelineConfig *flow.EnhancerPipelineConfig
 	conn                   FlowServerConn
@@ -208,6 +210,10 @@ func (s *FlowServer) storeFlows(flows []*flow.Flow) {
 
 		logging.GetLogger().Debugf("%d flows stored", len(flows))
 	}
+
+	if s.exporter != nil && len(flows) > 0 {
+		s.exporter.Export(flows)
+	}
 }
 
 // Start the flow server
@@ -215,6 +221,10 @@ func (s *FlowServer) Start() {
 	atomic.StoreInt64(&s.state, common.RunningState)
 	s.wgServer.Add(1)
 
+	if s.exporter != nil {
+		s.exporter.Start()
+	}
+
 	s.conn.Serve(s.ch, s.quit, &s.wgServer)
 	go func() {
 		defer s.wgServer.Done()
@@ -249,6 +259,10 @@ func (s *FlowServer) Stop() {
 		s.quit <- struct{}{}
 		s.quit <- struct{}{}
 		s.wgServer.Wait()
+
+		if s.exporter != nil {
+			s.exporter.Stop()
+		}
 	}
 }
 
@@ -300,8 +314,14 @@ func NewFlowServer(s *shttp.Server, g *graph.Graph, store storage.Storage, probe
 		return nil, err
 	}
 
+	exporter, err := netflow.NewExporterFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	fs := &FlowServer{
 		storage:                store,
+		exporter:               exporter,
 		enhancerPipeline:       pipeline,
 		enhancerPipelineConfig: flow.NewEnhancerPipelineConfig(),
 		conn: conn,
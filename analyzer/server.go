@@ -307,7 +307,11 @@ func NewServerFromConfig() (*Server, error) {
 	}
 	piClient := packet_injector.NewPacketInjectorClient(agentWSServer, etcdClient, piAPIHandler, g)
 
-	if _, err = api.RegisterAlertAPI(apiServer, apiAuthBackend); err != nil {
+	if _, err = api.RegisterAlertAPI(apiServer, storage, apiAuthBackend); err != nil {
+		return nil, err
+	}
+
+	if _, err = api.RegisterSilenceAPI(apiServer, apiAuthBackend); err != nil {
 		return nil, err
 	}
 
@@ -324,7 +328,7 @@ func NewServerFromConfig() (*Server, error) {
 		return nil, err
 	}
 
-	alertServer, err := alert.NewServer(apiServer, subscriberWSServer, g, tr, etcdClient)
+	alertServer, err := alert.NewServer(apiServer, subscriberWSServer, g, tr, etcdClient, storage)
 	if err != nil {
 		return nil, err
 	}
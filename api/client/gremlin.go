@@ -44,6 +44,16 @@ import (
 // GremlinQueryHelper describes a gremlin query request query helper mechanism
 type GremlinQueryHelper struct {
 	authOptions *shttp.AuthenticationOpts
+	bindings    map[string]interface{}
+}
+
+// WithBindings returns a helper sending the values of the bindings, the $name
+// parameters, along with the queries
+func (g *GremlinQueryHelper) WithBindings(bindings map[string]interface{}) *GremlinQueryHelper {
+	return &GremlinQueryHelper{
+		authOptions: g.authOptions,
+		bindings:    bindings,
+	}
 }
 
 // Request send a Gremlin request to the topology API
@@ -53,7 +63,10 @@ func (g *GremlinQueryHelper) Request(query interface{}, header http.Header) (*ht
 		return nil, err
 	}
 
-	gq := types.TopologyParam{GremlinQuery: gremlin.NewQueryStringFromArgument(query).String()}
+	gq := types.TopologyParam{
+		GremlinQuery: gremlin.NewQueryStringFromArgument(query).String(),
+		Bindings:     g.bindings,
+	}
 	s, err := json.Marshal(gq)
 	if err != nil {
 		return nil, err
@@ -150,6 +163,12 @@ func (g *GremlinQueryHelper) GetFlows(query interface{}) (flows []*flow.Flow, er
 	return
 }
 
+// GetRows from the Gremlin query, as returned by the ValueMap and Project steps
+func (g *GremlinQueryHelper) GetRows(query interface{}) (rows []map[string]interface{}, err error) {
+	err = g.QueryObject(query, &rows)
+	return
+}
+
 // GetFlowMetric from Gremlin query
 func (g *GremlinQueryHelper) GetFlowMetric(query interface{}) (m *flow.FlowMetric, _ error) {
 	flows, err := g.GetFlows(query)
@@ -23,10 +23,20 @@
 package server
 
 import (
+	"encoding/json"
+	"net/http"
 	"time"
 
+	"github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+
 	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/filters"
+	"github.com/skydive-project/skydive/flow/storage"
 	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/rbac"
 )
 
 // AlertResourceHandler aims to creates and manage a new Alert.
@@ -51,8 +61,95 @@ func (a *AlertResourceHandler) Name() string {
 	return "alert"
 }
 
-// RegisterAlertAPI registers an Alert's API to a designated API Server
-func RegisterAlertAPI(apiServer *Server, authBackend shttp.AuthenticationBackend) (*AlertAPIHandler, error) {
+// Decorate hides the secrets of the alert
+func (a *AlertAPIHandler) Decorate(resource types.Resource) {
+	alert := resource.(*types.Alert)
+	*alert = *alert.Redacted()
+}
+
+type alertHistoryAPI struct {
+	storage storage.Storage
+}
+
+// alertHistoryQuery returns the query of the events of an alert, the time
+// range being given by the optional from and to RFC 3339 parameters
+func alertHistoryQuery(id string, r *http.Request) (filters.SearchQuery, error) {
+	filterList := []*filters.Filter{filters.NewTermStringFilter("AlertUUID", id)}
+
+	values := r.URL.Query()
+	if from := values.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filters.SearchQuery{}, err
+		}
+		filterList = append(filterList, filters.NewGteInt64Filter("Timestamp", common.UnixMillis(t)))
+	}
+	if to := values.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filters.SearchQuery{}, err
+		}
+		filterList = append(filterList, filters.NewLteInt64Filter("Timestamp", common.UnixMillis(t)))
+	}
+
+	return filters.SearchQuery{Filter: filters.NewAndFilter(filterList...), Sort: true, SortBy: "Timestamp"}, nil
+}
+
+func (h *alertHistoryAPI) alertHistory(w http.ResponseWriter, r *auth.AuthenticatedRequest) {
+	if !rbac.Enforce(r.Username, "alert", "read") {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.storage == nil {
+		writeError(w, http.StatusServiceUnavailable, storage.ErrNoStorageConfigured)
+		return
+	}
+
+	fsq, err := alertHistoryQuery(mux.Vars(&r.Request)["id"], &r.Request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := h.storage.SearchAlertEvents(fsq)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if events == nil {
+		events = []*types.AlertEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		logging.GetLogger().Warningf("Error while writing response: %s", err)
+	}
+}
+
+func (h *alertHistoryAPI) registerEndpoints(r *shttp.Server, authBackend shttp.AuthenticationBackend) {
+	routes := []shttp.Route{
+		{
+			Name:        "AlertHistory",
+			Method:      "GET",
+			Path:        "/api/alert/{id}/history",
+			HandlerFunc: h.alertHistory,
+		},
+	}
+
+	r.RegisterRoutes(routes, authBackend)
+}
+
+// RegisterAlertAPI registers an Alert's API to a designated API Server, with
+// the history of the alerts recorded in the given storage
+func RegisterAlertAPI(apiServer *Server, store storage.Storage, authBackend shttp.AuthenticationBackend) (*AlertAPIHandler, error) {
+	// the history route has to be registered before the alert ones as their
+	// path prefix matches it
+	history := &alertHistoryAPI{storage: store}
+	history.registerEndpoints(apiServer.HTTPServer, authBackend)
+
 	alertAPIHandler := &AlertAPIHandler{
 		BasicAPIHandler: BasicAPIHandler{
 			ResourceHandler: &AlertResourceHandler{},
@@ -24,6 +24,7 @@ package server
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/skydive-project/skydive/api/types"
 	"github.com/skydive-project/skydive/common"
@@ -67,7 +68,7 @@ func (c *CaptureAPIHandler) Decorate(resource types.Resource) {
 	c.Graph.RLock()
 	defer c.Graph.RUnlock()
 
-	res, err := ge.TopologyGremlinQuery(c.Graph, capture.GremlinQuery)
+	res, err := ge.TopologyGremlinBoundQuery(c.Graph, capture.GremlinQuery, capture.Bindings)
 	if err != nil {
 		logging.GetLogger().Errorf("Gremlin error: %s", err.Error())
 		return
@@ -127,7 +128,7 @@ func (c *CaptureAPIHandler) Create(r types.Resource) error {
 	resources := c.Index()
 	for _, resource := range resources {
 		resource := resource.(*types.Capture)
-		if resource.GremlinQuery == capture.GremlinQuery {
+		if resource.GremlinQuery == capture.GremlinQuery && reflect.DeepEqual(resource.Bindings, capture.Bindings) {
 			return fmt.Errorf("Duplicate capture, uuid=%s", capture.UUID)
 		}
 	}
@@ -149,6 +149,7 @@ func (a *Server) RegisterAPIHandler(handler Handler, authBackend shttp.Authentic
 					writeError(w, http.StatusBadRequest, err)
 					return
 				}
+				handler.Decorate(resource)
 
 				data, err := json.Marshal(&resource)
 				if err != nil {
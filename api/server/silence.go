@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/abbot/go-http-auth"
+	"github.com/gorilla/mux"
+
+	"github.com/skydive-project/skydive/api/types"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/rbac"
+)
+
+// SilenceResourceHandler aims to creates and manage a new Silence.
+type SilenceResourceHandler struct {
+	ResourceHandler
+}
+
+// SilenceAPIHandler aims to exposes the Silence API.
+type SilenceAPIHandler struct {
+	BasicAPIHandler
+}
+
+// New creates a new silence
+func (s *SilenceResourceHandler) New() types.Resource {
+	return types.NewSilence()
+}
+
+// Name returns resource name "silence"
+func (s *SilenceResourceHandler) Name() string {
+	return "silence"
+}
+
+// Create stores the silence, starting it now if no start time was given
+func (s *SilenceAPIHandler) Create(r types.Resource) error {
+	silence := r.(*types.Silence)
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now().UTC()
+	}
+
+	return s.BasicAPIHandler.Create(silence)
+}
+
+// Expire ends the silence now, the alerting server then removes it and
+// notifies the transitions it muted
+func (s *SilenceAPIHandler) Expire(id string) (*types.Silence, error) {
+	resource, found := s.Get(id)
+	if !found {
+		return nil, nil
+	}
+
+	silence := resource.(*types.Silence)
+	if now := time.Now().UTC(); now.Before(silence.EndsAt) {
+		silence.EndsAt = now
+		if now.Before(silence.StartsAt) {
+			silence.StartsAt = now
+		}
+
+		if err := s.Update(id, silence); err != nil {
+			return nil, err
+		}
+	}
+
+	return silence, nil
+}
+
+func (s *SilenceAPIHandler) expire(w http.ResponseWriter, r *auth.AuthenticatedRequest) {
+	if !rbac.Enforce(r.Username, "silence", "write") {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	silence, err := s.Expire(mux.Vars(&r.Request)["id"])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if silence == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(silence); err != nil {
+		logging.GetLogger().Warningf("Error while writing response: %s", err)
+	}
+}
+
+func (s *SilenceAPIHandler) registerEndpoints(r *shttp.Server, authBackend shttp.AuthenticationBackend) {
+	routes := []shttp.Route{
+		{
+			Name:        "SilenceExpire",
+			Method:      "POST",
+			Path:        "/api/silence/{id}/expire",
+			HandlerFunc: s.expire,
+		},
+	}
+
+	r.RegisterRoutes(routes, authBackend)
+}
+
+// RegisterSilenceAPI registers a Silence's API to a designated API Server
+func RegisterSilenceAPI(apiServer *Server, authBackend shttp.AuthenticationBackend) (*SilenceAPIHandler, error) {
+	silenceAPIHandler := &SilenceAPIHandler{
+		BasicAPIHandler: BasicAPIHandler{
+			ResourceHandler: &SilenceResourceHandler{},
+			EtcdKeyAPI:      apiServer.EtcdKeyAPI,
+		},
+	}
+
+	silenceAPIHandler.registerEndpoints(apiServer.HTTPServer, authBackend)
+
+	if err := apiServer.RegisterAPIHandler(silenceAPIHandler, authBackend); err != nil {
+		return nil, err
+	}
+	return silenceAPIHandler, nil
+}
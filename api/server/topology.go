@@ -147,14 +147,28 @@ func (t *TopologyAPI) topologySearch(w http.ResponseWriter, r *auth.Authenticate
 		return
 	}
 
-	ts, err := t.gremlinParser.Parse(strings.NewReader(resource.GremlinQuery))
+	ts, err := t.gremlinParser.ParseQuery(resource.GremlinQuery)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	res, err := ts.Exec(t.graph, true)
-	if err != nil {
+	if ts, err = ts.Bind(resource.Bindings); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the query is canceled if the client disconnects
+	res, err := ts.ExecContext(r.Context(), t.graph, true)
+	switch err {
+	case nil:
+	case traversal.ErrQueryCanceled:
+		logging.GetLogger().Debugf("Gremlin query '%s' canceled by the client", resource.GremlinQuery)
+		return
+	case traversal.ErrQueryTimeout:
+		writeError(w, http.StatusGatewayTimeout, err)
+		return
+	default:
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
@@ -23,7 +23,13 @@
 package types
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
 	"time"
 
 	shttp "github.com/skydive-project/skydive/http"
@@ -53,14 +59,168 @@ func (b *BasicResource) SetID(i string) {
 // Alert is a set of parameters, the Alert Action will Trigger according to its Expression.
 type Alert struct {
 	BasicResource
-	Name        string `json:",omitempty"`
-	Description string `json:",omitempty"`
-	Expression  string `json:",omitempty" valid:"nonzero"`
-	Action      string `json:",omitempty" valid:"regexp=^(|http://|https://|file://).*$"`
-	Trigger     string `json:",omitempty" valid:"regexp=^(graph|duration:.+|)$"`
+	Name        string                 `json:",omitempty"`
+	Description string                 `json:",omitempty"`
+	Expression  string                 `json:",omitempty" valid:"nonzero"`
+	Bindings    map[string]interface{} `json:",omitempty"`
+	Action      string                 `json:",omitempty" valid:"regexp=^(|http://|https://|file://|smtp://|syslog://).*$"`
+	Trigger     string                 `json:",omitempty" valid:"regexp=^(graph|duration:.+|)$"`
+	For         string                 `json:",omitempty" valid:"isValidDuration"`
+	Webhook     *AlertWebhook          `json:",omitempty"`
+	Template    string                 `json:",omitempty"`
 	CreateTime  time.Time
 }
 
+// AlertTemplateData is the data given to the template of an alert to render
+// the body of its notifications. Time is the time the alert was triggered,
+// ReasonData the result of the evaluation of its expression.
+type AlertTemplateData struct {
+	Alert         *Alert
+	Time          time.Time
+	State         string
+	PreviousState string
+	ReasonData    interface{}
+}
+
+// AlertTemplateFuncs are the functions available in the template of an alert
+var AlertTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// ParseTemplate returns the template of the alert, nil if it has none
+func (a *Alert) ParseTemplate() (*template.Template, error) {
+	if a.Template == "" {
+		return nil, nil
+	}
+	return template.New(a.UUID).Funcs(AlertTemplateFuncs).Parse(a.Template)
+}
+
+// AlertSyslogFacilities are the facilities of the syslog actions, by name
+var AlertSyslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// AlertSyslogSeverities are the severities of the syslog actions, by name
+var AlertSyslogSeverities = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// AlertMailRecipients returns the recipients of the to parameter of an email
+// action, a comma separated list
+func AlertMailRecipients(to string) (recipients []string) {
+	for _, recipient := range strings.Split(to, ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	return
+}
+
+func (a *Alert) validateAction() error {
+	u, err := url.Parse(a.Action)
+	if err != nil {
+		return err
+	}
+
+	values := u.Query()
+	switch u.Scheme {
+	case "smtp":
+		if u.Hostname() == "" {
+			return errors.New("no SMTP server specified")
+		}
+		if values.Get("from") == "" {
+			return errors.New("no sender specified")
+		}
+		if len(AlertMailRecipients(values.Get("to"))) == 0 {
+			return errors.New("no recipient specified")
+		}
+	case "syslog":
+		if name := values.Get("facility"); name != "" {
+			if _, ok := AlertSyslogFacilities[name]; !ok {
+				return fmt.Errorf("unknown syslog facility %s", name)
+			}
+		}
+		if name := values.Get("severity"); name != "" {
+			if _, ok := AlertSyslogSeverities[name]; !ok {
+				return fmt.Errorf("unknown syslog severity %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate verifies the parameters of the action and the template of the alert
+func (a *Alert) Validate() error {
+	if err := a.validateAction(); err != nil {
+		return fmt.Errorf("invalid action: %s", err)
+	}
+	if _, err := a.ParseTemplate(); err != nil {
+		return fmt.Errorf("invalid template: %s", err)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the alert without its secrets, the webhook
+// secret and the password of the action, that can be exposed by the API or
+// to the templates
+func (a *Alert) Redacted() *Alert {
+	redacted := *a
+	if a.Webhook != nil {
+		webhook := *a.Webhook
+		webhook.Secret = ""
+		redacted.Webhook = &webhook
+	}
+	if u, err := url.Parse(a.Action); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.User(u.User.Username())
+			redacted.Action = u.String()
+		}
+	}
+	return &redacted
+}
+
+// AlertWebhook describes how the notifications of an alert are delivered to
+// its webhook. A failed delivery is retried Retries times, waiting Backoff,
+// doubled at each retry. The payload is sent as JSON unless another
+// Content-Type is given in Headers, for instance for templated payloads. It
+// is signed when a Secret is given, the secret being write only.
+type AlertWebhook struct {
+	Timeout string            `json:",omitempty" valid:"isValidDuration"`
+	Retries int               `json:",omitempty" valid:"isPositiveInt"`
+	Backoff string            `json:",omitempty" valid:"isValidDuration"`
+	Headers map[string]string `json:",omitempty"`
+	Secret  string            `json:",omitempty"`
+}
+
 // NewAlert creates a New empty Alert, only UUID and CreateTime are set.
 func NewAlert() *Alert {
 	return &Alert{
@@ -68,6 +228,70 @@ func NewAlert() *Alert {
 	}
 }
 
+// AlertDelivery describes the outcome of the delivery of an alert
+// notification through the action of the alert
+type AlertDelivery struct {
+	Action  string
+	Success bool
+	Error   string `json:",omitempty"`
+}
+
+// AlertEvent describes a trigger of an alert, as recorded in its history.
+// Timestamp is in milliseconds. SilenceUUID is the silence that muted the
+// notification of the trigger, if any.
+type AlertEvent struct {
+	AlertUUID   string
+	Timestamp   int64
+	State       string
+	ReasonData  interface{}
+	Deliveries  []AlertDelivery `json:",omitempty"`
+	SilenceUUID string          `json:",omitempty"`
+}
+
+// Silence mutes the notifications of the alerts it matches from StartsAt
+// until EndsAt. An alert is matched by its UUID, by its name against the
+// NamePattern regular expression or when the nodes of its reason data are
+// selected by the NodeSelector Gremlin expression.
+type Silence struct {
+	BasicResource
+	AlertUUID    string `json:",omitempty"`
+	NamePattern  string `json:",omitempty"`
+	NodeSelector string `json:",omitempty" valid:"isOptionalGremlinExpr"`
+	Comment      string `json:",omitempty"`
+	StartsAt     time.Time
+	EndsAt       time.Time
+	CreateTime   time.Time
+}
+
+// NewSilence creates a new empty Silence, only CreateTime is set.
+func NewSilence() *Silence {
+	return &Silence{
+		CreateTime: time.Now().UTC(),
+	}
+}
+
+// Active returns whether the silence mutes the alerts at the given time
+func (s *Silence) Active(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Validate verifies the silence has a matcher and a valid time window
+func (s *Silence) Validate() error {
+	if s.AlertUUID == "" && s.NamePattern == "" && s.NodeSelector == "" {
+		return errors.New("an alert UUID, a name pattern or a node selector has to be specified")
+	}
+	if _, err := regexp.Compile(s.NamePattern); err != nil {
+		return fmt.Errorf("invalid name pattern: %s", err)
+	}
+	if s.EndsAt.IsZero() {
+		return errors.New("no end time specified")
+	}
+	if !s.StartsAt.IsZero() && !s.EndsAt.After(s.StartsAt) {
+		return errors.New("end time has to be after the start time")
+	}
+	return nil
+}
+
 // AnalyzerStatus describes the status of an analyzer
 type AnalyzerStatus struct {
 	Agents      map[string]shttp.WSConnStatus
@@ -82,20 +306,27 @@ type AnalyzerStatus struct {
 // Capture describes a capture API
 type Capture struct {
 	BasicResource
-	GremlinQuery   string `json:"GremlinQuery,omitempty" valid:"isGremlinExpr"`
-	BPFFilter      string `json:"BPFFilter,omitempty" valid:"isBPFFilter"`
-	Name           string `json:"Name,omitempty"`
-	Description    string `json:"Description,omitempty"`
-	Type           string `json:"Type,omitempty"`
-	Count          int    `json:"Count"`
-	PCAPSocket     string `json:"PCAPSocket,omitempty"`
-	Port           int    `json:"Port,omitempty"`
-	RawPacketLimit int    `json:"RawPacketLimit,omitempty" valid:"isValidRawPacketLimit"`
-	HeaderSize     int    `json:"HeaderSize,omitempty" valid:"isValidCaptureHeaderSize"`
-	ExtraTCPMetric bool   `json:"ExtraTCPMetric"`
-	IPDefrag       bool   `json:"IPDefrag"`
-	ReassembleTCP  bool   `json:"ReassembleTCP"`
-	LayerKeyMode   string `json:"LayerKeyMode,omitempty" valid:"isValidLayerKeyMode"`
+	GremlinQuery   string                 `json:"GremlinQuery,omitempty" valid:"isGremlinExpr"`
+	Bindings       map[string]interface{} `json:"Bindings,omitempty"`
+	BPFFilter      string                 `json:"BPFFilter,omitempty" valid:"isBPFFilter"`
+	Name           string                 `json:"Name,omitempty"`
+	Description    string                 `json:"Description,omitempty"`
+	Type           string                 `json:"Type,omitempty"`
+	Count          int                    `json:"Count"`
+	PCAPSocket     string                 `json:"PCAPSocket,omitempty"`
+	Port           int                    `json:"Port,omitempty"`
+	RawPacketLimit int                    `json:"RawPacketLimit,omitempty" valid:"isValidRawPacketLimit"`
+	HeaderSize     int                    `json:"HeaderSize,omitempty" valid:"isValidCaptureHeaderSize"`
+	ExtraTCPMetric bool                   `json:"ExtraTCPMetric"`
+	IPDefrag       bool                   `json:"IPDefrag"`
+	ReassembleTCP  bool                   `json:"ReassembleTCP"`
+	LayerKeyMode   string                 `json:"LayerKeyMode,omitempty" valid:"isValidLayerKeyMode"`
+	MaxFlows       int                    `json:"MaxFlows,omitempty" valid:"isPositiveInt"`
+	MaxMemory      int64                  `json:"MaxMemory,omitempty" valid:"isPositiveInt"`
+	EvictionPolicy string                 `json:"EvictionPolicy,omitempty" valid:"isValidEvictionPolicy"`
+	SamplingRate   int                    `json:"SamplingRate,omitempty" valid:"isPositiveInt"`
+	SamplingMode   string                 `json:"SamplingMode,omitempty" valid:"isValidSamplingMode"`
+	MaxPPS         int                    `json:"MaxPPS,omitempty" valid:"isPositiveInt"`
 }
 
 // NewCapture creates a new capture
@@ -149,7 +380,8 @@ type PeersStatus struct {
 
 // TopologyParam topology API parameter
 type TopologyParam struct {
-	GremlinQuery string `json:"GremlinQuery,omitempty" valid:"isGremlinExpr"`
+	GremlinQuery string                 `json:"GremlinQuery,omitempty" valid:"isGremlinExpr"`
+	Bindings     map[string]interface{} `json:"Bindings,omitempty"`
 }
 
 // UserMetadata describes a user metadata
@@ -23,10 +23,16 @@
 package client
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/skydive-project/skydive/api/client"
 	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/logging"
 	"github.com/skydive-project/skydive/validator"
 
@@ -39,8 +45,45 @@ var (
 	alertExpression  string
 	alertAction      string
 	alertTrigger     string
+	alertFor         string
+	alertTemplate    string
+	webhookTimeout   string
+	webhookRetries   int
+	webhookBackoff   string
+	webhookHeaders   []string
+	webhookSecret    string
+	historyFrom      string
+	historyTo        string
 )
 
+// webhookSettings returns the delivery settings of the webhook given by the
+// command line, nil if none was given
+func webhookSettings() (*types.AlertWebhook, error) {
+	if webhookTimeout == "" && webhookRetries == 0 && webhookBackoff == "" && len(webhookHeaders) == 0 && webhookSecret == "" {
+		return nil, nil
+	}
+
+	webhook := &types.AlertWebhook{
+		Timeout: webhookTimeout,
+		Retries: webhookRetries,
+		Backoff: webhookBackoff,
+		Secret:  webhookSecret,
+	}
+
+	for _, header := range webhookHeaders {
+		kv := strings.SplitN(header, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("Webhook header '%s' should be in the form 'Name: value'", header)
+		}
+		if webhook.Headers == nil {
+			webhook.Headers = make(map[string]string)
+		}
+		webhook.Headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return webhook, nil
+}
+
 // AlertCmd skydive alert root command
 var AlertCmd = &cobra.Command{
 	Use:          "alert",
@@ -67,6 +110,13 @@ var AlertCreate = &cobra.Command{
 		alert.Expression = alertExpression
 		alert.Trigger = alertTrigger
 		alert.Action = alertAction
+		alert.For = alertFor
+		alert.Template = alertTemplate
+
+		if alert.Webhook, err = webhookSettings(); err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
 
 		if err := validator.Validate(alert); err != nil {
 			logging.GetLogger().Error(err)
@@ -154,12 +204,72 @@ var AlertDelete = &cobra.Command{
 	},
 }
 
+// AlertHistory skydive alert history command
+var AlertHistory = &cobra.Command{
+	Use:   "history [alert]",
+	Short: "Display the history of an alert",
+	Long:  "Display the history of an alert",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := client.NewRestClientFromConfig(&AuthenticationOpts)
+		if err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+
+		values := url.Values{}
+		if historyFrom != "" {
+			values.Set("from", historyFrom)
+		}
+		if historyTo != "" {
+			values.Set("to", historyTo)
+		}
+
+		path := "alert/" + args[0] + "/history"
+		if len(values) > 0 {
+			path += "?" + values.Encode()
+		}
+
+		resp, err := client.Request("GET", path, nil, nil)
+		if err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			data, _ := ioutil.ReadAll(resp.Body)
+			logging.GetLogger().Errorf("Failed to get alert history, %s: %s", resp.Status, data)
+			os.Exit(1)
+		}
+
+		var events []types.AlertEvent
+		if err := common.JSONDecode(resp.Body, &events); err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+		printJSON(events)
+	},
+}
+
 func addAlertFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&alertName, "name", "", "", "alert name")
 	cmd.Flags().StringVarP(&alertDescription, "description", "", "", "description of the alert")
 	cmd.Flags().StringVarP(&alertTrigger, "trigger", "", "graph", "event that triggers the alert evaluation")
 	cmd.Flags().StringVarP(&alertExpression, "expression", "", "", "Gremlin of JavaScript expression evaluated to trigger the alarm")
-	cmd.Flags().StringVarP(&alertAction, "action", "", "", "can be either an empty string, or a URL (use 'file://' for local scripts)")
+	cmd.Flags().StringVarP(&alertAction, "action", "", "", "can be either an empty string, or a URL (use 'file://' for local scripts, 'smtp://' for emails, 'syslog://' for syslog)")
+	cmd.Flags().StringVarP(&alertFor, "for", "", "", "duration during which the expression has to be true before the alert fires, e.g. 30s")
+	cmd.Flags().StringVarP(&alertTemplate, "template", "", "", "Go text/template rendering the body of the notifications from .Alert, .Time, .State and .ReasonData")
+	cmd.Flags().StringVarP(&webhookTimeout, "webhook-timeout", "", "", "timeout of the webhook requests, e.g. 5s")
+	cmd.Flags().IntVarP(&webhookRetries, "webhook-retries", "", 0, "number of retries of a failed webhook request")
+	cmd.Flags().StringVarP(&webhookBackoff, "webhook-backoff", "", "", "delay before the first retry of a webhook request, doubled at each retry")
+	cmd.Flags().StringArrayVarP(&webhookHeaders, "webhook-header", "", []string{}, "header of the webhook requests, in the form 'Name: value'")
+	cmd.Flags().StringVarP(&webhookSecret, "webhook-secret", "", "", "secret used to sign the webhook payloads with HMAC-SHA256")
 }
 
 func init() {
@@ -167,6 +277,10 @@ func init() {
 	AlertCmd.AddCommand(AlertGet)
 	AlertCmd.AddCommand(AlertCreate)
 	AlertCmd.AddCommand(AlertDelete)
+	AlertCmd.AddCommand(AlertHistory)
 
 	addAlertFlags(AlertCreate)
+
+	AlertHistory.Flags().StringVarP(&historyFrom, "from", "", "", "only display the triggers since this RFC 3339 time")
+	AlertHistory.Flags().StringVarP(&historyTo, "to", "", "", "only display the triggers until this RFC 3339 time")
 }
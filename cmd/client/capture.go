@@ -48,6 +48,12 @@ var (
 	ipDefrag           bool
 	reassembleTCP      bool
 	layerKeyMode       string
+	maxFlows           int
+	maxMemory          int64
+	evictionPolicy     string
+	samplingRate       int
+	samplingMode       string
+	maxPPS             int
 )
 
 // CaptureCmd skdyive capture root command
@@ -90,6 +96,12 @@ var CaptureCreate = &cobra.Command{
 		capture.ReassembleTCP = reassembleTCP
 		capture.LayerKeyMode = layerKeyMode
 		capture.RawPacketLimit = rawPacketLimit
+		capture.MaxFlows = maxFlows
+		capture.MaxMemory = maxMemory
+		capture.EvictionPolicy = evictionPolicy
+		capture.SamplingRate = samplingRate
+		capture.SamplingMode = samplingMode
+		capture.MaxPPS = maxPPS
 
 		if err := validator.Validate(capture); err != nil {
 			logging.GetLogger().Error(err)
@@ -203,6 +215,12 @@ func addCaptureFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&ipDefrag, "ip-defrag", "", false, "Defragment IPv4 packets, default: false")
 	cmd.Flags().BoolVarP(&reassembleTCP, "reassamble-tcp", "", false, "Reassemble TCP packets, default: false")
 	cmd.Flags().StringVarP(&layerKeyMode, "layer-key-mode", "", "L2", "Defines the first layer used by flow key calculation, L2 or L3")
+	cmd.Flags().IntVarP(&maxFlows, "max-flows", "", 0, "Maximum number of flows of the flow table, 0 uses the agent limit")
+	cmd.Flags().Int64VarP(&maxMemory, "max-memory", "", 0, "Approximate memory budget of the flow table in bytes, 0 uses the agent limit")
+	cmd.Flags().StringVarP(&evictionPolicy, "eviction-policy", "", "", "Flows evicted first when a limit is reached, oldest-last-seen or smallest-flow")
+	cmd.Flags().IntVarP(&samplingRate, "sampling-rate", "", 0, "Process one packet out of N, metrics are scaled accordingly, 0 no sampling")
+	cmd.Flags().StringVarP(&samplingMode, "sampling-mode", "", "deterministic", "Packet sampling mode, deterministic or random")
+	cmd.Flags().IntVarP(&maxPPS, "max-pps", "", 0, "Maximum number of packets processed per second, 0 no limit")
 }
 
 func init() {
@@ -54,6 +54,7 @@ func RegisterClientCommands(cmd *cobra.Command) {
 	cmd.AddCommand(PcapCmd)
 	cmd.AddCommand(QueryCmd)
 	cmd.AddCommand(ShellCmd)
+	cmd.AddCommand(SilenceCmd)
 	cmd.AddCommand(StatusCmd)
 	cmd.AddCommand(TopologyCmd)
 	cmd.AddCommand(UserMetadataCmd)
@@ -0,0 +1,246 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/skydive-project/skydive/api/client"
+	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/validator"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	silenceAlert        string
+	silenceNamePattern  string
+	silenceNodeSelector string
+	silenceComment      string
+	silenceStartsAt     string
+	silenceEndsAt       string
+	silenceDuration     string
+)
+
+// SilenceCmd skydive silence root command
+var SilenceCmd = &cobra.Command{
+	Use:          "silence",
+	Short:        "Manage alert silences",
+	Long:         "Manage alert silences",
+	SilenceUsage: false,
+}
+
+// silenceWindow returns the time window of the silence given by the flags,
+// it starts now and lasts the given duration by default
+func silenceWindow() (startsAt time.Time, endsAt time.Time, err error) {
+	startsAt = time.Now().UTC()
+	if silenceStartsAt != "" {
+		if startsAt, err = time.Parse(time.RFC3339, silenceStartsAt); err != nil {
+			return
+		}
+	}
+
+	if silenceEndsAt != "" {
+		endsAt, err = time.Parse(time.RFC3339, silenceEndsAt)
+		return
+	}
+
+	duration, err := time.ParseDuration(silenceDuration)
+	if err != nil {
+		return
+	}
+	return startsAt, startsAt.Add(duration), nil
+}
+
+// SilenceCreate skydive silence create command
+var SilenceCreate = &cobra.Command{
+	Use:   "create",
+	Short: "Create silence",
+	Long:  "Create silence",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := client.NewCrudClientFromConfig(&AuthenticationOpts)
+		if err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+
+		silence := types.NewSilence()
+		silence.AlertUUID = silenceAlert
+		silence.NamePattern = silenceNamePattern
+		silence.NodeSelector = silenceNodeSelector
+		silence.Comment = silenceComment
+
+		if silence.StartsAt, silence.EndsAt, err = silenceWindow(); err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+
+		if err := validator.Validate(silence); err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+
+		if err := client.Create("silence", &silence); err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+		printJSON(&silence)
+	},
+}
+
+// SilenceList skydive silence list command
+var SilenceList = &cobra.Command{
+	Use:   "list",
+	Short: "List silences",
+	Long:  "List silences",
+	Run: func(cmd *cobra.Command, args []string) {
+		var silences map[string]types.Silence
+		client, err := client.NewCrudClientFromConfig(&AuthenticationOpts)
+		if err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+		if err := client.List("silence", &silences); err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+		printJSON(silences)
+	},
+}
+
+// SilenceGet skydive silence get command
+var SilenceGet = &cobra.Command{
+	Use:   "get [silence]",
+	Short: "Display silence",
+	Long:  "Display silence",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var silence types.Silence
+		client, err := client.NewCrudClientFromConfig(&AuthenticationOpts)
+		if err != nil {
+			logging.GetLogger().Critical(err.Error())
+			os.Exit(1)
+		}
+
+		if err := client.Get("silence", args[0], &silence); err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+		printJSON(&silence)
+	},
+}
+
+// SilenceExpire skydive silence expire command
+var SilenceExpire = &cobra.Command{
+	Use:   "expire [silence]",
+	Short: "Expire silence",
+	Long:  "End a silence now, the silence being kept in the list",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := client.NewRestClientFromConfig(&AuthenticationOpts)
+		if err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+
+		for _, id := range args {
+			resp, err := client.Request("POST", "silence/"+id+"/expire", nil, nil)
+			if err != nil {
+				logging.GetLogger().Error(err)
+				continue
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				data, _ := ioutil.ReadAll(resp.Body)
+				logging.GetLogger().Errorf("Failed to expire silence %s, %s: %s", id, resp.Status, data)
+				resp.Body.Close()
+				continue
+			}
+
+			var silence types.Silence
+			if err := common.JSONDecode(resp.Body, &silence); err != nil {
+				logging.GetLogger().Error(err)
+			} else {
+				printJSON(&silence)
+			}
+			resp.Body.Close()
+		}
+	},
+}
+
+// SilenceDelete skydive silence delete command
+var SilenceDelete = &cobra.Command{
+	Use:   "delete [silence]",
+	Short: "Delete silence",
+	Long:  "Delete silence",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := client.NewCrudClientFromConfig(&AuthenticationOpts)
+		if err != nil {
+			logging.GetLogger().Error(err)
+			os.Exit(1)
+		}
+
+		for _, id := range args {
+			if err := client.Delete("silence", id); err != nil {
+				logging.GetLogger().Error(err)
+			}
+		}
+	},
+}
+
+func init() {
+	SilenceCmd.AddCommand(SilenceList)
+	SilenceCmd.AddCommand(SilenceGet)
+	SilenceCmd.AddCommand(SilenceCreate)
+	SilenceCmd.AddCommand(SilenceExpire)
+	SilenceCmd.AddCommand(SilenceDelete)
+
+	SilenceCreate.Flags().StringVarP(&silenceAlert, "alert", "", "", "UUID of the alert to silence")
+	SilenceCreate.Flags().StringVarP(&silenceNamePattern, "name-pattern", "", "", "regular expression matching the names of the alerts to silence")
+	SilenceCreate.Flags().StringVarP(&silenceNodeSelector, "node-selector", "", "", "Gremlin expression selecting the nodes whose alerts are silenced")
+	SilenceCreate.Flags().StringVarP(&silenceComment, "comment", "", "", "comment on the silence, e.g. the reason of the maintenance")
+	SilenceCreate.Flags().StringVarP(&silenceStartsAt, "starts-at", "", "", "RFC 3339 time the silence starts, now by default")
+	SilenceCreate.Flags().StringVarP(&silenceEndsAt, "ends-at", "", "", "RFC 3339 time the silence ends")
+	SilenceCreate.Flags().StringVarP(&silenceDuration, "duration", "", "1h", "duration of the silence, when no end time is given")
+}
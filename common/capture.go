@@ -66,7 +66,7 @@ func initCaptureTypes() {
 	}
 
 	for _, t := range types {
-		CaptureTypes[t] = CaptureType{Allowed: []string{"afpacket", "pcap", "pcapsocket", "sflow", "ebpf"}, Default: "afpacket"}
+		CaptureTypes[t] = CaptureType{Allowed: []string{"afpacket", "pcap", "pcapsocket", "sflow", "netflow", "ipfix", "ebpf"}, Default: "afpacket"}
 	}
 }
 
@@ -559,6 +559,100 @@ func IPV4CIDRToRegex(cidr string) (string, error) {
 	return "^" + regex + `(\/[0-9]?[0-9])?$`, nil
 }
 
+// hextetToRegex returns a regex matching the hextets, written with or without
+// their leading zeros, whose value masked by the given mask equals the value,
+// along with whether the zero hextet matches
+func hextetToRegex(value, mask uint16) (string, bool) {
+	if mask == 0 {
+		return "[0-9a-f]{1,4}", true
+	}
+
+	// pattern of each hex digit, the most significant first
+	var digits [4]string
+	var zeros [4]bool
+	for i := range digits {
+		shift := uint(12 - 4*i)
+		v, m := (value>>shift)&0xf, (mask>>shift)&0xf
+
+		var class string
+		for d := uint16(0); d < 16; d++ {
+			if d&m == v&m {
+				class += strconv.FormatUint(uint64(d), 16)
+			}
+		}
+
+		switch len(class) {
+		case 1:
+			digits[i] = class
+		case 16:
+			digits[i] = "[0-9a-f]"
+		default:
+			digits[i] = "[" + class + "]"
+		}
+		zeros[i] = v&m == 0
+	}
+
+	// a hextet written with n digits has its 4-n first digits set to zero
+	var alternatives []string
+	for n := 4; n > 0; n-- {
+		alternatives = append(alternatives, strings.Join(digits[4-n:], ""))
+		if !zeros[4-n] {
+			break
+		}
+	}
+
+	return "(" + strings.Join(alternatives, "|") + ")", value&mask == 0
+}
+
+// IPV6CIDRToRegex returns a regex matching IPs belonging to a given IPv6 cidr.
+// The IPs are expected to be written in lower case, the zeros being
+// compressed or not.
+func IPV6CIDRToRegex(cidr string) (string, error) {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.Contains(cidr, ":") {
+		return "", fmt.Errorf("%s is not an IPv6 CIDR", cidr)
+	}
+
+	ones, _ := ipnet.Mask.Size()
+	ip := ipnet.IP.To16()
+
+	var hextets [8]string
+	var zeros [8]bool
+	for i := range hextets {
+		var mask uint16
+		if bits := ones - 16*i; bits >= 16 {
+			mask = 0xffff
+		} else if bits > 0 {
+			mask = 0xffff << uint(16-bits)
+		}
+		value := uint16(ip[2*i])<<8 | uint16(ip[2*i+1])
+		hextets[i], zeros[i] = hextetToRegex(value, mask)
+	}
+
+	// only the hextets of the prefix are checked, the following ones being
+	// compressed or not
+	prefix := (ones + 15) / 16
+
+	alternatives := []string{"[0-9a-f:]+"}
+	if prefix > 0 {
+		alternatives[0] = strings.Join(hextets[:prefix], ":") + "(:[0-9a-f:]*)?"
+	}
+
+	// the zero hextets of the prefix may be compressed by ::, the number of
+	// hextets following it giving the length of the compressed run
+	for i := 0; i < prefix; i++ {
+		for j := i + 1; j <= len(hextets) && zeros[j-1]; j++ {
+			alternatives = append(alternatives, strings.Join(hextets[:i], ":")+"::"+strings.Join(hextets[j:], ":"))
+		}
+	}
+
+	return "^(" + strings.Join(alternatives, "|") + `)(\/[0-9]?[0-9]?[0-9])?$`, nil
+}
+
 // IsIPv6 returns whether is a IPV6 addresses or not
 func IsIPv6(str string) bool {
 	ip := net.ParseIP(str)
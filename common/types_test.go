@@ -24,6 +24,8 @@ package common
 
 import (
 	"fmt"
+	"math/rand"
+	"net"
 	"reflect"
 	"regexp"
 	"testing"
@@ -84,6 +86,67 @@ func TestIPV4Range16(t *testing.T) {
 	}
 }
 
+func TestIPV6Range(t *testing.T) {
+	expr, err := IPV6CIDRToRegex("2001:db8:0:1::/64")
+	if err != nil {
+		t.Error(err)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, ip := range []string{"2001:db8:0:1::1", "2001:0db8:0000:0001::/64", "2001:db8:0:1:2:3:4:5", "2001:db8:0:1::"} {
+		if !re.MatchString(ip) {
+			t.Errorf("%s not matching the rexp %s", ip, expr)
+		}
+	}
+
+	for _, ip := range []string{"2001:db8::1", "2001:db8::1:0:0:1", "2001:db8:1:1::1", "::1", "192.168.0.1"} {
+		if re.MatchString(ip) {
+			t.Errorf("%s matches the rexp %s", ip, expr)
+		}
+	}
+
+	if _, err := IPV6CIDRToRegex("192.168.0.0/16"); err == nil {
+		t.Error("IPv4 CIDR should be rejected")
+	}
+}
+
+func TestIPV6RangeRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, cidr := range []string{"::/0", "fe80::/10", "2001:db8::/32", "2001:db8:0:1::/64", "0:0:1::/48", "::/96", "::1/128", "fd00:0:12::/47"} {
+		expr, err := IPV6CIDRToRegex(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re := regexp.MustCompile(expr)
+
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		for i := 0; i != 2000; i++ {
+			// random IPs around the network, with zero hextets
+			ip := make(net.IP, net.IPv6len)
+			copy(ip, ipnet.IP)
+			for j := r.Intn(net.IPv6len); j < net.IPv6len; j++ {
+				if r.Intn(3) == 0 {
+					ip[j] = byte(r.Intn(256))
+				} else {
+					ip[j] = 0
+				}
+			}
+
+			if ip.To4() != nil {
+				continue
+			}
+
+			if ipnet.Contains(ip) != re.MatchString(ip.String()) {
+				t.Errorf("%s: regex %s returns %v for %s", cidr, expr, !ipnet.Contains(ip), ip)
+			}
+		}
+	}
+}
+
 func TestNormalizeStructToMap(t *testing.T) {
 	type (
 		B struct {
@@ -88,10 +88,16 @@ func init() {
 	cfg.SetDefault("agent.topology.socketinfo.host_update", 10)
 	cfg.SetDefault("agent.X509_servername", "")
 
+	cfg.SetDefault("analyzer.alert.retry_queue.path", "/var/lib/skydive/alert/retry_queue.json")
+	cfg.SetDefault("analyzer.alert.retry_queue.size", 1000)
+	cfg.SetDefault("analyzer.alert.webhook_timeout", 10)
 	cfg.SetDefault("analyzer.auth.cluster.backend", "noauth")
 	cfg.SetDefault("analyzer.auth.api.backend", "noauth")
 	cfg.SetDefault("analyzer.flow.backend", "memory")
 	cfg.SetDefault("analyzer.flow.max_buffer_size", 100000)
+	cfg.SetDefault("analyzer.flow.ipfix.domain_id", 0)
+	cfg.SetDefault("analyzer.flow.ipfix.enterprise_number", 2312)
+	cfg.SetDefault("analyzer.flow.ipfix.template_refresh", 60)
 	cfg.SetDefault("analyzer.listen", "127.0.0.1:8082")
 	cfg.SetDefault("analyzer.replication.debug", false)
 	cfg.SetDefault("analyzer.topology.backend", "memory")
@@ -118,6 +124,9 @@ func init() {
 	cfg.SetDefault("flow.update", 60)
 	cfg.SetDefault("flow.protocol", "udp")
 
+	cfg.SetDefault("gremlin.query_timeout", 30)
+	cfg.SetDefault("gremlin.max_elements", 0)
+
 	cfg.SetDefault("host_id", host)
 
 	cfg.SetDefault("http.rest.debug", false)
@@ -135,6 +144,9 @@ func init() {
 	cfg.SetDefault("logging.level", "INFO")
 	cfg.SetDefault("logging.syslog.tag", "skydive")
 
+	cfg.SetDefault("netflow.port_min", 2056)
+	cfg.SetDefault("netflow.port_max", 2066)
+
 	cfg.SetDefault("netns.run_path", "/var/run/netns")
 
 	cfg.SetDefault("opencontrail.host", "localhost")
@@ -75,6 +75,9 @@ func (f *Filter) Eval(g Getter) bool {
 	if f.IPV4RangeFilter != nil {
 		return f.IPV4RangeFilter.Eval(g)
 	}
+	if f.IPV6RangeFilter != nil {
+		return f.IPV6RangeFilter.Eval(g)
+	}
 
 	return true
 }
@@ -95,56 +98,53 @@ func (b *BoolFilter) Eval(g Getter) bool {
 	return b.Op == BoolFilterOp_AND || len(b.Filters) == 0
 }
 
-// Eval evaluates an int64 > filter
-func (r *GtInt64Filter) Eval(g Getter) bool {
-	field, err := g.GetFieldInt64(r.Key)
+// evalInt64 evaluates a predicate against an int64 field. For fields holding
+// multiple values, the predicate has to match one of them.
+func evalInt64(g Getter, key string, predicate func(int64) bool) bool {
+	if field, err := g.GetField(key); err == nil {
+		switch field := field.(type) {
+		case []int64:
+			for _, v := range field {
+				if predicate(v) {
+					return true
+				}
+			}
+			return false
+		case []interface{}:
+			for _, intf := range field {
+				if v, err := common.ToInt64(intf); err == nil && predicate(v) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	field, err := g.GetFieldInt64(key)
 	if err != nil {
 		return false
 	}
+	return predicate(field)
+}
 
-	if field > r.Value {
-		return true
-	}
-	return false
+// Eval evaluates an int64 > filter
+func (r *GtInt64Filter) Eval(g Getter) bool {
+	return evalInt64(g, r.Key, func(field int64) bool { return field > r.Value })
 }
 
 // Eval evaluates an int64 < filter
 func (r *LtInt64Filter) Eval(g Getter) bool {
-	field, err := g.GetFieldInt64(r.Key)
-	if err != nil {
-		return false
-	}
-
-	if field < r.Value {
-		return true
-	}
-	return false
+	return evalInt64(g, r.Key, func(field int64) bool { return field < r.Value })
 }
 
 // Eval evaluates an int64 >= filter
 func (r *GteInt64Filter) Eval(g Getter) bool {
-	field, err := g.GetFieldInt64(r.Key)
-	if err != nil {
-		return false
-	}
-
-	if field >= r.Value {
-		return true
-	}
-	return false
+	return evalInt64(g, r.Key, func(field int64) bool { return field >= r.Value })
 }
 
 // Eval evaluates an int64 <= filter
 func (r *LteInt64Filter) Eval(g Getter) bool {
-	field, err := g.GetFieldInt64(r.Key)
-	if err != nil {
-		return false
-	}
-
-	if field <= r.Value {
-		return true
-	}
-	return false
+	return evalInt64(g, r.Key, func(field int64) bool { return field <= r.Value })
 }
 
 // Eval evaluates an string type filter
@@ -329,6 +329,56 @@ func NewIPV4RangeFilter(key, cidr string) (*IPV4RangeFilter, error) {
 	return &IPV4RangeFilter{Key: key, Value: cidr}, nil
 }
 
+// Eval evaluates an ipv6 range filter
+func (r *IPV6RangeFilter) Eval(g Getter) bool {
+	field, err := g.GetField(r.Key)
+	if err != nil {
+		return false
+	}
+
+	re, found := regexpCache.Get(r.Value)
+	if !found {
+		// ignore error at this point should have been check in the contructor
+		regex, _ := common.IPV6CIDRToRegex(r.Value)
+		re = regexp.MustCompile(regex)
+		regexpCache.Set(r.Value, re, cache.DefaultExpiration)
+	}
+
+	switch field := field.(type) {
+	case []interface{}:
+		for _, intf := range field {
+			if s, ok := intf.(string); ok && re.(*regexp.Regexp).MatchString(s) {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range field {
+			if re.(*regexp.Regexp).MatchString(s) {
+				return true
+			}
+		}
+	case string:
+		return re.(*regexp.Regexp).MatchString(field)
+	}
+
+	return false
+}
+
+// NewIPV6RangeFilter creates a regex based filter corresponding to the ip range
+func NewIPV6RangeFilter(key, cidr string) (*IPV6RangeFilter, error) {
+	regex, err := common.IPV6CIDRToRegex(cidr)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Set(cidr, re, cache.DefaultExpiration)
+
+	return &IPV6RangeFilter{Key: key, Value: cidr}, nil
+}
+
 // NewBoolFilter creates a new boolean filter
 func NewBoolFilter(op BoolFilterOp, filters ...*Filter) *Filter {
 	boolFilter := &BoolFilter{
@@ -36,6 +36,13 @@ type TableAllocator struct {
 	expire   time.Duration
 	tables   map[*Table]bool
 	pipeline *EnhancerPipeline
+	evicted  int64
+}
+
+// TableAllocatorStatus describes the status of the allocated tables
+type TableAllocatorStatus struct {
+	Tables       int
+	EvictedFlows int64
 }
 
 // Expire returns the expire parameter used by allocated tables
@@ -99,9 +106,27 @@ func (a *TableAllocator) Alloc(flowCallBack ExpireUpdateFunc, nodeTID string, op
 func (a *TableAllocator) Release(t *Table) {
 	a.Lock()
 	delete(a.tables, t)
+	a.evicted += t.EvictedFlows()
 	a.Unlock()
 }
 
+// Status returns the number of allocated tables and the number of flows
+// evicted by all the tables since the allocator creation
+func (a *TableAllocator) Status() TableAllocatorStatus {
+	a.RLock()
+	defer a.RUnlock()
+
+	status := TableAllocatorStatus{
+		Tables:       len(a.tables),
+		EvictedFlows: a.evicted,
+	}
+	for table := range a.tables {
+		status.EvictedFlows += table.EvictedFlows()
+	}
+
+	return status
+}
+
 // NewTableAllocator creates a new flow table
 func NewTableAllocator(update, expire time.Duration, pipeline *EnhancerPipeline) *TableAllocator {
 	return &TableAllocator{
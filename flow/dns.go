@@ -0,0 +1,200 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"encoding/binary"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+const (
+	// DNSPort well known DNS port
+	DNSPort = 53
+	// maxDNSRecords maximum number of queries, answers or pending queries kept per flow
+	maxDNSRecords = 32
+)
+
+func appendDNSRecord(records []string, record string) []string {
+	if len(records) >= maxDNSRecords {
+		return records
+	}
+
+	for _, r := range records {
+		if r == record {
+			return records
+		}
+	}
+	return append(records, record)
+}
+
+func isDNSFlow(f *Flow) bool {
+	if f.Transport == nil || (f.Transport.A != DNSPort && f.Transport.B != DNSPort) {
+		return false
+	}
+	return f.Transport.Protocol == FlowProtocol_UDP || f.Transport.Protocol == FlowProtocol_TCP
+}
+
+// decodeDNS decodes the DNS message carried by the transport layer of the packet
+func decodeDNS(packet *Packet) (*layers.DNS, error) {
+	transportLayer := packet.TransportLayer()
+	if transportLayer == nil {
+		return nil, ErrLayerNotFound
+	}
+
+	data := transportLayer.LayerPayload()
+
+	// DNS over TCP messages are prefixed by their length, only consider
+	// segments starting with a message
+	if transportLayer.LayerType() == layers.LayerTypeTCP {
+		if len(data) < 2 || int(binary.BigEndian.Uint16(data)) > len(data)-2 {
+			return nil, ErrLayerNotFound
+		}
+		data = data[2:]
+	}
+
+	if len(data) == 0 {
+		return nil, ErrLayerNotFound
+	}
+
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	}
+
+	return dns, nil
+}
+
+func (f *Flow) updateDNSLayer(packet *Packet) error {
+	if !isDNSFlow(f) {
+		return nil
+	}
+
+	dns, err := decodeDNS(packet)
+	if err != nil {
+		return err
+	}
+
+	if f.DNS == nil {
+		f.DNS = &DNSLayer{}
+	}
+
+	for _, question := range dns.Questions {
+		f.DNS.Query = appendDNSRecord(f.DNS.Query, string(question.Name))
+		f.DNS.QueryType = appendDNSRecord(f.DNS.QueryType, question.Type.String())
+	}
+
+	now := packet.GoPacket.Metadata().CaptureInfo.Timestamp.UnixNano()
+
+	if !dns.QR {
+		if f.XXX_state.dnsQueries == nil {
+			f.XXX_state.dnsQueries = make(map[uint16]int64)
+		}
+		if _, ok := f.XXX_state.dnsQueries[dns.ID]; !ok && len(f.XXX_state.dnsQueries) < maxDNSRecords {
+			f.XXX_state.dnsQueries[dns.ID] = now
+		}
+		return nil
+	}
+
+	f.DNS.ResponseCode = dns.ResponseCode.String()
+
+	for _, answer := range dns.Answers {
+		switch answer.Type {
+		case layers.DNSTypeA, layers.DNSTypeAAAA:
+			f.DNS.Answer = appendDNSRecord(f.DNS.Answer, answer.IP.String())
+		case layers.DNSTypeCNAME:
+			f.DNS.Answer = appendDNSRecord(f.DNS.Answer, string(answer.CNAME))
+		case layers.DNSTypePTR:
+			f.DNS.Answer = appendDNSRecord(f.DNS.Answer, string(answer.PTR))
+		}
+	}
+
+	if start, ok := f.XXX_state.dnsQueries[dns.ID]; ok {
+		f.DNS.Latency = now - start
+		delete(f.XXX_state.dnsQueries, dns.ID)
+	}
+
+	return nil
+}
+
+// GetStringField returns the value of a DNS field, the first one
+// for fields holding multiple values
+func (d *DNSLayer) GetStringField(field string) (string, error) {
+	if d == nil {
+		return "", common.ErrFieldNotFound
+	}
+
+	var values []string
+	switch field {
+	case "ResponseCode":
+		return d.ResponseCode, nil
+	case "Query":
+		values = d.Query
+	case "QueryType":
+		values = d.QueryType
+	case "Answer":
+		values = d.Answer
+	default:
+		return "", common.ErrFieldNotFound
+	}
+
+	if len(values) == 0 {
+		return "", common.ErrFieldNotFound
+	}
+	return values[0], nil
+}
+
+// GetFieldInt64 returns the value of a DNS field
+func (d *DNSLayer) GetFieldInt64(field string) (int64, error) {
+	if d == nil {
+		return 0, common.ErrFieldNotFound
+	}
+
+	switch field {
+	case "Latency":
+		return d.Latency, nil
+	default:
+		return 0, common.ErrFieldNotFound
+	}
+}
+
+// GetFieldInterface returns the value of a DNS field
+func (d *DNSLayer) GetFieldInterface(field string) (interface{}, error) {
+	if d == nil {
+		return nil, common.ErrFieldNotFound
+	}
+
+	switch field {
+	case "Query":
+		return d.Query, nil
+	case "QueryType":
+		return d.QueryType, nil
+	case "Answer":
+		return d.Answer, nil
+	default:
+		return nil, common.ErrFieldNotFound
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/filters"
+)
+
+func TestFlowDNS(t *testing.T) {
+	flows := flowsFromPCAP(t, "pcaptraces/eth-ip4-arp-dns-req-http-google.pcap", layers.LinkTypeEthernet, nil)
+
+	var dnsFlow *Flow
+	for _, f := range flows {
+		if f.Transport != nil && f.Transport.A == 33553 && f.Transport.B == 53 {
+			dnsFlow = f
+		}
+	}
+
+	if dnsFlow == nil || dnsFlow.DNS == nil {
+		t.Fatalf("DNS flow not found: %+v", flows)
+	}
+
+	expected := &DNSLayer{
+		Query:        []string{"www.google.fr"},
+		QueryType:    []string{"A", "AAAA"},
+		ResponseCode: layers.DNSResponseCodeNoErr.String(),
+		Answer:       []string{"216.58.211.67", "2a00:1450:4007:80b::2003"},
+		Latency:      9922000,
+	}
+
+	if !reflect.DeepEqual(expected, dnsFlow.DNS) {
+		t.Errorf("DNS layer mismatch, expected %+v, got %+v", expected, dnsFlow.DNS)
+	}
+
+	if query, err := dnsFlow.GetFieldString("DNS.Query"); err != nil || query != "www.google.fr" {
+		t.Errorf("Wrong DNS.Query field: %s (%v)", query, err)
+	}
+
+	filter := filters.NewTermStringFilter("DNS.Answer", "2a00:1450:4007:80b::2003")
+	if !filter.Eval(dnsFlow) {
+		t.Error("DNS flow should match answer filter")
+	}
+
+	filter = filters.NewNotNullFilter("DNS.Query")
+	for _, f := range flows {
+		if f.DNS == nil && filter.Eval(f) {
+			t.Errorf("Flow without DNS layer should not match: %+v", f)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/logging"
+)
+
+// EvictionPolicy defines which flows are removed first when a flow table
+// reaches one of its limits
+type EvictionPolicy int
+
+const (
+	DefaultEvictionPolicy                = OldestLastSeenPolicy // default policy
+	OldestLastSeenPolicy  EvictionPolicy = 0                    // evicts the flows with the oldest last packet first
+	SmallestFlowPolicy    EvictionPolicy = 1                    // evicts the flows with the fewest packets first
+)
+
+const (
+	// flowBaseMemory is the approximate memory used by a flow, its layers
+	// and its internal state, raw packets excluded
+	flowBaseMemory = 2048
+
+	// when a limit is reached, flows are evicted until the table goes under
+	// this ratio of the limit so that evictions are done by batch
+	evictionWatermark = 0.9
+)
+
+func (e EvictionPolicy) String() string {
+	if e == SmallestFlowPolicy {
+		return "smallest-flow"
+	}
+	return "oldest-last-seen"
+}
+
+// EvictionPolicyByName returns the eviction policy for the given name
+func EvictionPolicyByName(name string) (EvictionPolicy, error) {
+	switch name {
+	case "oldest-last-seen":
+		return OldestLastSeenPolicy, nil
+	case "smallest-flow":
+		return SmallestFlowPolicy, nil
+	}
+	return DefaultEvictionPolicy, errors.New("EvictionPolicy unknown")
+}
+
+// DefaultEvictionPolicyName returns the name of the eviction policy used by default
+func DefaultEvictionPolicyName() string {
+	policy := config.GetString("flow.eviction_policy")
+	if policy == "" {
+		policy = DefaultEvictionPolicy.String()
+	}
+	return policy
+}
+
+// flowMemorySize returns the approximate memory used by a flow
+func flowMemorySize(f *Flow) int64 {
+	size := int64(flowBaseMemory)
+	for _, packet := range f.LastRawPackets {
+		size += int64(len(packet.Data))
+	}
+	for _, pending := range f.XXX_state.tlsPending {
+		size += int64(len(pending))
+	}
+	return size
+}
+
+// resizeFlow updates the memory accounted for the flow
+func (ft *Table) resizeFlow(f *Flow) {
+	size := flowMemorySize(f)
+	ft.memory += size - f.XXX_state.memorySize
+	f.XXX_state.memorySize = size
+}
+
+// removeFlow removes the flow from the table without notifying
+func (ft *Table) removeFlow(key string, f *Flow) {
+	delete(ft.table, key)
+	ft.memory -= f.XXX_state.memorySize
+}
+
+func (ft *Table) isOverLimits(flows int, memory int64, ratio float64) bool {
+	return (ft.Opts.MaxFlows > 0 && float64(flows) > float64(ft.Opts.MaxFlows)*ratio) ||
+		(ft.Opts.MaxMemory > 0 && float64(memory) > float64(ft.Opts.MaxMemory)*ratio)
+}
+
+type evictionCandidate struct {
+	key  string
+	flow *Flow
+}
+
+func (ft *Table) sortEvictionCandidates(candidates []evictionCandidate) {
+	less := func(i, j int) bool {
+		return candidates[i].flow.Last < candidates[j].flow.Last
+	}
+
+	if ft.Opts.EvictionPolicy == SmallestFlowPolicy {
+		less = func(i, j int) bool {
+			mi, mj := candidates[i].flow.Metric, candidates[j].flow.Metric
+			pi, pj := mi.ABPackets+mi.BAPackets, mj.ABPackets+mj.BAPackets
+			if pi != pj {
+				return pi < pj
+			}
+			return candidates[i].flow.Last < candidates[j].flow.Last
+		}
+	}
+
+	sort.Slice(candidates, less)
+}
+
+// evict removes flows according to the eviction policy so that newFlows
+// flows can be added without exceeding the table limits. Evicted flows are
+// sent through the expire handler.
+func (ft *Table) evict(newFlows int) {
+	newMemory := int64(newFlows) * flowBaseMemory
+	if !ft.isOverLimits(len(ft.table)+newFlows, ft.memory+newMemory, 1) {
+		return
+	}
+
+	candidates := make([]evictionCandidate, 0, len(ft.table))
+	for k, f := range ft.table {
+		candidates = append(candidates, evictionCandidate{key: k, flow: f})
+	}
+	ft.sortEvictionCandidates(candidates)
+
+	var evictedFlows []*Flow
+	for _, c := range candidates {
+		if !ft.isOverLimits(len(ft.table)+newFlows, ft.memory+newMemory, evictionWatermark) {
+			break
+		}
+
+		if c.flow.XXX_state.updateVersion > ft.updateVersion {
+			ft.updateMetric(c.flow, ft.lastUpdate, c.flow.Last)
+		}
+
+		evictedFlows = append(evictedFlows, c.flow)
+		ft.removeFlow(c.key, c.flow)
+	}
+
+	atomic.AddInt64(&ft.evicted, int64(len(evictedFlows)))
+
+	logging.GetLogger().Debugf("Evict flows : removed %d ; new size %d, memory %d", len(evictedFlows), len(ft.table), ft.memory)
+
+	if ft.expireHandler != nil {
+		ft.expireHandler.callback(evictedFlows)
+	}
+}
+
+// EvictedFlows returns the number of flows evicted since the table creation
+func (ft *Table) EvictedFlows() int64 {
+	return atomic.LoadInt64(&ft.evicted)
+}
@@ -65,6 +65,13 @@ type flowState struct {
 	link1stPacket    int64
 	network1stPacket int64
 	updateVersion    int64
+	dnsQueries       map[uint16]int64
+	httpPending      []*httpTransaction
+	appInspected     uint8
+	appDetected      bool
+	tlsPending       [2][]byte
+	tlsDecoded       uint8
+	memorySize       int64
 }
 
 // Packet describes one packet
@@ -76,6 +83,9 @@ type Packet struct {
 	Length   int64            // length of the original packet meaning layers + payload
 	IPMetric *IPMetric
 
+	SamplingRate   int64 // sampling rate applied to the packet
+	sampledPackets int64 // number of packets this packet stands for when sampled
+
 	linkLayer        gopacket.LinkLayer        // fast access to link layer
 	networkLayer     gopacket.NetworkLayer     // fast access to network layer
 	transportLayer   gopacket.TransportLayer   // fast access to transport layer
@@ -85,6 +95,7 @@ type Packet struct {
 // PacketSequence represents a suite of parent/child Packet
 type PacketSequence struct {
 	Packets []*Packet
+	sampled bool // whether the capture sampling was already applied
 }
 
 // RawPackets embeds flow RawPacket array with the associated link type
@@ -108,6 +119,7 @@ type FlowOpts struct {
 	IPDefrag     bool
 	LayerKeyMode LayerKeyMode
 	AppPortMap   *ApplicationPortMap
+	AppDetector  *ApplicationDetector
 }
 
 // FlowUUIDs describes UUIDs that can be applied to flows
@@ -142,6 +154,17 @@ func DefaultLayerKeyModeName() string {
 	return mode
 }
 
+// sampledCount returns the number of packets the packet stands for
+func (p *Packet) sampledCount() int64 {
+	if p.sampledPackets > 1 {
+		return p.sampledPackets
+	}
+	if p.SamplingRate > 1 {
+		return p.SamplingRate
+	}
+	return 1
+}
+
 // Layer returns the given layer type
 func (p *Packet) Layer(t gopacket.LayerType) gopacket.Layer {
 	for _, l := range p.Layers {
@@ -194,6 +217,16 @@ func (p *Packet) TransportLayer() gopacket.TransportLayer {
 	return p.transportLayer
 }
 
+// packetDirection returns 1 for the packets of the flow sent from its A
+// endpoint, 2 for the ones sent from its B endpoint
+func (f *Flow) packetDirection(networkLayer gopacket.NetworkLayer, transportLayer gopacket.TransportLayer) uint8 {
+	if src, port := networkLayer.NetworkFlow().Src().String(), transportLayer.TransportFlow().Src().String(); src != f.Network.A ||
+		(f.Network.A == f.Network.B && port != strconv.FormatInt(f.Transport.A, 10)) {
+		return 2
+	}
+	return 1
+}
+
 // ApplicationFlow returns first application flow
 func (p *Packet) ApplicationFlow() (gopacket.Flow, error) {
 	if layer := p.Layer(layers.LayerTypeICMPv4); layer != nil {
@@ -367,6 +400,12 @@ func networkID(p *Packet) int64 {
 		if layer.LayerType() == layers.LayerTypeGeneve {
 			return int64(layer.(*layers.Geneve).VNI)
 		}
+		if layer.LayerType() == LayerTypeERSPAN {
+			return int64(layer.(*ERSPAN).SessionID)
+		}
+		if layer.LayerType() == LayerTypeGTPv1U {
+			return int64(layer.(*GTPv1U).TEID)
+		}
 	}
 	return id
 }
@@ -510,6 +549,13 @@ func (f *Flow) Update(packet *Packet, opts FlowOpts) {
 	f.Last = now
 	f.Metric.Last = now
 
+	if packet.SamplingRate > 1 {
+		f.SamplingRate = packet.SamplingRate
+	}
+	if packet.sampledCount() > 1 {
+		f.EstimatedMetric = true
+	}
+
 	if opts.LayerKeyMode == L3PreferedKeyMode {
 		// use the ethernet length as we want to get the full size and we want to
 		// rely on the l3 address order.
@@ -529,6 +575,9 @@ func (f *Flow) Update(packet *Packet, opts FlowOpts) {
 	if f.TCPMetric != nil {
 		f.updateTCPMetrics(packet)
 	}
+	f.updateDNSLayer(packet)
+	f.updateTLSLayer(packet)
+	f.detectApplication(packet, opts)
 }
 
 func (f *Flow) newLinkLayer(packet *Packet) error {
@@ -579,11 +628,11 @@ func (f *Flow) updateMetricsWithLinkLayer(packet *Packet) bool {
 	}
 
 	if f.Link.A == ethernetPacket.SrcMAC.String() {
-		f.Metric.ABPackets++
-		f.Metric.ABBytes += length
+		f.Metric.ABPackets += packet.sampledCount()
+		f.Metric.ABBytes += length * packet.sampledCount()
 	} else {
-		f.Metric.BAPackets++
-		f.Metric.BABytes += length
+		f.Metric.BAPackets += packet.sampledCount()
+		f.Metric.BABytes += length * packet.sampledCount()
 	}
 
 	if f.XXX_state.link1stPacket == 0 {
@@ -649,11 +698,11 @@ func (f *Flow) updateMetricsWithNetworkLayer(packet *Packet, length int64) error
 			length = int64(ipv4Packet.Length)
 		}
 		if f.Network.A == ipv4Packet.SrcIP.String() {
-			f.Metric.ABPackets++
-			f.Metric.ABBytes += length
+			f.Metric.ABPackets += packet.sampledCount()
+			f.Metric.ABBytes += length * packet.sampledCount()
 		} else {
-			f.Metric.BAPackets++
-			f.Metric.BABytes += length
+			f.Metric.BAPackets += packet.sampledCount()
+			f.Metric.BABytes += length * packet.sampledCount()
 		}
 
 		// update RTT
@@ -672,11 +721,11 @@ func (f *Flow) updateMetricsWithNetworkLayer(packet *Packet, length int64) error
 			length = int64(ipv6Packet.Length)
 		}
 		if f.Network.A == ipv6Packet.SrcIP.String() {
-			f.Metric.ABPackets++
-			f.Metric.ABBytes += length
+			f.Metric.ABPackets += packet.sampledCount()
+			f.Metric.ABBytes += length * packet.sampledCount()
 		} else {
-			f.Metric.BAPackets++
-			f.Metric.BABytes += length
+			f.Metric.BAPackets += packet.sampledCount()
+			f.Metric.BABytes += length * packet.sampledCount()
 		}
 
 		// update RTT
@@ -866,35 +915,28 @@ func PacketSeqFromGoPacket(packet gopacket.Packet, outerLength int64, bpf *BPF,
 		length -= len(layer.LayerContents())
 		offset += len(layer.LayerContents())
 
-		switch layer.LayerType() {
-		case layers.LayerTypeGRE:
-			// If the next layer type is MPLS, we don't
-			// creates the tunneling packet at this level, but at the next one.
-			if i < len(packetLayers)-2 && packetLayers[i+1].LayerType() == layers.LayerTypeMPLS {
-				continue
-			}
-			fallthrough
-			// We don't split on vlan layers.LayerTypeDot1Q
-		case layers.LayerTypeVXLAN, layers.LayerTypeMPLS, layers.LayerTypeGeneve:
-			p := &Packet{
-				GoPacket: packet,
-				Layers:   packetLayers[topLayerIndex : i+1],
-				Data:     packetData[topLayerOffset:],
-				Length:   int64(topLayerLength),
-			}
-			// As this is the top flow, we can use the layer pointer from GoPacket
-			// This avoid to parse them later.
-			if len(ps.Packets) == 0 {
-				p.networkLayer = packet.NetworkLayer()
-				p.transportLayer = packet.TransportLayer()
-			}
-
-			ps.Packets = append(ps.Packets, p)
+		if !isEncapsulation(packetLayers, i) {
+			continue
+		}
 
-			topLayerIndex = i + 1
-			topLayerLength = length
-			topLayerOffset = offset
+		p := &Packet{
+			GoPacket: packet,
+			Layers:   packetLayers[topLayerIndex : i+1],
+			Data:     packetData[topLayerOffset:],
+			Length:   int64(topLayerLength),
+		}
+		// As this is the top flow, we can use the layer pointer from GoPacket
+		// This avoid to parse them later.
+		if len(ps.Packets) == 0 {
+			p.networkLayer = packet.NetworkLayer()
+			p.transportLayer = packet.TransportLayer()
 		}
+
+		ps.Packets = append(ps.Packets, p)
+
+		topLayerIndex = i + 1
+		topLayerLength = length
+		topLayerOffset = offset
 	}
 
 	p := &Packet{
@@ -1148,6 +1190,15 @@ func (f *Flow) GetFieldString(field string) (string, error) {
 		return f.Network.GetStringField(fields[1])
 	case "ETHERNET":
 		return f.Link.GetStringField(fields[1])
+	case "DNS":
+		return f.DNS.GetStringField(fields[1])
+	case "HTTP":
+		if len(f.HTTP) == 0 {
+			return "", common.ErrFieldNotFound
+		}
+		return f.HTTP[0].GetStringField(fields[1])
+	case "TLS":
+		return f.TLS.GetStringField(fields[1])
 	}
 	return "", common.ErrFieldNotFound
 }
@@ -1161,6 +1212,8 @@ func (f *Flow) GetFieldInt64(field string) (_ int64, err error) {
 		return f.Start, nil
 	case "RTT":
 		return f.RTT, nil
+	case "SamplingRate":
+		return f.SamplingRate, nil
 	}
 
 	fields := strings.Split(field, ".")
@@ -1185,6 +1238,13 @@ func (f *Flow) GetFieldInt64(field string) (_ int64, err error) {
 		return f.ICMP.GetFieldInt64(fields[1])
 	case "Transport":
 		return f.Transport.GetFieldInt64(fields[1])
+	case "DNS":
+		return f.DNS.GetFieldInt64(fields[1])
+	case "HTTP":
+		if len(f.HTTP) == 0 {
+			return 0, common.ErrFieldNotFound
+		}
+		return f.HTTP[0].GetFieldInt64(fields[1])
 	case "RawPacketsCaptured":
 		return f.RawPacketsCaptured, nil
 	default:
@@ -1209,9 +1269,25 @@ func (f *Flow) GetFieldInterface(field string) (_ interface{}, err error) {
 		return f.ICMP, nil
 	case "Transport":
 		return f.Transport, nil
-	default:
-		return 0, common.ErrFieldNotFound
+	case "DNS":
+		return f.DNS, nil
+	case "HTTP":
+		return f.HTTP, nil
+	case "TLS":
+		return f.TLS, nil
+	}
+
+	// sub fields holding multiple values
+	fields := strings.Split(field, ".")
+	if len(fields) == 2 {
+		switch fields[0] {
+		case "DNS":
+			return f.DNS.GetFieldInterface(fields[1])
+		case "HTTP":
+			return f.httpFieldInterface(fields[1])
+		}
 	}
+	return 0, common.ErrFieldNotFound
 }
 
 // GetField returns the value of a field
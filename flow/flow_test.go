@@ -838,6 +838,206 @@ func TestGREEthernet(t *testing.T) {
 	validatePCAP(t, "pcaptraces/gre-gre-icmpv4.pcap", layers.LinkTypeEthernet, nil, expected)
 }
 
+func TestERSPAN(t *testing.T) {
+	expected := []*Flow{
+		{
+			LayersPath:  "Ethernet/IPv4/GRE/ERSPAN",
+			Application: "ERSPAN",
+			Link: &FlowLayer{
+				Protocol: FlowProtocol_ETHERNET,
+				A:        "00:00:5e:00:53:01",
+				B:        "00:00:5e:00:53:02",
+				ID:       0,
+			},
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "192.0.2.1",
+				B:        "192.0.2.2",
+				ID:       100,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 2,
+				ABBytes:   296,
+				BAPackets: 0,
+				BABytes:   0,
+			},
+		},
+		{
+			LayersPath:  "Ethernet/IPv4/ICMPv4",
+			Application: "ICMPv4",
+			Link: &FlowLayer{
+				Protocol: FlowProtocol_ETHERNET,
+				A:        "02:00:00:00:00:01",
+				B:        "02:00:00:00:00:02",
+				ID:       0,
+			},
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "10.0.0.1",
+				B:        "10.0.0.2",
+				ID:       0,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   98,
+				BAPackets: 1,
+				BABytes:   98,
+			},
+		},
+	}
+
+	validatePCAP(t, "pcaptraces/erspan-ii-icmpv4.pcap", layers.LinkTypeEthernet, nil, expected)
+}
+
+func TestIPIP(t *testing.T) {
+	expected := []*Flow{
+		{
+			LayersPath:  "Ethernet/IPv4",
+			Application: "IPv4",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "192.0.2.1",
+				B:        "192.0.2.2",
+				ID:       0,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   118,
+				BAPackets: 1,
+				BABytes:   118,
+			},
+		},
+		{
+			LayersPath:  "IPv4/ICMPv4",
+			Application: "ICMPv4",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "10.0.0.1",
+				B:        "10.0.0.2",
+				ID:       0,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   84,
+				BAPackets: 1,
+				BABytes:   84,
+			},
+		},
+	}
+
+	validatePCAP(t, "pcaptraces/ipip-icmpv4.pcap", layers.LinkTypeEthernet, nil, expected)
+}
+
+func TestGTPU(t *testing.T) {
+	expected := []*Flow{
+		{
+			LayersPath:  "Ethernet/IPv4/UDP/GTPv1U",
+			Application: "GTPv1U",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "192.0.2.1",
+				B:        "192.0.2.2",
+				ID:       4097,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   142,
+				BAPackets: 0,
+				BABytes:   0,
+			},
+		},
+		{
+			LayersPath:  "Ethernet/IPv4/UDP/GTPv1U",
+			Application: "GTPv1U",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "192.0.2.2",
+				B:        "192.0.2.1",
+				ID:       8194,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   134,
+				BAPackets: 0,
+				BABytes:   0,
+			},
+		},
+		{
+			LayersPath:  "IPv4/ICMPv4",
+			Application: "ICMPv4",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "10.0.0.1",
+				B:        "10.0.0.2",
+				ID:       0,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   84,
+				BAPackets: 0,
+				BABytes:   0,
+			},
+		},
+		{
+			LayersPath:  "IPv4/ICMPv4",
+			Application: "ICMPv4",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV4,
+				A:        "10.0.0.2",
+				B:        "10.0.0.1",
+				ID:       0,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   84,
+				BAPackets: 0,
+				BABytes:   0,
+			},
+		},
+	}
+
+	validatePCAP(t, "pcaptraces/gtpu-icmpv4.pcap", layers.LinkTypeEthernet, nil, expected)
+}
+
+func TestSRv6(t *testing.T) {
+	expected := []*Flow{
+		{
+			LayersPath:  "Ethernet/IPv6/SRv6",
+			Application: "SRv6",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV6,
+				A:        "2001:db8::1",
+				B:        "2001:db8::2",
+				ID:       0,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   186,
+				BAPackets: 1,
+				BABytes:   186,
+			},
+		},
+		{
+			LayersPath:  "IPv6/ICMPv6",
+			Application: "ICMPv6",
+			Network: &FlowLayer{
+				Protocol: FlowProtocol_IPV6,
+				A:        "fd00::1",
+				B:        "fd00::2",
+				ID:       0,
+			},
+			Metric: &FlowMetric{
+				ABPackets: 1,
+				ABBytes:   108,
+				BAPackets: 1,
+				BABytes:   108,
+			},
+		},
+	}
+
+	validatePCAP(t, "pcaptraces/srv6-icmpv6.pcap", layers.LinkTypeEthernet, nil, expected)
+}
+
 func benchmarkPacketParsing(b *testing.B, filename string, linkType layers.LinkType) {
 	handleRead, err := pcap.OpenOffline(filename)
 	if err != nil {
@@ -0,0 +1,379 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+const (
+	// maxHTTPRecords maximum number of HTTP transactions kept per flow
+	maxHTTPRecords = 32
+	// maxHTTPHeaderSize maximum size of the headers of a HTTP message
+	maxHTTPHeaderSize = 16 * 1024
+	// maxHTTPLineSize maximum size of chunk size and trailer lines
+	maxHTTPLineSize = 1024
+)
+
+type httpParserState int
+
+const (
+	httpStateHeader httpParserState = iota
+	httpStateBody
+	httpStateBodyUntilClose
+	httpStateChunkSize
+	httpStateChunkData
+	httpStateChunkDataEnd
+	httpStateChunkTrailer
+	httpStateDisabled
+)
+
+var (
+	httpMethods = [][]byte{
+		[]byte("GET "), []byte("POST "), []byte("PUT "), []byte("DELETE "), []byte("HEAD "),
+		[]byte("OPTIONS "), []byte("PATCH "), []byte("TRACE "), []byte("CONNECT "),
+	}
+	httpResponsePrefix = []byte("HTTP/1.")
+	crlf               = []byte("\r\n")
+	crlfcrlf           = []byte("\r\n\r\n")
+)
+
+// httpTransaction holds a request waiting for its response
+type httpTransaction struct {
+	record *HTTPRecord
+	start  time.Time
+}
+
+// httpParser incrementally parses the HTTP/1.x messages of one direction
+// of a reassembled TCP stream
+type httpParser struct {
+	state     httpParserState
+	buffer    []byte
+	start     time.Time
+	remaining int64
+	size      int64
+	response  bool
+	record    *HTTPRecord
+}
+
+func isHTTPRequest(data []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(data, method) || (len(data) < len(method) && bytes.HasPrefix(method, data)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTTPResponse(data []byte) bool {
+	return bytes.HasPrefix(data, httpResponsePrefix) || (len(data) < len(httpResponsePrefix) && bytes.HasPrefix(httpResponsePrefix, data))
+}
+
+// reset makes the parser wait for a new message, data have been lost
+func (p *httpParser) reset() {
+	if p.state != httpStateDisabled {
+		p.state = httpStateHeader
+		p.buffer = nil
+		p.record = nil
+	}
+}
+
+// close is called when the stream is complete
+func (p *httpParser) close() {
+	if p.state == httpStateBodyUntilClose {
+		p.done()
+	}
+}
+
+func (p *httpParser) done() {
+	if p.record != nil {
+		if p.response {
+			p.record.ResponseBodySize = p.size
+		} else {
+			p.record.RequestBodySize = p.size
+		}
+	}
+
+	p.state = httpStateHeader
+	p.record = nil
+	p.size = 0
+}
+
+func (p *httpParser) onRequest(f *Flow, header []byte) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(header)))
+	if err != nil {
+		p.state = httpStateDisabled
+		return
+	}
+
+	p.response = false
+	p.record = &HTTPRecord{
+		Method: req.Method,
+		Host:   req.Host,
+		Path:   req.URL.Path,
+	}
+
+	if len(f.HTTP) < maxHTTPRecords {
+		f.HTTP = append(f.HTTP, p.record)
+		f.XXX_state.httpPending = append(f.XXX_state.httpPending, &httpTransaction{record: p.record, start: p.start})
+	}
+
+	switch {
+	case len(req.TransferEncoding) > 0 && req.TransferEncoding[0] == "chunked":
+		p.state = httpStateChunkSize
+	case req.ContentLength > 0:
+		p.state, p.remaining = httpStateBody, req.ContentLength
+	default:
+		p.done()
+	}
+}
+
+func (p *httpParser) onResponse(f *Flow, header []byte) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(header)), nil)
+	if err != nil {
+		p.state = httpStateDisabled
+		return
+	}
+
+	// informational responses precede the final one
+	if resp.StatusCode >= 100 && resp.StatusCode < 200 {
+		p.state = httpStateHeader
+		return
+	}
+
+	p.response = true
+	p.record = nil
+
+	var method string
+	if len(f.XXX_state.httpPending) > 0 {
+		tr := f.XXX_state.httpPending[0]
+		f.XXX_state.httpPending = f.XXX_state.httpPending[1:]
+
+		tr.record.StatusCode = int64(resp.StatusCode)
+		tr.record.Latency = p.start.Sub(tr.start).Nanoseconds()
+
+		p.record = tr.record
+		method = tr.record.Method
+	}
+
+	switch {
+	case method == "HEAD" || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified:
+		p.done()
+	case len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked":
+		p.state = httpStateChunkSize
+	case resp.ContentLength > 0:
+		p.state, p.remaining = httpStateBody, resp.ContentLength
+	case resp.ContentLength == 0:
+		p.done()
+	default:
+		p.state = httpStateBodyUntilClose
+	}
+}
+
+// line accumulates data until the given delimiter is found, it returns the
+// line including the delimiter and the remaining data
+func (p *httpParser) line(data []byte, delim []byte, max int) ([]byte, []byte, bool) {
+	p.buffer = append(p.buffer, data...)
+
+	i := bytes.Index(p.buffer, delim)
+	if i < 0 {
+		if len(p.buffer) > max {
+			p.state = httpStateDisabled
+		}
+		return nil, nil, false
+	}
+
+	line, rest := p.buffer[:i+len(delim)], p.buffer[i+len(delim):]
+	p.buffer = nil
+
+	return line, rest, true
+}
+
+// consume accounts body bytes, returns the remaining data
+func (p *httpParser) consume(data []byte) []byte {
+	n := int64(len(data))
+	if n > p.remaining {
+		n = p.remaining
+	}
+	p.remaining -= n
+
+	return data[n:]
+}
+
+// feed parses the given data seen at the given time
+func (p *httpParser) feed(f *Flow, data []byte, seen time.Time) {
+	for len(data) > 0 && p.state != httpStateDisabled {
+		switch p.state {
+		case httpStateHeader:
+			if len(p.buffer) == 0 {
+				if isHTTPRequest(data) {
+					p.response = false
+				} else if isHTTPResponse(data) {
+					p.response = true
+				} else {
+					p.state = httpStateDisabled
+					return
+				}
+				p.start = seen
+			}
+
+			header, rest, ok := p.line(data, crlfcrlf, maxHTTPHeaderSize)
+			if !ok {
+				return
+			}
+			data = rest
+
+			if p.response {
+				p.onResponse(f, header)
+			} else {
+				p.onRequest(f, header)
+			}
+		case httpStateBody:
+			rest := p.consume(data)
+			p.size += int64(len(data) - len(rest))
+			data = rest
+
+			if p.remaining == 0 {
+				p.done()
+			}
+		case httpStateBodyUntilClose:
+			p.size += int64(len(data))
+			return
+		case httpStateChunkSize:
+			line, rest, ok := p.line(data, crlf, maxHTTPLineSize)
+			if !ok {
+				return
+			}
+			data = rest
+
+			line = bytes.TrimSuffix(line, crlf)
+			if i := bytes.IndexByte(line, ';'); i >= 0 {
+				line = line[:i]
+			}
+
+			size, err := strconv.ParseInt(string(bytes.TrimSpace(line)), 16, 64)
+			if err != nil || size < 0 {
+				p.state = httpStateDisabled
+				return
+			}
+
+			if size == 0 {
+				p.state = httpStateChunkTrailer
+			} else {
+				p.state, p.remaining = httpStateChunkData, size
+			}
+		case httpStateChunkData:
+			rest := p.consume(data)
+			p.size += int64(len(data) - len(rest))
+			data = rest
+
+			if p.remaining == 0 {
+				p.state, p.remaining = httpStateChunkDataEnd, int64(len(crlf))
+			}
+		case httpStateChunkDataEnd:
+			data = p.consume(data)
+			if p.remaining == 0 {
+				p.state = httpStateChunkSize
+			}
+		case httpStateChunkTrailer:
+			line, rest, ok := p.line(data, crlf, maxHTTPLineSize)
+			if !ok {
+				return
+			}
+			data = rest
+
+			// an empty line ends the trailer
+			if len(line) == len(crlf) {
+				p.done()
+			}
+		}
+	}
+}
+
+// httpFieldInterface returns the values of a field for all the HTTP records
+func (f *Flow) httpFieldInterface(field string) (interface{}, error) {
+	if len(f.HTTP) == 0 {
+		return nil, common.ErrFieldNotFound
+	}
+
+	switch field {
+	case "Method", "Host", "Path":
+		values := make([]string, len(f.HTTP))
+		for i, r := range f.HTTP {
+			values[i], _ = r.GetStringField(field)
+		}
+		return values, nil
+	case "StatusCode", "Latency", "RequestBodySize", "ResponseBodySize":
+		values := make([]int64, len(f.HTTP))
+		for i, r := range f.HTTP {
+			values[i], _ = r.GetFieldInt64(field)
+		}
+		return values, nil
+	default:
+		return nil, common.ErrFieldNotFound
+	}
+}
+
+// GetStringField returns the value of a HTTP record field
+func (r *HTTPRecord) GetStringField(field string) (string, error) {
+	if r == nil {
+		return "", common.ErrFieldNotFound
+	}
+
+	switch field {
+	case "Method":
+		return r.Method, nil
+	case "Host":
+		return r.Host, nil
+	case "Path":
+		return r.Path, nil
+	default:
+		return "", common.ErrFieldNotFound
+	}
+}
+
+// GetFieldInt64 returns the value of a HTTP record field
+func (r *HTTPRecord) GetFieldInt64(field string) (int64, error) {
+	if r == nil {
+		return 0, common.ErrFieldNotFound
+	}
+
+	switch field {
+	case "StatusCode":
+		return r.StatusCode, nil
+	case "Latency":
+		return r.Latency, nil
+	case "RequestBodySize":
+		return r.RequestBodySize, nil
+	case "ResponseBodySize":
+		return r.ResponseBodySize, nil
+	default:
+		return 0, common.ErrFieldNotFound
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/filters"
+)
+
+func TestHTTPParser(t *testing.T) {
+	f := NewFlow()
+
+	var client, server httpParser
+	now := time.Unix(1500000000, 0)
+
+	// request split over two segments, followed by a pipelined one
+	client.feed(f, []byte("POST /api/node?x=1 HTTP/1.1\r\nHost: skydive\r\nContent-"), now)
+	client.feed(f, []byte("Length: 5\r\n\r\nhelloGET /index.html HTTP/1.1\r\nHost: skydive\r\n\r\n"), now.Add(time.Millisecond))
+
+	// chunked response then a response with a content length
+	server.feed(f, []byte("HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 201 Created\r\nTransfer-Encoding: chunked\r\n\r\n4\r\nabcd\r\n3;ext=1\r\nefg\r\n0\r\n\r\n"), now.Add(10*time.Millisecond))
+	server.feed(f, []byte("HTTP/1.1 404 Not Found\r\nContent-Length: 10\r\n\r\n0123"), now.Add(20*time.Millisecond))
+	server.feed(f, []byte("456789"), now.Add(21*time.Millisecond))
+
+	expected := []*HTTPRecord{
+		{
+			Method:           "POST",
+			Host:             "skydive",
+			Path:             "/api/node",
+			StatusCode:       201,
+			Latency:          int64(10 * time.Millisecond),
+			RequestBodySize:  5,
+			ResponseBodySize: 7,
+		},
+		{
+			Method:           "GET",
+			Host:             "skydive",
+			Path:             "/index.html",
+			StatusCode:       404,
+			Latency:          int64(19 * time.Millisecond),
+			ResponseBodySize: 10,
+		},
+	}
+
+	if !reflect.DeepEqual(expected, f.HTTP) {
+		t.Errorf("HTTP records mismatch, expected %+v, got %+v", expected, f.HTTP)
+	}
+
+	if !filters.NewTermInt64Filter("HTTP.StatusCode", 404).Eval(f) {
+		t.Error("Flow should match status code filter")
+	}
+
+	// range filters have to consider all the transactions
+	if !filters.NewGteInt64Filter("HTTP.StatusCode", 400).Eval(f) {
+		t.Error("Flow should match status code range filter")
+	}
+
+	if filters.NewLtInt64Filter("HTTP.StatusCode", 200).Eval(f) {
+		t.Error("Flow should not match status code range filter")
+	}
+
+	if method, err := f.GetFieldString("HTTP.Method"); err != nil || method != "POST" {
+		t.Errorf("Wrong HTTP.Method field: %s (%v)", method, err)
+	}
+}
+
+func TestHTTPParserNotHTTP(t *testing.T) {
+	f := NewFlow()
+
+	var p httpParser
+	p.feed(f, []byte("SSH-2.0-OpenSSH_7.4\r\n"), time.Now())
+	p.feed(f, []byte("GET / HTTP/1.1\r\n\r\n"), time.Now())
+
+	if p.state != httpStateDisabled || len(f.HTTP) != 0 {
+		t.Errorf("Parser should be disabled on non HTTP stream, got %+v", f.HTTP)
+	}
+}
@@ -189,10 +189,10 @@ func (o *OnDemandProbeClient) unregisterProbe(node *graph.Node, capture *types.C
 	return true
 }
 
-func (o *OnDemandProbeClient) applyGremlinExpr(query string) []interface{} {
-	res, err := ge.TopologyGremlinQuery(o.graph, query)
+func (o *OnDemandProbeClient) applyGremlinExpr(capture *types.Capture) []interface{} {
+	res, err := ge.TopologyGremlinBoundQuery(o.graph, capture.GremlinQuery, capture.Bindings)
 	if err != nil {
-		logging.GetLogger().Errorf("Gremlin %s error: %s", query, err.Error())
+		logging.GetLogger().Errorf("Gremlin %s error: %s", capture.GremlinQuery, err.Error())
 		return nil
 	}
 	return res.Values()
@@ -212,7 +212,7 @@ func (o *OnDemandProbeClient) checkForRegistrationCallback() {
 	defer o.RUnlock()
 
 	for _, capture := range o.captures {
-		res := o.applyGremlinExpr(capture.GremlinQuery)
+		res := o.applyGremlinExpr(capture)
 		if len(res) > 0 {
 			go o.registerProbes(res, capture)
 		}
@@ -271,7 +271,7 @@ func (o *OnDemandProbeClient) registerCapture(capture *types.Capture) {
 	o.captures[capture.UUID] = capture
 	o.Unlock()
 
-	nodes := o.applyGremlinExpr(capture.GremlinQuery)
+	nodes := o.applyGremlinExpr(capture)
 	if len(nodes) > 0 {
 		go o.registerProbes(nodes, capture)
 	}
@@ -295,7 +295,7 @@ func (o *OnDemandProbeClient) unregisterCapture(capture *types.Capture) {
 	delete(o.captures, capture.UUID)
 	o.Unlock()
 
-	res, err := ge.TopologyGremlinQuery(o.graph, capture.GremlinQuery)
+	res, err := ge.TopologyGremlinBoundQuery(o.graph, capture.GremlinQuery, capture.Bindings)
 	if err != nil {
 		logging.GetLogger().Errorf("Gremlin error: %s", err.Error())
 		return
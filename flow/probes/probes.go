@@ -27,6 +27,7 @@ import (
 
 	"github.com/skydive-project/skydive/analyzer"
 	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/flow"
 	"github.com/skydive-project/skydive/logging"
 	"github.com/skydive-project/skydive/probe"
@@ -60,7 +61,7 @@ func (a *FlowProbeTableAllocator) Alloc(nodeTID string, opts flow.TableOpts) *fl
 }
 
 func NewFlowProbeBundle(tb *probe.ProbeBundle, g *graph.Graph, fta *flow.TableAllocator, fcpool *analyzer.FlowClientPool) *probe.ProbeBundle {
-	list := []string{"pcapsocket", "ovssflow", "sflow", "gopacket", "dpdk", "ebpf", "ovsmirror"}
+	list := []string{"pcapsocket", "ovssflow", "sflow", "netflow", "gopacket", "dpdk", "ebpf", "ovsmirror"}
 	logging.GetLogger().Infof("Flow probes: %v", list)
 
 	var captureTypes []string
@@ -95,6 +96,9 @@ func NewFlowProbeBundle(tb *probe.ProbeBundle, g *graph.Graph, fta *flow.TableAl
 		case "sflow":
 			fp, err = NewSFlowProbesHandler(g, fpta)
 			captureTypes = []string{"sflow"}
+		case "netflow":
+			fp, err = NewNetFlowProbesHandler(g, fpta)
+			captureTypes = []string{"netflow", "ipfix"}
 		case "dpdk":
 			if fp, err = NewDPDKProbesHandler(g, fpta); err == nil {
 				captureTypes = []string{"dpdk"}
@@ -127,11 +131,36 @@ func NewFlowProbeBundle(tb *probe.ProbeBundle, g *graph.Graph, fta *flow.TableAl
 func tableOptsFromCapture(capture *types.Capture) flow.TableOpts {
 	layerKeyMode, _ := flow.LayerKeyModeByName(capture.LayerKeyMode)
 
+	// limits of the capture take precedence over the agent ones
+	maxFlows := int64(capture.MaxFlows)
+	if maxFlows == 0 {
+		maxFlows = int64(config.GetInt("flow.max_flows"))
+	}
+
+	maxMemory := capture.MaxMemory
+	if maxMemory == 0 {
+		maxMemory = int64(config.GetInt("flow.max_memory"))
+	}
+
+	policyName := capture.EvictionPolicy
+	if policyName == "" {
+		policyName = flow.DefaultEvictionPolicyName()
+	}
+	evictionPolicy, _ := flow.EvictionPolicyByName(policyName)
+
+	samplingMode, _ := flow.SamplingModeByName(capture.SamplingMode)
+
 	return flow.TableOpts{
 		RawPacketLimit: int64(capture.RawPacketLimit),
 		ExtraTCPMetric: capture.ExtraTCPMetric,
 		IPDefrag:       capture.IPDefrag,
 		ReassembleTCP:  capture.ReassembleTCP,
 		LayerKeyMode:   layerKeyMode,
+		MaxFlows:       maxFlows,
+		MaxMemory:      maxMemory,
+		EvictionPolicy: evictionPolicy,
+		SamplingRate:   int64(capture.SamplingRate),
+		SamplingMode:   samplingMode,
+		MaxPPS:         int64(capture.MaxPPS),
 	}
 }
@@ -108,7 +108,7 @@ func (d *SFlowProbesHandler) RegisterProbe(n *graph.Node, capture *types.Capture
 	ft := d.fpta.Alloc(tid, opts)
 
 	addr := common.ServiceAddress{Addr: address, Port: capture.Port}
-	if _, err := d.allocator.Alloc(tid, ft, capture.BPFFilter, headerSize, &addr); err != nil {
+	if _, err := d.allocator.Alloc(tid, ft, capture.BPFFilter, headerSize, &addr, d.Graph, n); err != nil {
 		return err
 	}
 
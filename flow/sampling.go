@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SamplingMode defines how packets are selected when sampling is enabled
+type SamplingMode int
+
+const (
+	DefaultSamplingMode                = DeterministicSampling // default mode
+	DeterministicSampling SamplingMode = 0                     // selects one packet every N packets
+	RandomSampling        SamplingMode = 1                     // selects each packet with a 1/N probability
+)
+
+func (m SamplingMode) String() string {
+	if m == RandomSampling {
+		return "random"
+	}
+	return "deterministic"
+}
+
+// SamplingModeByName returns the sampling mode for the given name
+func SamplingModeByName(name string) (SamplingMode, error) {
+	switch name {
+	case "deterministic":
+		return DeterministicSampling, nil
+	case "random":
+		return RandomSampling, nil
+	}
+	return DefaultSamplingMode, errors.New("SamplingMode unknown")
+}
+
+// Sampler selects the packets processed by a flow table, one out of Rate
+// packets, and limits the number of packets processed per second. When the
+// limit is exceeded, the sampling rate is raised for the next second so that
+// the metrics can still be scaled, the packets dropped in the meantime being
+// spread over the packets selected during the next second.
+type Sampler struct {
+	sync.Mutex
+	Mode    SamplingMode
+	Rate    int64
+	MaxPPS  int64
+	rate    int64
+	counter int64
+	second  int64
+	seen    int64
+	kept    int64
+	dropped int64
+	share   int64
+	rand    *rand.Rand
+}
+
+// NewSampler returns a new sampler or nil if the parameters don't
+// require any sampling
+func NewSampler(mode SamplingMode, rate int64, maxPPS int64) *Sampler {
+	if rate <= 1 && maxPPS <= 0 {
+		return nil
+	}
+
+	if rate < 1 {
+		rate = 1
+	}
+
+	return &Sampler{
+		Mode:   mode,
+		Rate:   rate,
+		MaxPPS: maxPPS,
+		rate:   rate,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Sample returns whether the packet captured at the given time is selected,
+// the sampling rate applied and the number of packets it stands for
+func (s *Sampler) Sample(now time.Time) (bool, int64, int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	if second := now.Unix(); second != s.second {
+		// adapt the rate so that the previous second would have been
+		// under the packets per second limit
+		s.rate = s.Rate
+		if s.MaxPPS > 0 && s.second == second-1 {
+			if rate := (s.seen + s.MaxPPS - 1) / s.MaxPPS; rate > s.rate {
+				s.rate = rate
+			}
+		}
+		s.second, s.seen, s.kept = second, 0, 0
+
+		// number of dropped packets each selected packet stands for, so
+		// that they get accounted to the flows seen during this second
+		s.share = 0
+		if s.MaxPPS > 0 {
+			s.share = (s.dropped + s.MaxPPS - 1) / s.MaxPPS
+		}
+	}
+	s.seen++
+
+	if s.rate > 1 {
+		if s.Mode == RandomSampling {
+			if s.rand.Int63n(s.rate) != 0 {
+				return false, 0, 0
+			}
+		} else {
+			if s.counter++; s.counter < s.rate {
+				return false, 0, 0
+			}
+			s.counter = 0
+		}
+	}
+
+	// hard limit until the rate gets adapted
+	if s.MaxPPS > 0 && s.kept >= s.MaxPPS {
+		s.dropped += s.rate
+		return false, 0, 0
+	}
+	s.kept++
+
+	extra := s.share
+	if extra > s.dropped {
+		extra = s.dropped
+	}
+	s.dropped -= extra
+
+	count := s.rate + extra
+
+	return true, s.rate, count
+}
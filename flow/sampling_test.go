@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/skydive-project/skydive/filters"
+)
+
+func samplePackets(s *Sampler, now time.Time, count int) (selected int, rate int64, packets int64) {
+	for i := 0; i < count; i++ {
+		if ok, r, c := s.Sample(now); ok {
+			selected++
+			rate = r
+			packets += c
+		}
+	}
+	return
+}
+
+func TestSamplerDeterministic(t *testing.T) {
+	if NewSampler(DeterministicSampling, 1, 0) != nil {
+		t.Error("No sampler expected without rate and limit")
+	}
+
+	s := NewSampler(DeterministicSampling, 4, 0)
+	if selected, rate, _ := samplePackets(s, time.Unix(1, 0), 100); selected != 25 || rate != 4 {
+		t.Errorf("Expected 25 packets at rate 4, got %d at rate %d", selected, rate)
+	}
+}
+
+func TestSamplerRandom(t *testing.T) {
+	s := NewSampler(RandomSampling, 10, 0)
+	if selected, rate, _ := samplePackets(s, time.Unix(1, 0), 10000); selected < 800 || selected > 1200 || rate != 10 {
+		t.Errorf("Expected around 1000 packets at rate 10, got %d at rate %d", selected, rate)
+	}
+}
+
+func TestSamplerMaxPPS(t *testing.T) {
+	s := NewSampler(DeterministicSampling, 1, 10)
+
+	// hard limit during the first second
+	if selected, rate, packets := samplePackets(s, time.Unix(1, 0), 100); selected != 10 || rate != 1 || packets != 10 {
+		t.Errorf("Expected 10 packets at rate 1, got %d at rate %d standing for %d packets", selected, rate, packets)
+	}
+
+	// then the rate is adapted to the previous second, the 90 packets
+	// dropped by the limit being spread over the selected packets
+	var selected, packets int64
+	for i := 0; i < 100; i++ {
+		if ok, rate, count := s.Sample(time.Unix(2, 0)); ok {
+			if rate != 10 || count != 19 {
+				t.Errorf("Expected a packet at rate 10 standing for 19 packets, got rate %d standing for %d packets", rate, count)
+			}
+			selected++
+			packets += count
+		}
+	}
+
+	if selected != 10 || packets != 190 {
+		t.Errorf("Expected 10 packets standing for 190 packets, got %d standing for %d packets", selected, packets)
+	}
+
+	// back to the capture rate once the traffic decreases
+	if selected, rate, packets := samplePackets(s, time.Unix(4, 0), 5); selected != 5 || rate != 1 || packets != 5 {
+		t.Errorf("Expected 5 packets at rate 1, got %d at rate %d standing for %d packets", selected, rate, packets)
+	}
+}
+
+func testFlowSampling(t *testing.T, feed func(table *Table, p gopacket.Packet)) {
+	table := NewTable(nil, nil, NewEnhancerPipeline(), "", TableOpts{SamplingRate: 2})
+
+	handleRead, err := pcap.OpenOffline("pcaptraces/icmpv4-symetric.pcap")
+	if err != nil {
+		t.Fatal("PCAP OpenOffline error (handle to read packet): ", err)
+	}
+	defer handleRead.Close()
+
+	for {
+		data, ci, err := handleRead.ReadPacketData()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("PCAP OpenOffline error (handle to read packet): ", err)
+		}
+
+		p := gopacket.NewPacket(data, layers.LinkTypeEthernet, gopacket.Default)
+		p.Metadata().CaptureInfo = ci
+
+		feed(table, p)
+		for len(table.packetSeqChan) > 0 {
+			table.processPacketSeq(<-table.packetSeqChan)
+		}
+	}
+
+	// 200 packets, one out of two processed and accounted twice
+	var packets int64
+	for _, f := range table.getFlows(&filters.SearchQuery{}).Flows {
+		if f.SamplingRate != 2 || !f.EstimatedMetric {
+			t.Errorf("Flow should be marked as sampled: %+v", f)
+		}
+		packets += f.Metric.ABPackets + f.Metric.BAPackets
+	}
+
+	if packets != 200 {
+		t.Errorf("Should estimate 200 packets, got %d", packets)
+	}
+}
+
+func TestFlowSampling(t *testing.T) {
+	testFlowSampling(t, func(table *Table, p gopacket.Packet) {
+		table.FeedWithGoPacket(p, nil)
+	})
+}
+
+// packet sequences sent by feeders, like the pcap socket one, are sampled by
+// the table
+func TestFlowSamplingPacketSeq(t *testing.T) {
+	testFlowSampling(t, func(table *Table, p gopacket.Packet) {
+		table.packetSeqChan <- PacketSeqFromGoPacket(p, 0, nil, nil)
+	})
+}
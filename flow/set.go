@@ -23,6 +23,8 @@
 package flow
 
 import (
+	"strings"
+
 	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/filters"
 )
@@ -43,14 +45,39 @@ func NewFlowSet() *FlowSet {
 	}
 }
 
-func getDedupField(flow *Flow, field string) (string, error) {
+// optionalLayers are the application layers only decoded for some flows
+var optionalLayers = []string{"DNS.", "HTTP.", "TLS."}
+
+// isOptionalLayerField returns whether the field belongs to a layer that
+// flows may not have. Such flows are sorted as having an empty value and
+// deduped by their TrackingID instead of failing.
+func isOptionalLayerField(field string) bool {
+	for _, prefix := range optionalLayers {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingLayerKey is the dedup key of the flows without the layer of the
+// dedup field, distinct from the values of the field
+type missingLayerKey struct {
+	trackingID string
+}
+
+func getDedupField(flow *Flow, field string) (interface{}, error) {
 	if field == "" {
 		return flow.TrackingID, nil
 	}
 
 	// only flow string field are support for dedup as only few make sense
 	// for dedup like NodeTID, etc.
-	return flow.GetFieldString(field)
+	value, err := flow.GetFieldString(field)
+	if err != nil && isOptionalLayerField(field) {
+		return missingLayerKey{trackingID: flow.TrackingID}, nil
+	}
+	return value, err
 }
 
 func compareByField(lf, rf *Flow, field string) (bool, error) {
@@ -69,6 +96,11 @@ func compareByField(lf, rf *Flow, field string) (bool, error) {
 		return v1 <= v2, nil
 	}
 
+	// flows without the layer come first
+	if isOptionalLayerField(field) {
+		return true, nil
+	}
+
 	return false, common.ErrFieldNotFound
 }
 
@@ -0,0 +1,293 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/logging"
+)
+
+// ApplicationSignature matches the first payload bytes sent in one
+// direction of a flow
+type ApplicationSignature interface {
+	Application() string
+	Match(protocol FlowProtocol, payload []byte) bool
+}
+
+// PayloadSignature is an application signature based on a matching function
+type PayloadSignature struct {
+	Name      string
+	Protocols []FlowProtocol
+	Matcher   func(payload []byte) bool
+}
+
+// Application returns the application name of the signature
+func (s *PayloadSignature) Application() string {
+	return s.Name
+}
+
+// Match returns whether the payload matches the signature. If no protocol
+// is specified the signature applies to all of them.
+func (s *PayloadSignature) Match(protocol FlowProtocol, payload []byte) bool {
+	if len(s.Protocols) > 0 {
+		found := false
+		for _, p := range s.Protocols {
+			if p == protocol {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return s.Matcher(payload)
+}
+
+// NewRegexSignature returns a signature matching the payload against a
+// regular expression
+func NewRegexSignature(name string, pattern string, protocols ...FlowProtocol) (*PayloadSignature, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PayloadSignature{Name: name, Protocols: protocols, Matcher: re.Match}, nil
+}
+
+// ApplicationDetector detects the application of flows using the
+// signatures of their first payload bytes
+type ApplicationDetector struct {
+	signatures []ApplicationSignature
+}
+
+// Register adds a signature. Signatures are evaluated in the registration order.
+func (d *ApplicationDetector) Register(signature ApplicationSignature) {
+	d.signatures = append(d.signatures, signature)
+}
+
+// Detect returns the application matching the payload
+func (d *ApplicationDetector) Detect(protocol FlowProtocol, payload []byte) (string, bool) {
+	if d == nil || len(payload) == 0 {
+		return "", false
+	}
+
+	for _, signature := range d.signatures {
+		if signature.Match(protocol, payload) {
+			return signature.Application(), true
+		}
+	}
+	return "", false
+}
+
+func matchHTTP(payload []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(payload, method) {
+			return true
+		}
+	}
+	return bytes.HasPrefix(payload, httpResponsePrefix)
+}
+
+func matchTLS(payload []byte) bool {
+	// handshake record of SSL 3.0 up to TLS 1.3 holding a hello message
+	return len(payload) > 5 && payload[0] == tlsRecordTypeHandshake && payload[1] == 0x03 && payload[2] <= 0x04 &&
+		(payload[5] == tlsHandshakeClientHello || payload[5] == tlsHandshakeServerHello)
+}
+
+func matchSSH(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("SSH-"))
+}
+
+func matchDNS(payload []byte) bool {
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return false
+	}
+	return dns.OpCode == layers.DNSOpCodeQuery && len(dns.Questions) > 0
+}
+
+func matchDNSOverTCP(payload []byte) bool {
+	return len(payload) > 2 && int(binary.BigEndian.Uint16(payload)) == len(payload)-2 && matchDNS(payload[2:])
+}
+
+func matchMySQL(payload []byte) bool {
+	// server greeting, first packet of the connection with protocol version 10
+	if len(payload) < 5 {
+		return false
+	}
+	length := int(payload[0]) | int(payload[1])<<8 | int(payload[2])<<16
+	return length == len(payload)-4 && payload[3] == 0 && payload[4] == 0x0a
+}
+
+func matchPostgreSQL(payload []byte) bool {
+	// startup, SSL, GSSAPI encryption or cancel request
+	if len(payload) < 8 || int(binary.BigEndian.Uint32(payload)) != len(payload) {
+		return false
+	}
+
+	switch binary.BigEndian.Uint32(payload[4:]) {
+	case 196608, 80877102, 80877103, 80877104:
+		return true
+	}
+	return false
+}
+
+var redisRequest = regexp.MustCompile(`^\*[0-9]+\r\n\$[0-9]+\r\n`)
+
+func matchRedis(payload []byte) bool {
+	return redisRequest.Match(payload)
+}
+
+func matchAMQP(payload []byte) bool {
+	return len(payload) == 8 && bytes.HasPrefix(payload, []byte("AMQP"))
+}
+
+func matchKafka(payload []byte) bool {
+	// request header: size, api key, api version, correlation id, client id
+	if len(payload) < 14 || int(binary.BigEndian.Uint32(payload)) != len(payload)-4 {
+		return false
+	}
+
+	apiKey := int16(binary.BigEndian.Uint16(payload[4:]))
+	apiVersion := int16(binary.BigEndian.Uint16(payload[6:]))
+	clientID := int16(binary.BigEndian.Uint16(payload[12:]))
+
+	return apiKey >= 0 && apiKey <= 67 && apiVersion >= 0 && apiVersion <= 15 && clientID >= -1 && int(clientID) <= len(payload)-14
+}
+
+var builtinSignatures = []*PayloadSignature{
+	{Name: "HTTP", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchHTTP},
+	{Name: "TLS", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchTLS},
+	{Name: "SSH", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchSSH},
+	{Name: "DNS", Protocols: []FlowProtocol{FlowProtocol_UDP}, Matcher: matchDNS},
+	{Name: "DNS", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchDNSOverTCP},
+	{Name: "MYSQL", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchMySQL},
+	{Name: "POSTGRESQL", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchPostgreSQL},
+	{Name: "REDIS", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchRedis},
+	{Name: "AMQP", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchAMQP},
+	{Name: "KAFKA", Protocols: []FlowProtocol{FlowProtocol_TCP}, Matcher: matchKafka},
+}
+
+// signatureConfig is an application signature of the configuration file
+type signatureConfig struct {
+	Application string
+	Protocols   []string
+	Regex       string
+}
+
+func signatureFromConfig(sc *signatureConfig) (*PayloadSignature, error) {
+	if sc.Application == "" {
+		return nil, fmt.Errorf("no application defined")
+	}
+
+	if sc.Regex == "" {
+		return nil, fmt.Errorf("no regex defined")
+	}
+
+	var protocols []FlowProtocol
+	for _, protocol := range sc.Protocols {
+		p, ok := FlowProtocol_value[strings.ToUpper(protocol)]
+		if !ok {
+			return nil, fmt.Errorf("unknown protocol %s", protocol)
+		}
+		protocols = append(protocols, FlowProtocol(p))
+	}
+
+	return NewRegexSignature(strings.ToUpper(sc.Application), sc.Regex, protocols...)
+}
+
+// NewApplicationDetector returns a detector with the built-in signatures
+func NewApplicationDetector() *ApplicationDetector {
+	d := &ApplicationDetector{}
+	for _, signature := range builtinSignatures {
+		d.Register(signature)
+	}
+	return d
+}
+
+// NewApplicationDetectorFromConfig returns a new application detector with
+// the signatures of the configuration file evaluated in their order, before
+// the built-in ones
+func NewApplicationDetectorFromConfig() *ApplicationDetector {
+	d := &ApplicationDetector{}
+
+	var configs []*signatureConfig
+	if err := config.GetConfig().UnmarshalKey("flow.application_signatures", &configs); err != nil {
+		logging.GetLogger().Errorf("Unable to load application signatures: %s", err)
+	}
+
+	for i, sc := range configs {
+		signature, err := signatureFromConfig(sc)
+		if err != nil {
+			logging.GetLogger().Errorf("Unable to load application signature %d: %s", i, err)
+			continue
+		}
+		d.Register(signature)
+	}
+
+	for _, signature := range builtinSignatures {
+		d.Register(signature)
+	}
+
+	return d
+}
+
+// detectApplication inspects the first payload sent in each direction
+// until an application is found
+func (f *Flow) detectApplication(packet *Packet, opts FlowOpts) {
+	if opts.AppDetector == nil || f.XXX_state.appDetected || f.Transport == nil || f.Network == nil {
+		return
+	}
+
+	transportLayer := packet.TransportLayer()
+	networkLayer := packet.NetworkLayer()
+	if transportLayer == nil || networkLayer == nil {
+		return
+	}
+
+	payload := transportLayer.LayerPayload()
+	if len(payload) == 0 {
+		return
+	}
+
+	direction := f.packetDirection(networkLayer, transportLayer)
+	if f.XXX_state.appInspected&direction != 0 {
+		return
+	}
+	f.XXX_state.appInspected |= direction
+
+	if app, ok := opts.AppDetector.Detect(f.Transport.Protocol, payload); ok {
+		f.Application = app
+		f.XXX_state.appDetected = true
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"testing"
+
+	"github.com/skydive-project/skydive/config"
+)
+
+func TestApplicationDetector(t *testing.T) {
+	d := NewApplicationDetector()
+
+	tests := []struct {
+		protocol FlowProtocol
+		payload  []byte
+		expected string
+	}{
+		{FlowProtocol_TCP, []byte("GET / HTTP/1.1\r\n"), "HTTP"},
+		{FlowProtocol_TCP, []byte("HTTP/1.1 200 OK\r\n"), "HTTP"},
+		{FlowProtocol_TCP, clientHello(), "TLS"},
+		{FlowProtocol_TCP, []byte("SSH-2.0-OpenSSH_7.4\r\n"), "SSH"},
+		{FlowProtocol_UDP, []byte{0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 'a', 0x00, 0x00, 0x01, 0x00, 0x01}, "DNS"},
+		{FlowProtocol_TCP, []byte{0x07, 0x00, 0x00, 0x00, 0x0a, '5', '.', '7', '.', '2', 0x00}, "MYSQL"},
+		{FlowProtocol_TCP, []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}, "POSTGRESQL"},
+		{FlowProtocol_TCP, []byte("*1\r\n$4\r\nPING\r\n"), "REDIS"},
+		{FlowProtocol_TCP, []byte("AMQP\x00\x00\x09\x01"), "AMQP"},
+		{FlowProtocol_TCP, []byte{0x00, 0x00, 0x00, 0x0e, 0x00, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x04, 'k', 'c', 'a', 't'}, "KAFKA"},
+		{FlowProtocol_UDP, []byte("GET / HTTP/1.1\r\n"), ""},
+		{FlowProtocol_TCP, []byte("random payload"), ""},
+	}
+
+	for _, test := range tests {
+		app, _ := d.Detect(test.protocol, test.payload)
+		if app != test.expected {
+			t.Errorf("Expected application %s for %q, got %s", test.expected, test.payload, app)
+		}
+	}
+}
+
+func TestApplicationDetectorUserSignature(t *testing.T) {
+	signature, err := NewRegexSignature("MEMCACHED", "^(get|set) ", FlowProtocol_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &ApplicationDetector{}
+	d.Register(signature)
+	for _, s := range builtinSignatures {
+		d.Register(s)
+	}
+
+	if app, _ := d.Detect(FlowProtocol_TCP, []byte("get key\r\n")); app != "MEMCACHED" {
+		t.Errorf("Expected MEMCACHED application, got %s", app)
+	}
+
+	if _, err := NewRegexSignature("INVALID", "(", FlowProtocol_TCP); err == nil {
+		t.Error("Invalid regex should be rejected")
+	}
+}
+
+func TestApplicationDetectorFromConfig(t *testing.T) {
+	config.Set("flow.application_signatures", []interface{}{
+		map[string]interface{}{"application": "first", "regex": "^get "},
+		map[string]interface{}{"application": "second", "regex": "^get", "protocols": []interface{}{"tcp"}},
+		map[string]interface{}{"application": "invalid", "regex": "^set", "protocols": []interface{}{"sctp2"}},
+	})
+	defer config.Set("flow.application_signatures", nil)
+
+	// the signatures are evaluated in the order of the configuration file
+	for i := 0; i < 10; i++ {
+		d := NewApplicationDetectorFromConfig()
+
+		if app, _ := d.Detect(FlowProtocol_TCP, []byte("get key\r\n")); app != "FIRST" {
+			t.Fatalf("Expected FIRST application, got %s", app)
+		}
+
+		if app, _ := d.Detect(FlowProtocol_TCP, []byte("getkey\r\n")); app != "SECOND" {
+			t.Fatalf("Expected SECOND application, got %s", app)
+		}
+
+		if app, _ := d.Detect(FlowProtocol_TCP, []byte("set key\r\n")); app != "" {
+			t.Fatalf("Signature with an unknown protocol should be ignored, got %s", app)
+		}
+	}
+}
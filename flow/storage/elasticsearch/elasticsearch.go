@@ -29,6 +29,7 @@ import (
 	"github.com/google/gopacket/layers"
 	"github.com/olivere/elastic"
 
+	"github.com/skydive-project/skydive/api/types"
 	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/etcd"
 	"github.com/skydive-project/skydive/filters"
@@ -73,6 +74,30 @@ const flowMapping = `
 				}
 			}
 		},
+		{
+			"status": {
+				"match": "StatusCode",
+				"mapping": {
+					"type": "long"
+				}
+			}
+		},
+		{
+			"bodysize": {
+				"match": "*BodySize",
+				"mapping": {
+					"type": "long"
+				}
+			}
+		},
+		{
+			"latency": {
+				"match": "Latency",
+				"mapping": {
+					"type": "long"
+				}
+			}
+		},
 		{
 			"start": {
 				"match": "*Start",
@@ -103,6 +128,33 @@ const flowMapping = `
 	]
 }`
 
+// the reason data of the alert events are only stored, not indexed, as
+// their content depends on the alert expression
+const alertEventMapping = `
+{
+	"dynamic_templates": [
+		{
+			"strings": {
+				"match": "*",
+				"match_mapping_type": "string",
+				"mapping": {
+					"type": "keyword"
+				}
+			}
+		}
+	],
+	"properties": {
+		"Timestamp": {
+			"type": "date",
+			"format": "epoch_millis"
+		},
+		"ReasonData": {
+			"type": "object",
+			"enabled": false
+		}
+	}
+}`
+
 var (
 	flowIndex = es.Index{
 		Name:      "flow",
@@ -122,6 +174,11 @@ var (
 		Mapping:   flowMapping,
 		RollIndex: true,
 	}
+	alertEventIndex = es.Index{
+		Name:    "alertevent",
+		Type:    "alertevent",
+		Mapping: alertEventMapping,
+	}
 )
 
 // ElasticSearchStorage describes an ElasticSearch flow backend
@@ -131,36 +188,46 @@ type ElasticSearchStorage struct {
 
 // easyjson:json
 type embeddedFlow struct {
-	UUID         *string
-	LayersPath   *string
-	Application  *string
-	Link         *flow.FlowLayer      `json:"Link,omitempty"`
-	Network      *flow.FlowLayer      `json:"Network,omitempty"`
-	Transport    *flow.TransportLayer `json:"Transport,omitempty"`
-	ICMP         *flow.ICMPLayer      `json:"ICMP,omitempty"`
-	TrackingID   *string
-	L3TrackingID *string
-	ParentUUID   *string
-	NodeTID      *string
-	Start        *int64
-	Last         *int64
+	UUID            *string
+	LayersPath      *string
+	Application     *string
+	Link            *flow.FlowLayer      `json:"Link,omitempty"`
+	Network         *flow.FlowLayer      `json:"Network,omitempty"`
+	Transport       *flow.TransportLayer `json:"Transport,omitempty"`
+	ICMP            *flow.ICMPLayer      `json:"ICMP,omitempty"`
+	DNS             *flow.DNSLayer       `json:"DNS,omitempty"`
+	TLS             *flow.TLSLayer       `json:"TLS,omitempty"`
+	HTTP            []*flow.HTTPRecord   `json:"HTTP,omitempty"`
+	TrackingID      *string
+	L3TrackingID    *string
+	ParentUUID      *string
+	NodeTID         *string
+	Start           *int64
+	Last            *int64
+	SamplingRate    *int64
+	EstimatedMetric *bool
 }
 
 func flowToEmbbedFlow(f *flow.Flow) *embeddedFlow {
 	return &embeddedFlow{
-		UUID:         &f.UUID,
-		LayersPath:   &f.LayersPath,
-		Application:  &f.Application,
-		Link:         f.Link,
-		Network:      f.Network,
-		Transport:    f.Transport,
-		ICMP:         f.ICMP,
-		TrackingID:   &f.TrackingID,
-		L3TrackingID: &f.L3TrackingID,
-		ParentUUID:   &f.ParentUUID,
-		NodeTID:      &f.NodeTID,
-		Start:        &f.Start,
-		Last:         &f.Last,
+		UUID:            &f.UUID,
+		LayersPath:      &f.LayersPath,
+		Application:     &f.Application,
+		Link:            f.Link,
+		Network:         f.Network,
+		Transport:       f.Transport,
+		ICMP:            f.ICMP,
+		DNS:             f.DNS,
+		TLS:             f.TLS,
+		HTTP:            f.HTTP,
+		TrackingID:      &f.TrackingID,
+		L3TrackingID:    &f.L3TrackingID,
+		ParentUUID:      &f.ParentUUID,
+		NodeTID:         &f.NodeTID,
+		Start:           &f.Start,
+		Last:            &f.Last,
+		SamplingRate:    &f.SamplingRate,
+		EstimatedMetric: &f.EstimatedMetric,
 	}
 }
 
@@ -354,6 +421,43 @@ func (c *ElasticSearchStorage) SearchFlows(fsq filters.SearchQuery) (*flow.FlowS
 	return flowset, nil
 }
 
+// StoreAlertEvent pushes an alert event in the database
+func (c *ElasticSearchStorage) StoreAlertEvent(event *types.AlertEvent) error {
+	if !c.client.Started() {
+		return errors.New("ElasticSearchStorage is not yet started")
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return c.client.BulkIndex(alertEventIndex, "", json.RawMessage(data))
+}
+
+// SearchAlertEvents searches alert events matching filters in the database
+func (c *ElasticSearchStorage) SearchAlertEvents(fsq filters.SearchQuery) ([]*types.AlertEvent, error) {
+	if !c.client.Started() {
+		return nil, errors.New("ElasticSearchStorage is not yet started")
+	}
+
+	out, err := c.sendRequest("alertevent", es.FormatFilter(fsq.Filter, ""), fsq, alertEventIndex.IndexWildcard())
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*types.AlertEvent
+	for _, d := range out.Hits.Hits {
+		event := new(types.AlertEvent)
+		if err := json.Unmarshal([]byte(*d.Source), event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
 // Start the Database client
 func (c *ElasticSearchStorage) Start() {
 	go c.client.Start()
@@ -372,6 +476,7 @@ func New(backend string, etcdClient *etcd.Client) (*ElasticSearchStorage, error)
 		flowIndex,
 		metricIndex,
 		rawpacketIndex,
+		alertEventIndex,
 	}
 
 	client, err := es.NewClient(indices, cfg, etcdClient)
@@ -30,6 +30,7 @@ import (
 
 	"github.com/google/gopacket/layers"
 	"github.com/mitchellh/mapstructure"
+	"github.com/skydive-project/skydive/api/types"
 	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/filters"
@@ -134,6 +135,8 @@ func flowToDocument(flow *flow.Flow) orient.Document {
 		"ParentUUID":         flow.ParentUUID,
 		"NodeTID":            flow.NodeTID,
 		"RawPacketsCaptured": flow.RawPacketsCaptured,
+		"SamplingRate":       flow.SamplingRate,
+		"EstimatedMetric":    flow.EstimatedMetric,
 	}
 
 	if tcpMetricDoc != nil {
@@ -176,6 +179,39 @@ func flowToDocument(flow *flow.Flow) orient.Document {
 			"ID":       flow.Transport.ID,
 		}
 	}
+	if flow.DNS != nil {
+		flowDoc["DNS"] = orient.Document{
+			"Query":        flow.DNS.Query,
+			"QueryType":    flow.DNS.QueryType,
+			"ResponseCode": flow.DNS.ResponseCode,
+			"Answer":       flow.DNS.Answer,
+			"Latency":      flow.DNS.Latency,
+		}
+	}
+	if len(flow.HTTP) > 0 {
+		httpDocs := make([]orient.Document, len(flow.HTTP))
+		for i, record := range flow.HTTP {
+			httpDocs[i] = orient.Document{
+				"Method":           record.Method,
+				"Host":             record.Host,
+				"Path":             record.Path,
+				"StatusCode":       record.StatusCode,
+				"Latency":          record.Latency,
+				"RequestBodySize":  record.RequestBodySize,
+				"ResponseBodySize": record.ResponseBodySize,
+			}
+		}
+		flowDoc["HTTP"] = httpDocs
+	}
+	if flow.TLS != nil {
+		flowDoc["TLS"] = orient.Document{
+			"ServerName":  flow.TLS.ServerName,
+			"Version":     flow.TLS.Version,
+			"CipherSuite": flow.TLS.CipherSuite,
+			"JA3":         flow.TLS.JA3,
+			"JA3S":        flow.TLS.JA3S,
+		}
+	}
 	return flowDoc
 }
 
@@ -362,6 +398,35 @@ func (c *OrientDBStorage) SearchMetrics(fsq filters.SearchQuery, metricFilter *f
 	return metrics, nil
 }
 
+// StoreAlertEvent pushes an alert event in the database
+func (c *OrientDBStorage) StoreAlertEvent(event *types.AlertEvent) error {
+	doc := orient.Document{
+		"@class":      "AlertEvent",
+		"@type":       "d",
+		"AlertUUID":   event.AlertUUID,
+		"Timestamp":   event.Timestamp,
+		"State":       event.State,
+		"ReasonData":  event.ReasonData,
+		"Deliveries":  event.Deliveries,
+		"SilenceUUID": event.SilenceUUID,
+	}
+
+	if _, err := c.client.CreateDocument(doc); err != nil {
+		return fmt.Errorf("Error while pushing alert event %+v: %s", event, err)
+	}
+
+	return nil
+}
+
+// SearchAlertEvents searches alert events matching filters in the database
+func (c *OrientDBStorage) SearchAlertEvents(fsq filters.SearchQuery) ([]*types.AlertEvent, error) {
+	var events []*types.AlertEvent
+	if err := c.client.Query("AlertEvent", &fsq, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
 // Start the database client
 func (c *OrientDBStorage) Start() {
 }
@@ -492,6 +557,8 @@ func New(backend string) (*OrientDBStorage, error) {
 				{Name: "ParentUUID", Type: "STRING"},
 				{Name: "NodeTID", Type: "STRING"},
 				{Name: "RawPacketsCaptured", Type: "LONG"},
+				{Name: "SamplingRate", Type: "LONG"},
+				{Name: "EstimatedMetric", Type: "BOOLEAN"},
 			},
 			Indexes: []orient.Index{
 				{Name: "Flow.UUID", Fields: []string{"UUID"}, Type: "UNIQUE"},
@@ -504,6 +571,24 @@ func New(backend string) (*OrientDBStorage, error) {
 		}
 	}
 
+	if _, err := client.GetDocumentClass("AlertEvent"); err != nil {
+		class := orient.ClassDefinition{
+			Name: "AlertEvent",
+			Properties: []orient.Property{
+				{Name: "AlertUUID", Type: "STRING", Mandatory: true, NotNull: true},
+				{Name: "Timestamp", Type: "LONG", Mandatory: true, NotNull: true},
+				{Name: "State", Type: "STRING"},
+			},
+			Indexes: []orient.Index{
+				{Name: "AlertEvent.AlertUUID", Fields: []string{"AlertUUID"}, Type: "NOTUNIQUE"},
+				{Name: "AlertEvent.Timestamp", Fields: []string{"Timestamp"}, Type: "NOTUNIQUE"},
+			},
+		}
+		if err := client.CreateDocumentClass(class); err != nil {
+			return nil, fmt.Errorf("Failed to register class AlertEvent: %s", err.Error())
+		}
+	}
+
 	flowProp := orient.Property{Name: "Flow", Type: "LINK", LinkedClass: "Flow", Mandatory: false, NotNull: true}
 
 	client.CreateProperty("FlowMetric", flowProp)
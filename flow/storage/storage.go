@@ -26,6 +26,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/skydive-project/skydive/api/types"
 	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/etcd"
@@ -48,6 +49,8 @@ type Storage interface {
 	SearchFlows(fsq filters.SearchQuery) (*flow.FlowSet, error)
 	SearchMetrics(fsq filters.SearchQuery, metricFilter *filters.Filter) (map[string][]common.Metric, error)
 	SearchRawPackets(fsq filters.SearchQuery, packetFilter *filters.Filter) (map[string]*flow.RawPackets, error)
+	StoreAlertEvent(event *types.AlertEvent) error
+	SearchAlertEvents(fsq filters.SearchQuery) ([]*types.AlertEvent, error)
 	Stop()
 }
 
@@ -75,6 +75,12 @@ type TableOpts struct {
 	IPDefrag       bool
 	ReassembleTCP  bool
 	LayerKeyMode   LayerKeyMode
+	MaxFlows       int64
+	MaxMemory      int64
+	EvictionPolicy EvictionPolicy
+	SamplingRate   int64
+	SamplingMode   SamplingMode
+	MaxPPS         int64
 }
 
 // Table store the flow table and related metrics mechanism
@@ -103,6 +109,10 @@ type Table struct {
 	tcpAssembler   *TCPAssembler
 	flowOpts       FlowOpts
 	appPortMap     *ApplicationPortMap
+	appDetector    *ApplicationDetector
+	memory         int64
+	evicted        int64
+	sampler        *Sampler
 }
 
 // NewTable creates a new flow table
@@ -123,6 +133,7 @@ func NewTable(updateHandler *Handler, expireHandler *Handler, pipeline *Enhancer
 		ipDefragger:    NewIPDefragger(),
 		tcpAssembler:   NewTCPAssembler(),
 		appPortMap:     NewApplicationPortMapFromConfig(),
+		appDetector:    NewApplicationDetectorFromConfig(),
 	}
 	if len(opts) > 0 {
 		t.Opts = opts[0]
@@ -133,8 +144,11 @@ func NewTable(updateHandler *Handler, expireHandler *Handler, pipeline *Enhancer
 		IPDefrag:     t.Opts.IPDefrag,
 		LayerKeyMode: t.Opts.LayerKeyMode,
 		AppPortMap:   t.appPortMap,
+		AppDetector:  t.appDetector,
 	}
 
+	t.sampler = NewSampler(t.Opts.SamplingMode, t.Opts.SamplingRate, t.Opts.MaxPPS)
+
 	t.updateVersion = 0
 	return t
 }
@@ -177,8 +191,11 @@ func (ft *Table) getOrCreateFlow(key string) (*Flow, bool) {
 		return flow, false
 	}
 
+	ft.evict(1)
+
 	new := NewFlow()
 	ft.table[key] = new
+	ft.resizeFlow(new)
 
 	return new, true
 }
@@ -204,7 +221,7 @@ func (ft *Table) expire(expireBefore int64) {
 			expiredFlows = append(expiredFlows, f)
 
 			// need to use the key as the key could be not equal to the UUID
-			delete(ft.table, k)
+			ft.removeFlow(k, f)
 		}
 	}
 
@@ -270,6 +287,7 @@ func (ft *Table) update(updateFrom, updateTime int64) {
 		if ft.Opts.RawPacketLimit > 0 {
 			for _, f := range updatedFlows {
 				f.LastRawPackets = f.LastRawPackets[:0]
+				ft.resizeFlow(f)
 			}
 		}
 	}
@@ -389,12 +407,40 @@ func (ft *Table) packetToFlow(packet *Packet, parentUUID string) *Flow {
 			Data:      packet.Data,
 		}
 		flow.LastRawPackets = append(flow.LastRawPackets, data)
+		ft.resizeFlow(flow)
 	}
 
 	return flow
 }
 
+// samplePacketSeq applies the sampling of the capture to the packets of a
+// sequence, returning false if the sequence is not selected
+func (ft *Table) samplePacketSeq(ps *PacketSequence, timestamp time.Time) bool {
+	if ft.sampler == nil || ps.sampled {
+		return true
+	}
+	ps.sampled = true
+
+	selected, rate, count := ft.sampler.Sample(timestamp)
+	if !selected {
+		return false
+	}
+
+	for _, p := range ps.Packets {
+		p.SamplingRate = rate
+		p.sampledPackets = count
+	}
+	return true
+}
+
 func (ft *Table) processPacketSeq(ps *PacketSequence) {
+	// sequences not sampled by the feeder, like the ones from pcap sockets
+	if len(ps.Packets) > 0 && ps.Packets[0].GoPacket != nil {
+		if !ft.samplePacketSeq(ps, ps.Packets[0].GoPacket.Metadata().Timestamp) {
+			return
+		}
+	}
+
 	var parentUUID string
 	logging.GetLogger().Debugf("%d Packets received for capture node %s", len(ps.Packets), ft.nodeTID)
 	for _, packet := range ps.Packets {
@@ -404,12 +450,19 @@ func (ft *Table) processPacketSeq(ps *PacketSequence) {
 }
 
 func (ft *Table) processFlow(fl *Flow) {
+	if _, found := ft.table[fl.UUID]; !found {
+		ft.evict(1)
+	}
+
 	prev := ft.replaceFlow(fl.UUID, fl)
 	if prev != nil {
 		fl.LastUpdateMetric = prev.LastUpdateMetric
 
 		fl.XXX_state = prev.XXX_state
 	}
+	ft.resizeFlow(fl)
+
+	fl.XXX_state.updateVersion = ft.updateVersion + 1
 }
 
 // Run background jobs, like update/expire entries event
@@ -465,6 +518,11 @@ func (ft *Table) Run() {
 	}
 }
 
+// NodeTID returns the TID of the node the table is capturing on
+func (ft *Table) NodeTID() string {
+	return ft.nodeTID
+}
+
 // IPDefragger returns the ipDefragger if enabled
 func (ft *Table) IPDefragger() *IPDefragger {
 	if ft.Opts.IPDefrag {
@@ -475,7 +533,21 @@ func (ft *Table) IPDefragger() *IPDefragger {
 
 // FeedWithGoPacket feeds the table with a gopacket
 func (ft *Table) FeedWithGoPacket(packet gopacket.Packet, bpf *BPF) {
+	// sample before decoding to save the cost of the dropped packets
+	var rate, count int64
+	if ft.sampler != nil {
+		var selected bool
+		if selected, rate, count = ft.sampler.Sample(packet.Metadata().Timestamp); !selected {
+			return
+		}
+	}
+
 	if ps := PacketSeqFromGoPacket(packet, 0, bpf, ft.ipDefragger); len(ps.Packets) > 0 {
+		for _, p := range ps.Packets {
+			p.SamplingRate = rate
+			p.sampledPackets = count
+		}
+		ps.sampled = true
 		ft.packetSeqChan <- ps
 	}
 }
@@ -483,6 +555,8 @@ func (ft *Table) FeedWithGoPacket(packet gopacket.Packet, bpf *BPF) {
 // FeedWithSFlowSample feeds the table with sflow samples
 func (ft *Table) FeedWithSFlowSample(sample *layers.SFlowFlowSample, bpf *BPF) {
 	for _, ps := range PacketSeqFromSFlowSample(sample, bpf, ft.ipDefragger) {
+		// sFlow agents already sample the packets
+		ps.sampled = true
 		ft.packetSeqChan <- ps
 	}
 }
@@ -23,6 +23,8 @@
 package flow
 
 import (
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -226,3 +228,58 @@ func TestUpdate(t *testing.T) {
 		t.Errorf("Should have been notified : %+v", flow2)
 	}
 }
+
+func TestFlowEviction(t *testing.T) {
+	var received int
+	handler := NewFlowHandler(func(f []*Flow) {
+		received += len(f)
+	}, time.Second)
+
+	table := NewTable(nil, handler, NewEnhancerPipeline(), "", TableOpts{MaxFlows: 50})
+	fillTableFromPCAP(t, table, "pcaptraces/icmpv4-symetric.pcap", layers.LinkTypeEthernet, nil)
+
+	if flows := table.getFlows(&filters.SearchQuery{}).Flows; len(flows) > 50 {
+		t.Errorf("Should return at most 50 flows got : %d", len(flows))
+	}
+
+	if received == 0 || int64(received) != table.EvictedFlows() {
+		t.Errorf("Evicted flows should be sent to the expire handler, received %d, evicted %d", received, table.EvictedFlows())
+	}
+}
+
+func TestFlowEvictionPolicy(t *testing.T) {
+	var evicted []string
+	handler := NewFlowHandler(func(flows []*Flow) {
+		for _, f := range flows {
+			evicted = append(evicted, f.UUID)
+		}
+	}, time.Second)
+
+	fill := func(opts TableOpts) {
+		evicted = evicted[:0]
+
+		table := NewTable(nil, handler, NewEnhancerPipeline(), "", opts)
+		for i := 0; i < 10; i++ {
+			f, _ := table.getOrCreateFlow(strconv.Itoa(i))
+			f.UUID = strconv.Itoa(i)
+			f.Last = int64(i)
+			f.Metric.ABPackets = int64(10 - i)
+		}
+		table.getOrCreateFlow("new")
+	}
+
+	fill(TableOpts{MaxFlows: 10, EvictionPolicy: OldestLastSeenPolicy})
+	if !reflect.DeepEqual(evicted, []string{"0", "1"}) {
+		t.Errorf("Oldest flows should have been evicted, got %v", evicted)
+	}
+
+	fill(TableOpts{MaxFlows: 10, EvictionPolicy: SmallestFlowPolicy})
+	if !reflect.DeepEqual(evicted, []string{"9", "8"}) {
+		t.Errorf("Smallest flows should have been evicted, got %v", evicted)
+	}
+
+	fill(TableOpts{MaxMemory: 10 * flowBaseMemory})
+	if !reflect.DeepEqual(evicted, []string{"0", "1"}) {
+		t.Errorf("Flows should have been evicted by the memory limit, got %v", evicted)
+	}
+}
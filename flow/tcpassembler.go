@@ -53,6 +53,7 @@ type TCPAssemblerStream struct {
 	end          time.Time
 	sawStart     bool
 	sawEnd       bool
+	http         httpParser
 }
 
 // NewTCPAssembler returns a new TCPAssembler
@@ -125,6 +126,14 @@ func (s *TCPAssemblerStream) Reassembled(reassemblies []tcpassembly.Reassembly)
 		}
 		s.sawStart = s.sawStart || reassembly.Start
 		s.sawEnd = s.sawEnd || reassembly.End
+
+		if s.flow != nil {
+			// lost data, wait for the next message
+			if reassembly.Skip != 0 {
+				s.http.reset()
+			}
+			s.http.feed(s.flow, reassembly.Bytes, reassembly.Seen)
+		}
 	}
 }
 
@@ -135,6 +144,8 @@ func (s *TCPAssemblerStream) ReassemblyComplete() {
 		return
 	}
 
+	s.http.close()
+
 	m := f.TCPMetric
 	if m == nil {
 		m = &TCPMetric{}
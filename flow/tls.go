@@ -0,0 +1,408 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+const (
+	tlsRecordTypeHandshake  = 22
+	tlsHandshakeClientHello = 1
+	tlsHandshakeServerHello = 2
+
+	tlsExtensionServerName        = 0
+	tlsExtensionSupportedGroups   = 10
+	tlsExtensionECPointFormats    = 11
+	tlsExtensionSupportedVersions = 43
+
+	// maxTLSHelloSize is the maximum size of the records of a hello
+	// buffered until the hello is complete
+	maxTLSHelloSize = 65536
+)
+
+var (
+	// ErrTLSMalformed TLS handshake message can't be decoded
+	ErrTLSMalformed = errors.New("Malformed TLS handshake")
+
+	// errTLSIncomplete the handshake message continues in the next segments
+	errTLSIncomplete = errors.New("Incomplete TLS handshake")
+)
+
+var tlsVersions = map[uint16]string{
+	0x0300: "SSL 3.0",
+	0x0301: "TLS 1.0",
+	0x0302: "TLS 1.1",
+	0x0303: "TLS 1.2",
+	0x0304: "TLS 1.3",
+}
+
+var tlsCipherSuites = map[uint16]string{
+	0x000a: "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x002f: "TLS_RSA_WITH_AES_128_CBC_SHA",
+	0x0035: "TLS_RSA_WITH_AES_256_CBC_SHA",
+	0x003c: "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	0x009c: "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	0x009d: "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	0x009e: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0x009f: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384",
+	0x1301: "TLS_AES_128_GCM_SHA256",
+	0x1302: "TLS_AES_256_GCM_SHA384",
+	0x1303: "TLS_CHACHA20_POLY1305_SHA256",
+	0xc009: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	0xc00a: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	0xc013: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	0xc014: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	0xc023: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	0xc027: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+	0xc02b: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	0xc02c: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	0xc02f: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	0xc030: "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	0xcca8: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xcca9: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
+func tlsVersionName(v uint16) string {
+	if name, ok := tlsVersions[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+func tlsCipherSuiteName(c uint16) string {
+	if name, ok := tlsCipherSuites[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", c)
+}
+
+// isGREASE returns whether the value is a GREASE value, RFC 8701, which
+// is ignored by the fingerprints
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// tlsReader reads the fields of a handshake message
+type tlsReader struct {
+	data []byte
+	err  error
+}
+
+func (r *tlsReader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.data) {
+		r.err = ErrTLSMalformed
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *tlsReader) uint8() int {
+	if b := r.bytes(1); b != nil {
+		return int(b[0])
+	}
+	return 0
+}
+
+func (r *tlsReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return 0
+}
+
+func (r *tlsReader) uint24() int {
+	if b := r.bytes(3); b != nil {
+		return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	}
+	return 0
+}
+
+// uint16List reads a list of uint16 prefixed by its length in bytes
+func (r *tlsReader) uint16List(length int) []uint16 {
+	sub := &tlsReader{data: r.bytes(length)}
+	if r.err != nil {
+		return nil
+	}
+
+	var values []uint16
+	for len(sub.data) >= 2 {
+		values = append(values, sub.uint16())
+	}
+	return values
+}
+
+type tlsExtension struct {
+	kind uint16
+	data []byte
+}
+
+func (r *tlsReader) extensions() []tlsExtension {
+	// extensions are optional
+	if len(r.data) == 0 {
+		return nil
+	}
+
+	sub := &tlsReader{data: r.bytes(int(r.uint16()))}
+
+	var extensions []tlsExtension
+	for len(sub.data) > 0 && sub.err == nil {
+		kind := sub.uint16()
+		data := sub.bytes(int(sub.uint16()))
+		extensions = append(extensions, tlsExtension{kind: kind, data: data})
+	}
+
+	if sub.err != nil {
+		r.err = sub.err
+	}
+	return extensions
+}
+
+func joinUint16(values []uint16) string {
+	var s []string
+	for _, v := range values {
+		if !isGREASE(v) {
+			s = append(s, strconv.Itoa(int(v)))
+		}
+	}
+	return strings.Join(s, "-")
+}
+
+func fingerprint(fields ...string) string {
+	hash := md5.Sum([]byte(strings.Join(fields, ",")))
+	return hex.EncodeToString(hash[:])
+}
+
+func (t *TLSLayer) decodeClientHello(r *tlsReader) error {
+	version := r.uint16()
+	r.bytes(32)        // random
+	r.bytes(r.uint8()) // session id
+	ciphers := r.uint16List(int(r.uint16()))
+	r.bytes(r.uint8()) // compression methods
+	extensions := r.extensions()
+	if r.err != nil {
+		return r.err
+	}
+
+	var kinds, groups []uint16
+	var pointFormats []string
+	for _, ext := range extensions {
+		kinds = append(kinds, ext.kind)
+
+		sub := &tlsReader{data: ext.data}
+		switch ext.kind {
+		case tlsExtensionServerName:
+			// server name list, only host names are defined
+			sub.uint16()
+			if sub.uint8() == 0 {
+				t.ServerName = string(sub.bytes(int(sub.uint16())))
+			}
+		case tlsExtensionSupportedGroups:
+			groups = sub.uint16List(int(sub.uint16()))
+		case tlsExtensionECPointFormats:
+			for _, format := range sub.bytes(sub.uint8()) {
+				pointFormats = append(pointFormats, strconv.Itoa(int(format)))
+			}
+		}
+	}
+
+	t.JA3 = fingerprint(strconv.Itoa(int(version)), joinUint16(ciphers), joinUint16(kinds), joinUint16(groups), strings.Join(pointFormats, "-"))
+
+	return nil
+}
+
+func (t *TLSLayer) decodeServerHello(r *tlsReader) error {
+	version := r.uint16()
+	r.bytes(32)        // random
+	r.bytes(r.uint8()) // session id
+	cipher := r.uint16()
+	r.uint8() // compression method
+	extensions := r.extensions()
+	if r.err != nil {
+		return r.err
+	}
+
+	negotiated := version
+
+	var kinds []uint16
+	for _, ext := range extensions {
+		kinds = append(kinds, ext.kind)
+
+		// TLS 1.3 keeps the legacy version field to TLS 1.2
+		if ext.kind == tlsExtensionSupportedVersions && len(ext.data) == 2 {
+			negotiated = uint16(ext.data[0])<<8 | uint16(ext.data[1])
+		}
+	}
+
+	t.Version = tlsVersionName(negotiated)
+	t.CipherSuite = tlsCipherSuiteName(cipher)
+	t.JA3S = fingerprint(strconv.Itoa(int(version)), strconv.Itoa(int(cipher)), joinUint16(kinds))
+
+	return nil
+}
+
+// tlsHandshakeMessage returns the first handshake message of the TLS records
+// of the data, the message being possibly fragmented over several records
+func tlsHandshakeMessage(data []byte) ([]byte, error) {
+	var message []byte
+	for {
+		r := &tlsReader{data: data}
+		if kind := r.uint8(); r.err == nil && kind != tlsRecordTypeHandshake {
+			return nil, ErrLayerNotFound
+		}
+		r.uint16() // record version
+		length := int(r.uint16())
+		if r.err != nil {
+			return nil, errTLSIncomplete
+		}
+
+		// the record may continue in the next segments
+		if length > len(r.data) {
+			length = len(r.data)
+		}
+		message = append(message, r.data[:length]...)
+		data = r.data[length:]
+
+		if len(message) > 0 && message[0] != tlsHandshakeClientHello && message[0] != tlsHandshakeServerHello {
+			return nil, ErrLayerNotFound
+		}
+
+		if len(message) >= 4 {
+			size := 4 + (int(message[1])<<16 | int(message[2])<<8 | int(message[3]))
+			if len(message) >= size {
+				return message[:size], nil
+			}
+		}
+
+		if len(data) == 0 {
+			return nil, errTLSIncomplete
+		}
+	}
+}
+
+// decodeTLSHandshake decodes the ClientHello or ServerHello message of the
+// given TLS records
+func (t *TLSLayer) decodeTLSHandshake(data []byte) error {
+	message, err := tlsHandshakeMessage(data)
+	if err != nil {
+		return err
+	}
+
+	r := &tlsReader{data: message}
+	kind := r.uint8()
+	r.uint24() // length, the message is complete
+
+	if kind == tlsHandshakeClientHello {
+		return t.decodeClientHello(r)
+	}
+	return t.decodeServerHello(r)
+}
+
+// updateTLSLayer decodes the hello sent in each direction of the flow. The
+// segments of a hello are buffered until it is complete, retransmitted and
+// out of order segments not being handled.
+func (f *Flow) updateTLSLayer(packet *Packet) error {
+	if f.Transport == nil || f.Transport.Protocol != FlowProtocol_TCP || f.Network == nil {
+		return nil
+	}
+
+	transportLayer := packet.TransportLayer()
+	networkLayer := packet.NetworkLayer()
+	if transportLayer == nil || networkLayer == nil {
+		return ErrLayerNotFound
+	}
+
+	// hello already seen in this direction
+	direction := f.packetDirection(networkLayer, transportLayer)
+	if f.XXX_state.tlsDecoded&direction != 0 {
+		return nil
+	}
+
+	data := transportLayer.LayerPayload()
+	if len(data) == 0 {
+		return nil
+	}
+
+	pending := &f.XXX_state.tlsPending[direction-1]
+	if len(*pending) > 0 {
+		data = append(*pending, data...)
+	} else if data[0] != tlsRecordTypeHandshake {
+		return nil
+	}
+
+	tls := f.TLS
+	if tls == nil {
+		tls = &TLSLayer{}
+	}
+
+	err := tls.decodeTLSHandshake(data)
+	if err == errTLSIncomplete && len(data) < maxTLSHelloSize {
+		// the packet buffer may be reused
+		if len(*pending) == 0 {
+			data = append([]byte(nil), data...)
+		}
+		*pending = data
+		return nil
+	}
+
+	*pending = nil
+	if err != nil {
+		return err
+	}
+
+	f.XXX_state.tlsDecoded |= direction
+	f.TLS = tls
+
+	return nil
+}
+
+// GetStringField returns the value of a TLS field
+func (t *TLSLayer) GetStringField(field string) (string, error) {
+	if t == nil {
+		return "", common.ErrFieldNotFound
+	}
+
+	switch field {
+	case "ServerName":
+		return t.ServerName, nil
+	case "Version":
+		return t.Version, nil
+	case "CipherSuite":
+		return t.CipherSuite, nil
+	case "JA3":
+		return t.JA3, nil
+	case "JA3S":
+		return t.JA3S, nil
+	default:
+		return "", common.ErrFieldNotFound
+	}
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/filters"
+)
+
+func withLength16(data ...byte) []byte {
+	return append([]byte{byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+func tlsHandshake(kind byte, body []byte) []byte {
+	message := append([]byte{kind, 0, byte(len(body) >> 8), byte(len(body))}, body...)
+	return append([]byte{tlsRecordTypeHandshake, 0x03, 0x01, byte(len(message) >> 8), byte(len(message))}, message...)
+}
+
+func tlsExtensionBytes(kind uint16, data []byte) []byte {
+	return append([]byte{byte(kind >> 8), byte(kind)}, withLength16(data...)...)
+}
+
+func clientHello() []byte {
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 0)                   // session id
+
+	// GREASE, TLS_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
+	body = append(body, withLength16(0x1a, 0x1a, 0x13, 0x01, 0xc0, 0x2b)...)
+	body = append(body, 1, 0) // null compression
+
+	sni := append([]byte{0}, withLength16([]byte("skydive.network")...)...)
+
+	var extensions []byte
+	extensions = append(extensions, tlsExtensionBytes(0x2a2a, nil)...)
+	extensions = append(extensions, tlsExtensionBytes(tlsExtensionServerName, withLength16(sni...))...)
+	extensions = append(extensions, tlsExtensionBytes(tlsExtensionSupportedGroups, withLength16(0x00, 0x1d, 0x00, 0x17))...)
+	extensions = append(extensions, tlsExtensionBytes(tlsExtensionECPointFormats, []byte{1, 0})...)
+
+	body = append(body, withLength16(extensions...)...)
+
+	return tlsHandshake(tlsHandshakeClientHello, body)
+}
+
+func serverHello() []byte {
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 0)                   // session id
+	body = append(body, 0x13, 0x01)          // TLS_AES_128_GCM_SHA256
+	body = append(body, 0)                   // null compression
+
+	var extensions []byte
+	extensions = append(extensions, tlsExtensionBytes(tlsExtensionSupportedVersions, []byte{0x03, 0x04})...)
+
+	body = append(body, withLength16(extensions...)...)
+
+	return tlsHandshake(tlsHandshakeServerHello, body)
+}
+
+func md5String(s string) string {
+	hash := md5.Sum([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestTLSHandshake(t *testing.T) {
+	tls := &TLSLayer{}
+
+	if err := tls.decodeTLSHandshake(clientHello()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tls.decodeTLSHandshake(serverHello()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &TLSLayer{
+		ServerName:  "skydive.network",
+		Version:     "TLS 1.3",
+		CipherSuite: "TLS_AES_128_GCM_SHA256",
+		JA3:         md5String("771,4865-49195,0-10-11,29-23,0"),
+		JA3S:        md5String("771,4865,43"),
+	}
+
+	if !reflect.DeepEqual(expected, tls) {
+		t.Errorf("TLS layer mismatch, expected %+v, got %+v", expected, tls)
+	}
+
+	f := &Flow{TLS: tls}
+	if name, err := f.GetFieldString("TLS.ServerName"); err != nil || name != "skydive.network" {
+		t.Errorf("Wrong TLS.ServerName field: %s (%v)", name, err)
+	}
+}
+
+func TestTLSHandshakeTruncated(t *testing.T) {
+	tls := &TLSLayer{}
+
+	data := clientHello()
+	if err := tls.decodeTLSHandshake(data[:len(data)-4]); err == nil {
+		t.Error("Truncated ClientHello should fail")
+	}
+
+	if tls.JA3 != "" {
+		t.Errorf("No fingerprint expected, got %s", tls.JA3)
+	}
+}
+
+func TestTLSHandshakeFragmented(t *testing.T) {
+	// ClientHello fragmented over two records
+	data := clientHello()
+	message := data[5:]
+	split := len(message) / 2
+
+	var records []byte
+	for _, fragment := range [][]byte{message[:split], message[split:]} {
+		records = append(records, tlsRecordTypeHandshake, 0x03, 0x01, byte(len(fragment)>>8), byte(len(fragment)))
+		records = append(records, fragment...)
+	}
+
+	tls := &TLSLayer{}
+	if err := tls.decodeTLSHandshake(records[:len(records)-1]); err != errTLSIncomplete {
+		t.Fatalf("Hello should be incomplete, got %v", err)
+	}
+
+	if err := tls.decodeTLSHandshake(records); err != nil {
+		t.Fatal(err)
+	}
+
+	if tls.ServerName != "skydive.network" || tls.JA3 != md5String("771,4865-49195,0-10-11,29-23,0") {
+		t.Errorf("Wrong TLS layer decoded: %+v", tls)
+	}
+}
+
+func tlsPacket(t *testing.T, fromClient bool, payload []byte) gopacket.Packet {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x0f, 0xaa, 0xfa, 0xaa, 0x01},
+		DstMAC:       net.HardwareAddr{0x00, 0x0f, 0xaa, 0xfa, 0xaa, 0x02},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+	tcp := &layers.TCP{SrcPort: 34567, DstPort: 443, ACK: true, Window: 1024}
+
+	if !fromClient {
+		eth.SrcMAC, eth.DstMAC = eth.DstMAC, eth.SrcMAC
+		ip.SrcIP, ip.DstIP = ip.DstIP, ip.SrcIP
+		tcp.SrcPort, tcp.DstPort = tcp.DstPort, tcp.SrcPort
+	}
+	tcp.SetNetworkLayerForChecksum(ip)
+
+	buffer := gopacket.NewSerializeBuffer()
+	options := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, options, eth, ip, tcp, gopacket.Payload(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	return gopacket.NewPacket(buffer.Bytes(), layers.LinkTypeEthernet, gopacket.Default)
+}
+
+func TestTLSHandshakeSegments(t *testing.T) {
+	table := NewTable(nil, nil, NewEnhancerPipeline(), "", TableOpts{})
+
+	client, server := clientHello(), serverHello()
+
+	// the hellos span several segments, interleaved with empty segments
+	for _, segment := range []struct {
+		fromClient bool
+		payload    []byte
+	}{
+		{true, client[:3]},
+		{true, client[3:40]},
+		{false, server[:20]},
+		{false, nil},
+		{true, client[40:]},
+		{false, server[20:]},
+	} {
+		table.FeedWithGoPacket(tlsPacket(t, segment.fromClient, segment.payload), nil)
+		for len(table.packetSeqChan) > 0 {
+			table.processPacketSeq(<-table.packetSeqChan)
+		}
+	}
+
+	flows := table.getFlows(&filters.SearchQuery{}).Flows
+	if len(flows) != 1 {
+		t.Fatalf("Should return one flow, got %+v", flows)
+	}
+
+	expected := &TLSLayer{
+		ServerName:  "skydive.network",
+		Version:     "TLS 1.3",
+		CipherSuite: "TLS_AES_128_GCM_SHA256",
+		JA3:         md5String("771,4865-49195,0-10-11,29-23,0"),
+		JA3S:        md5String("771,4865,43"),
+	}
+
+	if !reflect.DeepEqual(expected, flows[0].TLS) {
+		t.Errorf("TLS layer mismatch, expected %+v, got %+v", expected, flows[0].TLS)
+	}
+}
+
+func TestFlowSetSortByTLS(t *testing.T) {
+	fs := NewFlowSet()
+	fs.Flows = []*Flow{
+		{UUID: "1", TrackingID: "t1", TLS: &TLSLayer{ServerName: "b"}},
+		{UUID: "2", TrackingID: "t2"},
+		{UUID: "3", TrackingID: "t3", TLS: &TLSLayer{ServerName: "a"}},
+		{UUID: "4", TrackingID: "t4", TLS: &TLSLayer{ServerName: "a"}},
+		{UUID: "5", TrackingID: "t5"},
+		{UUID: "6", TrackingID: "t2"},
+	}
+
+	if err := fs.Dedup("TLS.ServerName"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Sort(common.SortAscending, "TLS.ServerName")
+
+	var uuids []string
+	for _, f := range fs.Flows {
+		uuids = append(uuids, f.UUID)
+	}
+
+	// flows without TLS layer are only deduped by their tracking ID
+	if strings.Join(uuids, ",") != "2,5,3,1" {
+		t.Errorf("Wrong dedup/sort by TLS.ServerName: %v", uuids)
+	}
+}
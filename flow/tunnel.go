@@ -0,0 +1,265 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package flow
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// EthernetTypeERSPANII GRE protocol type of ERSPAN type II
+	EthernetTypeERSPANII layers.EthernetType = 0x88be
+	// EthernetTypeERSPANIII GRE protocol type of ERSPAN type III
+	EthernetTypeERSPANIII layers.EthernetType = 0x22eb
+	// GTPv1UPort UDP port of GTP-U
+	GTPv1UPort layers.UDPPort = 2152
+
+	ipv6RoutingTypeSRH         = 4
+	ipProtocolEthernet         = 143
+	gtpMessageTypeGPDU         = 0xff
+	erspanIIIFrameTypeIP       = 2
+	erspanIIIPlatformSubLength = 8
+)
+
+// LayerTypeERSPAN layer type of ERSPAN type II and III headers
+var LayerTypeERSPAN = gopacket.RegisterLayerType(55557, gopacket.LayerTypeMetadata{Name: "ERSPAN", Decoder: gopacket.DecodeFunc(decodeERSPAN)})
+
+// LayerTypeGTPv1U layer type of GTP-U headers
+var LayerTypeGTPv1U = gopacket.RegisterLayerType(55558, gopacket.LayerTypeMetadata{Name: "GTPv1U", Decoder: gopacket.DecodeFunc(decodeGTPv1U)})
+
+// LayerTypeSRv6 layer type of IPv6 segment routing headers
+var LayerTypeSRv6 = gopacket.RegisterLayerType(55559, gopacket.LayerTypeMetadata{Name: "SRv6", Decoder: gopacket.DecodeFunc(decodeSRv6)})
+
+var layerTypeIPv6Routing = gopacket.OverrideLayerType(47, gopacket.LayerTypeMetadata{Name: "IPv6Routing", Decoder: gopacket.DecodeFunc(decodeIPv6Routing)})
+
+// ErrTunnelHeaderTooShort tunnel header truncated
+var ErrTunnelHeaderTooShort = errors.New("Tunnel header too short")
+
+// ERSPAN describes an ERSPAN type II or III header
+type ERSPAN struct {
+	layers.BaseLayer
+	Version   uint8
+	VLAN      uint16
+	SessionID uint16
+	FrameType uint8
+}
+
+// LayerType returns the ERSPAN layer type
+func (e *ERSPAN) LayerType() gopacket.LayerType {
+	return LayerTypeERSPAN
+}
+
+func decodeERSPAN(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) < 8 {
+		return ErrTunnelHeaderTooShort
+	}
+
+	e := &ERSPAN{
+		Version:   data[0] >> 4,
+		VLAN:      binary.BigEndian.Uint16(data[0:2]) & 0x0fff,
+		SessionID: binary.BigEndian.Uint16(data[2:4]) & 0x03ff,
+	}
+
+	length := 8
+	if e.Version == 2 {
+		// type III, with an optional platform specific sub header
+		if len(data) < 12 {
+			return ErrTunnelHeaderTooShort
+		}
+		length = 12
+		e.FrameType = (data[10] >> 2) & 0x1f
+		if data[11]&0x01 != 0 {
+			length += erspanIIIPlatformSubLength
+		}
+		if len(data) < length {
+			return ErrTunnelHeaderTooShort
+		}
+	}
+
+	e.BaseLayer = layers.BaseLayer{Contents: data[:length], Payload: data[length:]}
+	p.AddLayer(e)
+
+	if e.FrameType == erspanIIIFrameTypeIP {
+		if ipPrefix, err := ipDecoderFromRawData(e.Payload, p); ipPrefix {
+			return err
+		}
+		return p.NextDecoder(gopacket.LayerTypePayload)
+	}
+
+	return p.NextDecoder(layers.LayerTypeEthernet)
+}
+
+// GTPv1U describes a GTP-U header
+type GTPv1U struct {
+	layers.BaseLayer
+	Version     uint8
+	MessageType uint8
+	TEID        uint32
+}
+
+// LayerType returns the GTP-U layer type
+func (g *GTPv1U) LayerType() gopacket.LayerType {
+	return LayerTypeGTPv1U
+}
+
+func decodeGTPv1U(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) < 8 {
+		return ErrTunnelHeaderTooShort
+	}
+
+	g := &GTPv1U{
+		Version:     data[0] >> 5,
+		MessageType: data[1],
+		TEID:        binary.BigEndian.Uint32(data[4:8]),
+	}
+
+	length := 8
+	// sequence number, N-PDU number or extension headers
+	if data[0]&0x07 != 0 {
+		if len(data) < 12 {
+			return ErrTunnelHeaderTooShort
+		}
+		length = 12
+
+		for next := data[11]; next != 0; {
+			if len(data) < length+1 || data[length] == 0 {
+				return ErrTunnelHeaderTooShort
+			}
+			extLength := int(data[length]) * 4
+			if len(data) < length+extLength {
+				return ErrTunnelHeaderTooShort
+			}
+			next = data[length+extLength-1]
+			length += extLength
+		}
+	}
+
+	g.BaseLayer = layers.BaseLayer{Contents: data[:length], Payload: data[length:]}
+	p.AddLayer(g)
+
+	// only G-PDU messages carry user packets
+	if g.MessageType != gtpMessageTypeGPDU || len(g.Payload) == 0 {
+		return nil
+	}
+
+	if ipPrefix, err := ipDecoderFromRawData(g.Payload, p); ipPrefix {
+		return err
+	}
+	return p.NextDecoder(gopacket.LayerTypePayload)
+}
+
+// SRv6 describes an IPv6 segment routing header
+type SRv6 struct {
+	layers.BaseLayer
+	NextHeader   layers.IPProtocol
+	SegmentsLeft uint8
+	LastEntry    uint8
+	Flags        uint8
+	Tag          uint16
+	Segments     []net.IP
+}
+
+// LayerType returns the SRv6 layer type
+func (s *SRv6) LayerType() gopacket.LayerType {
+	return LayerTypeSRv6
+}
+
+func decodeSRv6(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) < 8 {
+		return ErrTunnelHeaderTooShort
+	}
+
+	length := 8 + int(data[1])*8
+	if len(data) < length {
+		return ErrTunnelHeaderTooShort
+	}
+
+	s := &SRv6{
+		BaseLayer:    layers.BaseLayer{Contents: data[:length], Payload: data[length:]},
+		NextHeader:   layers.IPProtocol(data[0]),
+		SegmentsLeft: data[3],
+		LastEntry:    data[4],
+		Flags:        data[5],
+		Tag:          binary.BigEndian.Uint16(data[6:8]),
+	}
+	for segments := data[8:length]; len(segments) >= 16; segments = segments[16:] {
+		s.Segments = append(s.Segments, net.IP(segments[:16]))
+	}
+	p.AddLayer(s)
+
+	if s.NextHeader == ipProtocolEthernet {
+		return p.NextDecoder(layers.LayerTypeEthernet)
+	}
+	return p.NextDecoder(s.NextHeader)
+}
+
+// decodeIPv6Routing decodes segment routing headers which are not supported
+// by the gopacket routing header decoder
+func decodeIPv6Routing(data []byte, p gopacket.PacketBuilder) error {
+	if len(data) >= 4 && data[2] == ipv6RoutingTypeSRH {
+		return decodeSRv6(data, p)
+	}
+	return layers.IPProtocolMetadata[layers.IPProtocolIPv6Routing].DecodeWith.Decode(data, p)
+}
+
+// isEncapsulation returns whether the layer at the given index encapsulates
+// a packet which has to generate its own flow
+func isEncapsulation(packetLayers []gopacket.Layer, i int) bool {
+	var next gopacket.LayerType
+	if i < len(packetLayers)-1 {
+		next = packetLayers[i+1].LayerType()
+	}
+
+	switch packetLayers[i].LayerType() {
+	case layers.LayerTypeGRE:
+		// If the next layer type is MPLS or ERSPAN, we don't
+		// creates the tunneling packet at this level, but at the next one.
+		if i < len(packetLayers)-2 && (next == layers.LayerTypeMPLS || next == LayerTypeERSPAN) {
+			return false
+		}
+		return true
+		// We don't split on vlan layers.LayerTypeDot1Q
+	case layers.LayerTypeVXLAN, layers.LayerTypeMPLS, layers.LayerTypeGeneve:
+		return true
+	case LayerTypeERSPAN, LayerTypeGTPv1U:
+		return next != 0
+	case layers.LayerTypeIPv4, layers.LayerTypeIPv6, LayerTypeSRv6:
+		// IP in IP, or segment routing encapsulating an IP or Ethernet packet
+		return next == layers.LayerTypeIPv4 || next == layers.LayerTypeIPv6 ||
+			(packetLayers[i].LayerType() == LayerTypeSRv6 && next == layers.LayerTypeEthernet)
+	}
+
+	return false
+}
+
+func init() {
+	layers.EthernetTypeMetadata[EthernetTypeERSPANII] = layers.EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeERSPAN), Name: "ERSPAN", LayerType: LayerTypeERSPAN}
+	layers.EthernetTypeMetadata[EthernetTypeERSPANIII] = layers.EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeERSPAN), Name: "ERSPAN", LayerType: LayerTypeERSPAN}
+
+	layers.RegisterUDPPortLayerType(GTPv1UPort, LayerTypeGTPv1U)
+}
@@ -93,6 +93,11 @@ func (q QueryString) BPF(list ...interface{}) QueryString {
 	return q.newQueryString("BPF", list...)
 }
 
+// By append a By() operation to query
+func (q QueryString) By(list ...interface{}) QueryString {
+	return q.newQueryString("By", list...)
+}
+
 // CaptureNode append a CaptureNode() operation to query
 func (q QueryString) CaptureNode() QueryString {
 	return q.newQueryString("CaptureNode")
@@ -142,6 +147,11 @@ func (q QueryString) Dedup() QueryString {
 	return q.newQueryString("Dedup")
 }
 
+// Explain append a Explain() operation to query
+func (q QueryString) Explain() QueryString {
+	return q.newQueryString("Explain")
+}
+
 // Flows append a Flows() operation to query
 func (q QueryString) Flows(list ...interface{}) QueryString {
 	return q.newQueryString("Flows", list...)
@@ -207,6 +217,16 @@ func (q QueryString) OutV(list ...interface{}) QueryString {
 	return q.newQueryString("OutV", list...)
 }
 
+// Profile append a Profile() operation to query
+func (q QueryString) Profile() QueryString {
+	return q.newQueryString("Profile")
+}
+
+// Project append a Project() operation to query
+func (q QueryString) Project(list ...interface{}) QueryString {
+	return q.newQueryString("Project", list...)
+}
+
 // RawPackets append a RawPackets() operation to query
 func (q QueryString) RawPackets() QueryString {
 	return q.newQueryString("RawPackets")
@@ -231,3 +251,8 @@ func (q QueryString) Sockets() QueryString {
 func (q QueryString) V(list ...interface{}) QueryString {
 	return q.newQueryString("V", list...)
 }
+
+// ValueMap append a ValueMap() operation to query
+func (q QueryString) ValueMap(list ...interface{}) QueryString {
+	return q.newQueryString("ValueMap", list...)
+}
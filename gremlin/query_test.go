@@ -36,3 +36,11 @@ func TestQueryBuilder(t *testing.T) {
 		t.Errorf("Wrong query,\nexpected: \"%s\",\nactual: \"%s\"", expected, actual)
 	}
 }
+
+func TestQueryBuilderBinding(t *testing.T) {
+	expected := `G.V().Has("Name", $name, "Value", Gt($min))`
+	actual := G.V().Has(Quote("Name"), Binding("name"), Quote("Value"), Gt(Binding("min")))
+	if actual.String() != expected {
+		t.Errorf("Wrong query,\nexpected: \"%s\",\nactual: \"%s\"", expected, actual)
+	}
+}
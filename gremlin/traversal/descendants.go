@@ -125,3 +125,12 @@ func (d *DescendantsGremlinTraversalStep) Reduce(next traversal.GremlinTraversal
 func (d *DescendantsGremlinTraversalStep) Context() *traversal.GremlinTraversalContext {
 	return &d.context
 }
+
+// Bind Descendants step, replacing the bindings by their values
+func (d *DescendantsGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(d.context)
+	if err != nil {
+		return nil, err
+	}
+	return &DescendantsGremlinTraversalStep{context: context, maxDepth: d.maxDepth}, nil
+}
@@ -43,6 +43,10 @@ import (
 
 const (
 	defaultSortBy = "Last"
+
+	// components the flow steps can be delegated to
+	pushDownStorage = "storage"
+	pushDownAgents  = "agents"
 )
 
 // FlowTraversalExtension describes flows in a graph Gremlin language extension
@@ -70,6 +74,7 @@ type FlowGremlinTraversalStep struct {
 	sort               bool
 	sortBy             string
 	sortOrder          common.SortOrder
+	pushDown           string
 }
 
 // FlowTraversalStep a flow step linked to a storage
@@ -517,6 +522,82 @@ func (f *FlowTraversalStep) Sum(keys ...interface{}) *traversal.GraphTraversalVa
 	return traversal.NewGraphTraversalValue(f.GraphTraversal, s)
 }
 
+// fieldGetters returns the flows as elements of the grouping steps
+func (f *FlowTraversalStep) fieldGetters() []traversal.FieldGetter {
+	elements := make([]traversal.FieldGetter, len(f.flowset.Flows))
+	for i, fl := range f.flowset.Flows {
+		elements[i] = fl
+	}
+	return elements
+}
+
+// newStep returns a step made of the flows at the given indexes
+func (f *FlowTraversalStep) newStep(group []int) traversal.GraphTraversalStep {
+	flowset := flow.NewFlowSet()
+	for _, i := range group {
+		flowset.Flows = append(flowset.Flows, f.flowset.Flows[i])
+	}
+	return &FlowTraversalStep{GraphTraversal: f.GraphTraversal, Storage: f.Storage, flowset: flowset}
+}
+
+// GroupCount returns the number of flows per value of the key
+func (f *FlowTraversalStep) GroupCount(keys ...interface{}) *traversal.GraphTraversalValue {
+	if f.error != nil {
+		return traversal.NewGraphTraversalValueFromError(f.error)
+	}
+
+	counts, err := f.GraphTraversal.GroupCountElements(f.fieldGetters(), keys...)
+	if err != nil {
+		return traversal.NewGraphTraversalValueFromError(err)
+	}
+
+	return traversal.NewGraphTraversalValue(f.GraphTraversal, counts)
+}
+
+// Group returns the flows grouped per value of the key, or the result of
+// the given key or traversal for each group, e.g. the sum of their bytes
+func (f *FlowTraversalStep) Group(keys ...interface{}) *traversal.GraphTraversalValue {
+	if f.error != nil {
+		return traversal.NewGraphTraversalValueFromError(f.error)
+	}
+
+	groups, err := f.GraphTraversal.GroupElements(f.fieldGetters(), f.newStep, keys...)
+	if err != nil {
+		return traversal.NewGraphTraversalValueFromError(err)
+	}
+
+	return traversal.NewGraphTraversalValue(f.GraphTraversal, groups)
+}
+
+// ValueMap returns a row per flow made of the values of the given keys
+func (f *FlowTraversalStep) ValueMap(keys ...interface{}) *traversal.GraphTraversalValue {
+	if f.error != nil {
+		return traversal.NewGraphTraversalValueFromError(f.error)
+	}
+
+	rows, err := f.GraphTraversal.ValueMapElements(f.fieldGetters(), keys...)
+	if err != nil {
+		return traversal.NewGraphTraversalValueFromError(err)
+	}
+
+	return traversal.NewGraphTraversalValue(f.GraphTraversal, rows)
+}
+
+// Project returns a row per flow made of the given label and value pairs,
+// the value being a key of the flow or a traversal applied to the flow
+func (f *FlowTraversalStep) Project(keys ...interface{}) *traversal.GraphTraversalValue {
+	if f.error != nil {
+		return traversal.NewGraphTraversalValueFromError(f.error)
+	}
+
+	rows, err := f.GraphTraversal.ProjectElements(f.fieldGetters(), f.newStep, keys...)
+	if err != nil {
+		return traversal.NewGraphTraversalValueFromError(err)
+	}
+
+	return traversal.NewGraphTraversalValue(f.GraphTraversal, rows)
+}
+
 // PropertyValues returns a flow field value
 func (f *FlowTraversalStep) PropertyValues(keys ...interface{}) *traversal.GraphTraversalValue {
 	if f.error != nil {
@@ -551,6 +632,15 @@ func (f *FlowTraversalStep) PropertyKeys(keys ...interface{}) *traversal.GraphTr
 	return traversal.NewGraphTraversalValue(f.GraphTraversal, s)
 }
 
+// pushDown returns where the metrics or raw packets of the flows are looked up,
+// either in the flow storage or locally from the flows returned by the agents
+func (f *FlowTraversalStep) pushDown() string {
+	if f.error == nil && f.GraphTraversal.Graph.GetContext().TimeSlice != nil {
+		return pushDownStorage
+	}
+	return ""
+}
+
 // FlowMetrics returns flow metric counters
 func (f *FlowTraversalStep) FlowMetrics() *MetricsTraversalStep {
 	if f.error != nil {
@@ -845,6 +935,7 @@ func (s *FlowGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (trav
 		return nil, err
 	}
 
+	s.pushDown = ""
 	flowset := &flow.FlowSet{}
 
 	switch tv := last.(type) {
@@ -884,6 +975,7 @@ func (s *FlowGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (trav
 			return nil, storage.ErrNoStorageConfigured
 		}
 
+		s.pushDown = pushDownStorage
 		s.addTimeFilter(&flowSearchQuery, context.TimeSlice)
 
 		if len(nodes) != 0 {
@@ -909,6 +1001,7 @@ func (s *FlowGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (trav
 			return nil, err
 		}
 	} else {
+		s.pushDown = pushDownAgents
 		if len(nodes) != 0 {
 			graphTraversal.RLock()
 			hnmap := topology.BuildHostNodeTIDMap(nodes)
@@ -981,6 +1074,28 @@ func (s *FlowGremlinTraversalStep) Context() *traversal.GremlinTraversalContext
 	return &s.context
 }
 
+// Bind flow step, replacing the bindings by their values. The Has parameters
+// are copied as Reduce extends them.
+func (s *FlowGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FlowGremlinTraversalStep{
+		TableClient: s.TableClient,
+		Storage:     s.Storage,
+		context:     context,
+		hasParams:   append([]interface{}(nil), context.Params...),
+	}, nil
+}
+
+// PushDown returns where the flows were looked up, along with the filters,
+// sort and dedup of the reduced steps
+func (s *FlowGremlinTraversalStep) PushDown() string {
+	return s.pushDown
+}
+
 // Exec hops step
 func (s *HopsGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (traversal.GraphTraversalStep, error) {
 	switch last.(type) {
@@ -1006,6 +1121,15 @@ func (s *HopsGremlinTraversalStep) Context() *traversal.GremlinTraversalContext
 	return &s.context
 }
 
+// Bind hops step, replacing the bindings by their values
+func (s *HopsGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+	return &HopsGremlinTraversalStep{context: context}, nil
+}
+
 // Exec Nodes step
 func (s *NodesGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (traversal.GraphTraversalStep, error) {
 	switch last.(type) {
@@ -1030,6 +1154,15 @@ func (s *NodesGremlinTraversalStep) Context() *traversal.GremlinTraversalContext
 	return &s.context
 }
 
+// Bind nodes step, replacing the bindings by their values
+func (s *NodesGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+	return &NodesGremlinTraversalStep{context: context}, nil
+}
+
 // Exec Capture step
 func (s *CaptureNodeGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (traversal.GraphTraversalStep, error) {
 	switch last.(type) {
@@ -1055,6 +1188,15 @@ func (s *CaptureNodeGremlinTraversalStep) Context() *traversal.GremlinTraversalC
 	return &s.context
 }
 
+// Bind capture node step, replacing the bindings by their values
+func (s *CaptureNodeGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+	return &CaptureNodeGremlinTraversalStep{context: context}, nil
+}
+
 // Exec Aggregates step
 func (a *AggregatesGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (traversal.GraphTraversalStep, error) {
 	switch last.(type) {
@@ -1076,6 +1218,15 @@ func (a *AggregatesGremlinTraversalStep) Context() *traversal.GremlinTraversalCo
 	return &a.context
 }
 
+// Bind aggregates step, replacing the bindings by their values
+func (a *AggregatesGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(a.context)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregatesGremlinTraversalStep{context: context}, nil
+}
+
 // Exec BPF step
 func (s *BpfGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (traversal.GraphTraversalStep, error) {
 	switch last.(type) {
@@ -1095,3 +1246,12 @@ func (s *BpfGremlinTraversalStep) Reduce(next traversal.GremlinTraversalStep) tr
 func (s *BpfGremlinTraversalStep) Context() *traversal.GremlinTraversalContext {
 	return &s.context
 }
+
+// Bind bpf step, replacing the bindings by their values
+func (s *BpfGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+	return &BpfGremlinTraversalStep{context: context}, nil
+}
@@ -42,7 +42,8 @@ type MetricsTraversalExtension struct {
 
 // MetricsGremlinTraversalStep describes the Metrics gremlin traversal step
 type MetricsGremlinTraversalStep struct {
-	context traversal.GremlinTraversalContext
+	context  traversal.GremlinTraversalContext
+	pushDown string
 }
 
 // NewMetricsTraversalExtension returns a new graph traversal extension
@@ -72,10 +73,12 @@ func (e *MetricsTraversalExtension) ParseStep(t traversal.Token, p traversal.Gre
 
 // Exec executes the metrics step
 func (s *MetricsGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (traversal.GraphTraversalStep, error) {
+	s.pushDown = ""
 	switch tv := last.(type) {
 	case *traversal.GraphTraversalV:
 		return InterfaceMetrics(tv), nil
 	case *FlowTraversalStep:
+		s.pushDown = tv.pushDown()
 		return tv.FlowMetrics(), nil
 	}
 	return nil, traversal.ErrExecutionError
@@ -91,6 +94,20 @@ func (s *MetricsGremlinTraversalStep) Context() *traversal.GremlinTraversalConte
 	return &s.context
 }
 
+// Bind metrics step, replacing the bindings by their values
+func (s *MetricsGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsGremlinTraversalStep{context: context}, nil
+}
+
+// PushDown returns where the flow metrics were looked up
+func (s *MetricsGremlinTraversalStep) PushDown() string {
+	return s.pushDown
+}
+
 // MetricsTraversalStep traversal step metric interface counters
 type MetricsTraversalStep struct {
 	GraphTraversal *traversal.GraphTraversal
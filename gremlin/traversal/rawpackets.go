@@ -39,7 +39,8 @@ type RawPacketsTraversalExtension struct {
 
 // RawPacketsGremlinTraversalStep rawpackets step
 type RawPacketsGremlinTraversalStep struct {
-	context traversal.GremlinTraversalContext
+	context  traversal.GremlinTraversalContext
+	pushDown string
 }
 
 // RawPacketsTraversalStep rawpackets step
@@ -76,9 +77,11 @@ func (e *RawPacketsTraversalExtension) ParseStep(t traversal.Token, p traversal.
 
 // Exec RawPackets step
 func (r *RawPacketsGremlinTraversalStep) Exec(last traversal.GraphTraversalStep) (traversal.GraphTraversalStep, error) {
+	r.pushDown = ""
 	switch last.(type) {
 	case *FlowTraversalStep:
 		fs := last.(*FlowTraversalStep)
+		r.pushDown = fs.pushDown()
 		return fs.RawPackets(), nil
 	}
 
@@ -95,6 +98,20 @@ func (r *RawPacketsGremlinTraversalStep) Context() *traversal.GremlinTraversalCo
 	return &r.context
 }
 
+// Bind RawPackets step, replacing the bindings by their values
+func (r *RawPacketsGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(r.context)
+	if err != nil {
+		return nil, err
+	}
+	return &RawPacketsGremlinTraversalStep{context: context}, nil
+}
+
+// PushDown returns where the raw packets were looked up
+func (r *RawPacketsGremlinTraversalStep) PushDown() string {
+	return r.pushDown
+}
+
 // Values returns list of raw packets
 func (r *RawPacketsTraversalStep) Values() []interface{} {
 	if len(r.rawPackets) == 0 {
@@ -88,6 +88,15 @@ func (s *SocketsGremlinTraversalStep) Context() *traversal.GremlinTraversalConte
 	return &s.context
 }
 
+// Bind sockets step, replacing the bindings by their values
+func (s *SocketsGremlinTraversalStep) Bind(bindings traversal.Bindings) (traversal.GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+	return &SocketsGremlinTraversalStep{context: context}, nil
+}
+
 // SocketsTraversalStep connections step
 type SocketsTraversalStep struct {
 	GraphTraversal *traversal.GraphTraversal
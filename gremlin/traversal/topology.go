@@ -23,8 +23,6 @@
 package traversal
 
 import (
-	"strings"
-
 	"github.com/mitchellh/mapstructure"
 
 	"github.com/skydive-project/skydive/common"
@@ -118,13 +116,25 @@ nodeloop:
 	return &SocketsTraversalStep{GraphTraversal: tv.GraphTraversal, sockets: sockets}
 }
 
+// topologyGremlinParser parses, and caches, the queries without any extension
+var topologyGremlinParser = traversal.NewGremlinTraversalParser()
+
 // TopologyGremlinQuery run a gremlin query on the graph g without any extension
 func TopologyGremlinQuery(g *graph.Graph, query string) (traversal.GraphTraversalStep, error) {
-	tr := traversal.NewGremlinTraversalParser()
-	ts, err := tr.Parse(strings.NewReader(query))
+	return TopologyGremlinBoundQuery(g, query, nil)
+}
+
+// TopologyGremlinBoundQuery run a gremlin query on the graph g without any
+// extension, its bindings being replaced by the given values
+func TopologyGremlinBoundQuery(g *graph.Graph, query string, bindings traversal.Bindings) (traversal.GraphTraversalStep, error) {
+	ts, err := topologyGremlinParser.ParseQuery(query)
 	if err != nil {
 		return nil, err
 	}
 
+	if ts, err = ts.Bind(bindings); err != nil {
+		return nil, err
+	}
+
 	return ts.Exec(g, false)
 }
@@ -62,6 +62,11 @@ func Quote(format string, a ...interface{}) ValueString {
 	return ValueString(fmt.Sprintf(`"%s"`, s))
 }
 
+// Binding used for a named parameter whose value is given along with the query
+func Binding(name string) ValueString {
+	return ValueString("$" + name)
+}
+
 // Regex used for constructing a regexp expression string
 func Regex(format string, a ...interface{}) ValueString {
 	s := fmt.Sprintf(format, a...)
@@ -101,6 +106,11 @@ func Ipv4Range(list ...interface{}) ValueString {
 	return newValueString("Ipv4Range", list...)
 }
 
+// Ipv6Range append a Ipv6Range() operation to query
+func Ipv6Range(list ...interface{}) ValueString {
+	return newValueString("Ipv6Range", list...)
+}
+
 // Inside append a Inside() operation to query
 func Inside(list ...interface{}) ValueString {
 	return newValueString("Inside", list...)
@@ -32,6 +32,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strings"
 
 	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/config"
@@ -87,7 +88,13 @@ func (c *RestClient) debug() bool {
 }
 
 func (c *RestClient) Request(method, path string, body io.Reader, header http.Header) (*http.Response, error) {
-	url := c.url.ResolveReference(&url.URL{Path: path})
+	// the path may hold a query string
+	ref := &url.URL{Path: path}
+	if i := strings.IndexByte(path, '?'); i != -1 {
+		ref.Path, ref.RawQuery = path[:i], path[i+1:]
+	}
+
+	url := c.url.ResolveReference(ref)
 	req, err := http.NewRequest(method, url.String(), body)
 	if err != nil {
 		return nil, err
@@ -174,6 +174,9 @@ export class Capture extends APIObject {
 export class PacketInjection extends APIObject {
 }
 
+export class Silence extends APIObject {
+}
+
 export class API<T extends APIObject> {
     Resource: string
     Factory: new () => T
@@ -466,6 +469,14 @@ export class V extends Step {
         return new Keys(this.api, this, ...params);
     }
 
+    ValueMap(...params: any[]): Value {
+        return new ValueMap(this.api, this, ...params);
+    }
+
+    Project(...params: any[]): Project {
+        return new Project(this.api, this, ...params);
+    }
+
     Sum(...params: any[]): Value {
         return new Keys(this.api, this, ...params);
     }
@@ -542,6 +553,14 @@ export class E extends Step {
         return new Keys(this.api, this, ...params);
     }
 
+    ValueMap(...params: any[]): Value {
+        return new ValueMap(this.api, this, ...params);
+    }
+
+    Project(...params: any[]): Project {
+        return new Project(this.api, this, ...params);
+    }
+
     Sort(...params: any[]): E {
         return new SortE(this.api, this, ...params);
     }
@@ -619,7 +638,7 @@ export class ShortestPath extends Step {
     serialize(data) {
         var items: GraphNode[][] = [];
         for (var obj in data) {
-            items.push(SerializationHelper.unmarshalArray(data, GraphNode));
+            items.push(SerializationHelper.unmarshalArray(data[obj], GraphNode));
         }
         return items;
     }
@@ -663,6 +682,10 @@ export function IPV4RANGE(param: any): Predicate {
     return new Predicate("IPV4RANGE", param)
 }
 
+export function IPV6RANGE(param: any): Predicate {
+    return new Predicate("IPV6RANGE", param)
+}
+
 export function REGEX(param: any): Predicate {
     return new Predicate("REGEX", param)
 }
@@ -692,6 +715,10 @@ export var NOW = new Predicate("NOW")
 export var DESC = new Predicate("DESC")
 export var ASC = new Predicate("ASC")
 
+// Weight aggregations of ShortestPathTo(Metadata(...), "Key", SUM)
+export var SUM = "sum"
+export var MIN = "min"
+
 export class Metric {
 }
 
@@ -736,6 +763,14 @@ export class Flows extends Step {
         return new Keys(this.api, this, ...params);
     }
 
+    ValueMap(...params: any[]): Value {
+        return new ValueMap(this.api, this, ...params);
+    }
+
+    Project(...params: any[]): Project {
+        return new Project(this.api, this, ...params);
+    }
+
     Sum(...params: any[]): Value {
         return new Keys(this.api, this, ...params);
     }
@@ -819,6 +854,22 @@ export class Sum extends Value {
     name() { return "Sum" }
 }
 
+export class ValueMap extends Value {
+    name() { return "ValueMap" }
+}
+
+export class Project extends Value {
+    name() { return "Project" }
+
+    By(...params: any[]): Project {
+        return new By(this.api, this, ...params);
+    }
+}
+
+export class By extends Project {
+    name() { return "By" }
+}
+
 export class Metrics extends Step {
     name() { return "Metrics" }
 
@@ -887,6 +938,7 @@ export class Client {
     cookie: string
     alerts: API<Alert>
     captures: API<Capture>
+    silences: API<Silence>
     gremlin: GremlinAPI
     G: G
 
@@ -899,6 +951,7 @@ export class Client {
 
         this.alerts = new API(this, "alert", Alert);
         this.captures = new API(this, "capture", Capture);
+        this.silences = new API(this, "silence", Silence);
         this.gremlin = new GremlinAPI(this);
         this.G = this.gremlin.G();
     }
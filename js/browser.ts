@@ -30,6 +30,7 @@ window.LT = apiLib.LT
 window.GTE = apiLib.GTE
 window.LTE = apiLib.LTE
 window.IPV4RANGE = apiLib.IPV4RANGE
+window.IPV6RANGE = apiLib.IPV6RANGE
 window.REGEX = apiLib.REGEX
 window.WITHIN = apiLib.WITHIN
 window.WITHOUT = apiLib.WITHOUT
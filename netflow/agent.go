@@ -0,0 +1,225 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package netflow
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/flow"
+	"github.com/skydive-project/skydive/logging"
+)
+
+const (
+	maxDgramSize = 65535
+)
+
+var (
+	// ErrAgentAlreadyAllocated error agent already allocated for this uuid
+	ErrAgentAlreadyAllocated = errors.New("agent already allocated for this uuid")
+)
+
+// NetFlowAgent describes NetFlow/IPFIX agent probe
+type NetFlowAgent struct {
+	common.RWMutex
+	UUID      string
+	Addr      string
+	Port      int
+	FlowTable *flow.Table
+	Conn      *net.UDPConn
+	Resolver  InterfaceResolver
+	decoder   *Decoder
+	flows     *flowCache
+}
+
+// NetFlowAgentAllocator describes a NetFlow agent allocator to manage multiple NetFlow agent probe
+type NetFlowAgentAllocator struct {
+	common.RWMutex
+	portAllocator *common.PortAllocator
+	agents        []*NetFlowAgent
+}
+
+// GetTarget returns the current used connection
+func (nfa *NetFlowAgent) GetTarget() string {
+	target := []string{nfa.Addr, strconv.FormatInt(int64(nfa.Port), 10)}
+	return strings.Join(target, ":")
+}
+
+func (nfa *NetFlowAgent) feedFlowTable(flowChan chan *flow.Flow) {
+	var buf [maxDgramSize]byte
+	for {
+		n, addr, err := nfa.Conn.ReadFromUDP(buf[:])
+		if err != nil {
+			return
+		}
+
+		// templates are bound to the exporter address, records keep
+		// references to the datagram so it can't be reused.
+		data := make([]byte, n)
+		copy(data, buf[:n])
+
+		exporter := addr.IP.String()
+
+		packet, err := nfa.decoder.Decode(exporter, data)
+		if err != nil {
+			logging.GetLogger().Errorf("Unable to decode NetFlow packet from %s: %s", exporter, err)
+			if packet == nil {
+				continue
+			}
+		}
+
+		logging.GetLogger().Debugf("%d NetFlow records received from %s", len(packet.Records), exporter)
+		for i, f := range packet.Flows(exporter, nfa.FlowTable.NodeTID(), nfa.Resolver) {
+			if packet.Records[i].isDelta() {
+				nfa.flows.accumulate(f)
+			}
+			flowChan <- f
+		}
+	}
+}
+
+func (nfa *NetFlowAgent) start() error {
+	nfa.Lock()
+	addr := net.UDPAddr{
+		Port: nfa.Port,
+		IP:   net.ParseIP(nfa.Addr),
+	}
+	conn, err := net.ListenUDP("udp", &addr)
+	if err != nil {
+		logging.GetLogger().Errorf("Unable to listen on port %d: %s", nfa.Port, err.Error())
+		nfa.Unlock()
+		return err
+	}
+	nfa.Conn = conn
+	nfa.Unlock()
+
+	_, flowChan := nfa.FlowTable.Start()
+	defer nfa.FlowTable.Stop()
+
+	nfa.feedFlowTable(flowChan)
+
+	return nil
+}
+
+// Start the NetFlow probe agent
+func (nfa *NetFlowAgent) Start() {
+	go nfa.start()
+}
+
+// Stop the NetFlow probe agent
+func (nfa *NetFlowAgent) Stop() {
+	nfa.Lock()
+	defer nfa.Unlock()
+
+	if nfa.Conn != nil {
+		nfa.Conn.Close()
+	}
+}
+
+// NewNetFlowAgent creates a new NetFlow agent which will populate the given flowtable
+func NewNetFlowAgent(u string, a *common.ServiceAddress, ft *flow.Table, resolver InterfaceResolver) *NetFlowAgent {
+	return &NetFlowAgent{
+		UUID:      u,
+		Addr:      a.Addr,
+		Port:      a.Port,
+		FlowTable: ft,
+		Resolver:  resolver,
+		decoder:   NewDecoder(),
+		flows:     newFlowCache(time.Duration(config.GetInt("flow.expire")) * time.Second),
+	}
+}
+
+func (a *NetFlowAgentAllocator) release(uuid string) {
+	for i, agent := range a.agents {
+		if uuid == agent.UUID {
+			agent.Stop()
+			a.portAllocator.Release(agent.Port)
+			a.agents = append(a.agents[:i], a.agents[i+1:]...)
+
+			break
+		}
+	}
+}
+
+// Release a NetFlow agent
+func (a *NetFlowAgentAllocator) Release(uuid string) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.release(uuid)
+}
+
+// ReleaseAll NetFlow agents
+func (a *NetFlowAgentAllocator) ReleaseAll() {
+	a.Lock()
+	defer a.Unlock()
+
+	for _, agent := range a.agents {
+		a.release(agent.UUID)
+	}
+}
+
+// Alloc allocates a new NetFlow agent
+func (a *NetFlowAgentAllocator) Alloc(uuid string, ft *flow.Table, addr *common.ServiceAddress, resolver InterfaceResolver) (agent *NetFlowAgent, _ error) {
+	a.Lock()
+	defer a.Unlock()
+
+	// check if there is an already allocated agent for this uuid
+	for _, agent := range a.agents {
+		if uuid == agent.UUID {
+			return agent, ErrAgentAlreadyAllocated
+		}
+	}
+
+	// get port, if port is not given by user.
+	var err error
+	if addr.Port <= 0 {
+		if addr.Port, err = a.portAllocator.Allocate(); addr.Port <= 0 {
+			return nil, errors.New("failed to allocate netflow port: " + err.Error())
+		}
+	}
+	s := NewNetFlowAgent(uuid, addr, ft, resolver)
+
+	a.agents = append(a.agents, s)
+
+	s.Start()
+	return s, nil
+}
+
+// NewNetFlowAgentAllocator creates a new NetFlow agent allocator
+func NewNetFlowAgentAllocator() (*NetFlowAgentAllocator, error) {
+	min := config.GetInt("netflow.port_min")
+	max := config.GetInt("netflow.port_max")
+
+	portAllocator, err := common.NewPortAllocator(min, max)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NetFlowAgentAllocator{portAllocator: portAllocator}, nil
+}
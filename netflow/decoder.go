@@ -0,0 +1,531 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+// Protocol versions
+const (
+	VersionNetFlow5 = 5
+	VersionNetFlow9 = 9
+	VersionIPFIX    = 10
+)
+
+// Information element IDs, shared between NetFlow v9 and IPFIX (RFC 7012)
+const (
+	IEOctetDeltaCount          uint16 = 1
+	IEPacketDeltaCount         uint16 = 2
+	IEProtocolIdentifier       uint16 = 4
+	IETCPControlBits           uint16 = 6
+	IESourceTransportPort      uint16 = 7
+	IESourceIPv4Address        uint16 = 8
+	IEIngressInterface         uint16 = 10
+	IEDestinationTransportPort uint16 = 11
+	IEDestinationIPv4Address   uint16 = 12
+	IEEgressInterface          uint16 = 14
+	IEFlowEndSysUpTime         uint16 = 21
+	IEFlowStartSysUpTime       uint16 = 22
+	IESourceIPv6Address        uint16 = 27
+	IEDestinationIPv6Address   uint16 = 28
+	IEICMPTypeCodeIPv4         uint16 = 32
+	IESamplingInterval         uint16 = 34
+	IESamplerID                uint16 = 48
+	IESamplerRandomInterval    uint16 = 50
+	IESourceMacAddress         uint16 = 56
+	IEVlanID                   uint16 = 58
+	IEDestinationMacAddress    uint16 = 80
+	IEOctetTotalCount          uint16 = 85
+	IEPacketTotalCount         uint16 = 86
+	IEICMPTypeCodeIPv6         uint16 = 139
+	IEFlowStartSeconds         uint16 = 150
+	IEFlowEndSeconds           uint16 = 151
+	IEFlowStartMilliseconds    uint16 = 152
+	IEFlowEndMilliseconds      uint16 = 153
+	IESelectorID               uint16 = 302
+	IESamplingPacketInterval   uint16 = 305
+	IESamplingPacketSpace      uint16 = 306
+)
+
+const (
+	netflow5HeaderLength = 24
+	netflow5RecordLength = 48
+	netflow9HeaderLength = 20
+	ipfixHeaderLength    = 16
+	setHeaderLength      = 4
+
+	netflow9TemplateSetID        = 0
+	netflow9OptionsTemplateSetID = 1
+	ipfixTemplateSetID           = 2
+	ipfixOptionsTemplateSetID    = 3
+	minDataSetID                 = 256
+
+	enterpriseBit  = 0x8000
+	variableLength = 0xffff
+)
+
+var (
+	// ErrPacketTooShort the datagram is truncated
+	ErrPacketTooShort = errors.New("netflow packet too short")
+	// ErrUnknownVersion the datagram version is not supported
+	ErrUnknownVersion = errors.New("unsupported netflow version")
+)
+
+// TemplateField describes a field of a template
+type TemplateField struct {
+	ID               uint16
+	Length           uint16
+	EnterpriseNumber uint32
+}
+
+// Template describes a NetFlow v9 or IPFIX data template. The fields of
+// options templates start with ScopeCount scope fields.
+type Template struct {
+	ID         uint16
+	Fields     []TemplateField
+	Options    bool
+	ScopeCount int
+}
+
+// Record holds the values of a data record indexed by information element
+// ID. Only IANA information elements are kept.
+type Record struct {
+	Fields map[uint16][]byte
+	// SamplingInterval is the packet sampling interval applied by the
+	// exporter, 0 when unknown
+	SamplingInterval uint32
+}
+
+// Packet describes a decoded NetFlow/IPFIX datagram
+type Packet struct {
+	Version    uint16
+	ExportTime time.Time
+	// SysUptime in milliseconds, only for NetFlow v5 and v9
+	SysUptime        uint32
+	SequenceNumber   uint32
+	DomainID         uint32
+	SamplingInterval uint16
+	Records          []*Record
+}
+
+type templateKey struct {
+	exporter string
+	domainID uint32
+	id       uint16
+}
+
+type samplerKey struct {
+	exporter string
+	domainID uint32
+	id       uint64
+}
+
+// Decoder decodes NetFlow v5, v9 and IPFIX datagrams. It keeps the templates
+// received from each exporter in order to decode the following data sets, as
+// well as the sampling intervals announced in options data records.
+type Decoder struct {
+	common.RWMutex
+	templates map[templateKey]*Template
+	samplers  map[samplerKey]uint32
+}
+
+// Uint returns the value of a field as an unsigned integer
+func (r *Record) Uint(id uint16) (uint64, bool) {
+	b, ok := r.Fields[id]
+	if !ok || len(b) == 0 || len(b) > 8 {
+		return 0, false
+	}
+
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, true
+}
+
+// Bytes returns the raw value of a field
+func (r *Record) Bytes(id uint16) ([]byte, bool) {
+	b, ok := r.Fields[id]
+	return b, ok
+}
+
+// samplingInterval returns the sampling interval carried by a record, either
+// a data record or a sampler options record, 0 if none
+func (r *Record) samplingInterval() uint32 {
+	if v, ok := r.Uint(IESamplingInterval); ok && v > 0 {
+		return uint32(v)
+	}
+	if v, ok := r.Uint(IESamplerRandomInterval); ok && v > 0 {
+		return uint32(v)
+	}
+	// one packet selected, then space packets skipped
+	if interval, ok := r.Uint(IESamplingPacketInterval); ok && interval > 0 {
+		space, _ := r.Uint(IESamplingPacketSpace)
+		return uint32((interval + space) / interval)
+	}
+	return 0
+}
+
+// samplerID returns the ID of the sampler of a record, 0 if none
+func (r *Record) samplerID() uint64 {
+	if v, ok := r.Uint(IESamplerID); ok {
+		return v
+	}
+	if v, ok := r.Uint(IESelectorID); ok {
+		return v
+	}
+	return 0
+}
+
+func (d *Decoder) decodeNetFlow5(data []byte) (*Packet, error) {
+	if len(data) < netflow5HeaderLength {
+		return nil, ErrPacketTooShort
+	}
+
+	count := int(binary.BigEndian.Uint16(data[2:4]))
+	p := &Packet{
+		Version:          VersionNetFlow5,
+		SysUptime:        binary.BigEndian.Uint32(data[4:8]),
+		ExportTime:       time.Unix(int64(binary.BigEndian.Uint32(data[8:12])), int64(binary.BigEndian.Uint32(data[12:16]))),
+		SequenceNumber:   binary.BigEndian.Uint32(data[16:20]),
+		DomainID:         uint32(data[20])<<8 | uint32(data[21]),
+		SamplingInterval: binary.BigEndian.Uint16(data[22:24]) & 0x3fff,
+	}
+
+	if len(data) < netflow5HeaderLength+count*netflow5RecordLength {
+		return nil, ErrPacketTooShort
+	}
+
+	// map the fixed v5 record format to the v9/IPFIX information elements
+	// so that all the versions are handled the same way later on.
+	for i := 0; i < count; i++ {
+		b := data[netflow5HeaderLength+i*netflow5RecordLength:]
+		p.Records = append(p.Records, &Record{
+			SamplingInterval: uint32(p.SamplingInterval),
+			Fields: map[uint16][]byte{
+				IESourceIPv4Address:        b[0:4],
+				IEDestinationIPv4Address:   b[4:8],
+				IEIngressInterface:         b[12:14],
+				IEEgressInterface:          b[14:16],
+				IEPacketDeltaCount:         b[16:20],
+				IEOctetDeltaCount:          b[20:24],
+				IEFlowStartSysUpTime:       b[24:28],
+				IEFlowEndSysUpTime:         b[28:32],
+				IESourceTransportPort:      b[32:34],
+				IEDestinationTransportPort: b[34:36],
+				IETCPControlBits:           b[37:38],
+				IEProtocolIdentifier:       b[38:39],
+			},
+		})
+	}
+
+	return p, nil
+}
+
+// readTemplateFields reads count field specifiers, returning the remaining
+// data
+func readTemplateFields(data []byte, count int, ipfix bool) ([]TemplateField, []byte, error) {
+	var fields []TemplateField
+	for i := 0; i < count; i++ {
+		if len(data) < 4 {
+			return nil, data, ErrPacketTooShort
+		}
+
+		field := TemplateField{
+			ID:     binary.BigEndian.Uint16(data[0:2]),
+			Length: binary.BigEndian.Uint16(data[2:4]),
+		}
+		data = data[4:]
+
+		if ipfix && field.ID&enterpriseBit != 0 {
+			if len(data) < 4 {
+				return nil, data, ErrPacketTooShort
+			}
+			field.ID &^= enterpriseBit
+			field.EnterpriseNumber = binary.BigEndian.Uint32(data[0:4])
+			data = data[4:]
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, data, nil
+}
+
+func (d *Decoder) addTemplate(exporter string, domainID uint32, t *Template) {
+	d.Lock()
+	d.templates[templateKey{exporter: exporter, domainID: domainID, id: t.ID}] = t
+	d.Unlock()
+}
+
+func (d *Decoder) addTemplates(exporter string, domainID uint32, data []byte, ipfix bool) (err error) {
+	for len(data) >= 4 {
+		t := &Template{ID: binary.BigEndian.Uint16(data[0:2])}
+		count := int(binary.BigEndian.Uint16(data[2:4]))
+		data = data[4:]
+
+		// withdrawal or padding
+		if t.ID < minDataSetID {
+			return nil
+		}
+
+		if t.Fields, data, err = readTemplateFields(data, count, ipfix); err != nil {
+			return err
+		}
+
+		d.addTemplate(exporter, domainID, t)
+	}
+
+	return nil
+}
+
+// addOptionsTemplates reads options templates. NetFlow v9 gives the length
+// in bytes of the scope and option fields while IPFIX gives the number of
+// fields and of scope fields.
+func (d *Decoder) addOptionsTemplates(exporter string, domainID uint32, data []byte, ipfix bool) (err error) {
+	for len(data) >= 6 {
+		t := &Template{ID: binary.BigEndian.Uint16(data[0:2]), Options: true}
+
+		var count int
+		if ipfix {
+			count = int(binary.BigEndian.Uint16(data[2:4]))
+			t.ScopeCount = int(binary.BigEndian.Uint16(data[4:6]))
+		} else {
+			t.ScopeCount = int(binary.BigEndian.Uint16(data[2:4])) / 4
+			count = t.ScopeCount + int(binary.BigEndian.Uint16(data[4:6]))/4
+		}
+		data = data[6:]
+
+		// withdrawal or padding
+		if t.ID < minDataSetID {
+			return nil
+		}
+
+		if t.Fields, data, err = readTemplateFields(data, count, ipfix); err != nil {
+			return err
+		}
+
+		d.addTemplate(exporter, domainID, t)
+	}
+
+	return nil
+}
+
+// addSamplers keeps the sampling intervals of the sampler options records
+func (d *Decoder) addSamplers(exporter string, domainID uint32, records []*Record) {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, r := range records {
+		if interval := r.samplingInterval(); interval > 0 {
+			d.samplers[samplerKey{exporter: exporter, domainID: domainID, id: r.samplerID()}] = interval
+		}
+	}
+}
+
+// setSamplingIntervals sets the sampling interval of data records, given by
+// the record itself or by the options records of its sampler
+func (d *Decoder) setSamplingIntervals(exporter string, domainID uint32, records []*Record) {
+	d.RLock()
+	defer d.RUnlock()
+
+	for _, r := range records {
+		if r.SamplingInterval = r.samplingInterval(); r.SamplingInterval == 0 {
+			r.SamplingInterval = d.samplers[samplerKey{exporter: exporter, domainID: domainID, id: r.samplerID()}]
+		}
+	}
+}
+
+// minRecordLength returns the minimum length of a data record, variable
+// length fields accounting for at least their length prefix
+func (t *Template) minRecordLength() (length int) {
+	for _, field := range t.Fields {
+		if field.Length == variableLength {
+			length++
+		} else {
+			length += int(field.Length)
+		}
+	}
+	return
+}
+
+func (d *Decoder) decodeDataSet(t *Template, data []byte, ipfix bool) (records []*Record, err error) {
+	minLength := t.minRecordLength()
+	if minLength == 0 {
+		return nil, nil
+	}
+
+	// remaining bytes shorter than a record are padding
+	for len(data) >= minLength {
+		record := &Record{Fields: make(map[uint16][]byte)}
+
+		for i, field := range t.Fields {
+			length := int(field.Length)
+			if ipfix && field.Length == variableLength {
+				if len(data) < 1 {
+					return records, ErrPacketTooShort
+				}
+				length, data = int(data[0]), data[1:]
+				if length == 255 {
+					if len(data) < 2 {
+						return records, ErrPacketTooShort
+					}
+					length, data = int(binary.BigEndian.Uint16(data[0:2])), data[2:]
+				}
+			}
+
+			if len(data) < length {
+				return records, ErrPacketTooShort
+			}
+
+			// NetFlow v9 scope field types are not information elements
+			if field.EnterpriseNumber == 0 && (ipfix || i >= t.ScopeCount) {
+				record.Fields[field.ID] = data[:length]
+			}
+			data = data[length:]
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func (d *Decoder) decodeSets(p *Packet, exporter string, data []byte, ipfix bool) error {
+	templateSetID, optionsTemplateSetID := uint16(netflow9TemplateSetID), uint16(netflow9OptionsTemplateSetID)
+	if ipfix {
+		templateSetID, optionsTemplateSetID = ipfixTemplateSetID, ipfixOptionsTemplateSetID
+	}
+
+	for len(data) >= setHeaderLength {
+		id := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < setHeaderLength || length > len(data) {
+			return ErrPacketTooShort
+		}
+		set := data[setHeaderLength:length]
+		data = data[length:]
+
+		switch {
+		case id == templateSetID:
+			if err := d.addTemplates(exporter, p.DomainID, set, ipfix); err != nil {
+				return err
+			}
+		case id == optionsTemplateSetID:
+			if err := d.addOptionsTemplates(exporter, p.DomainID, set, ipfix); err != nil {
+				return err
+			}
+		case id >= minDataSetID:
+			d.RLock()
+			t, ok := d.templates[templateKey{exporter: exporter, domainID: p.DomainID, id: id}]
+			d.RUnlock()
+
+			if !ok {
+				// data received before its template, nothing we can do
+				continue
+			}
+
+			records, err := d.decodeDataSet(t, set, ipfix)
+			if t.Options {
+				// options records are not used to build flows
+				d.addSamplers(exporter, p.DomainID, records)
+			} else {
+				d.setSamplingIntervals(exporter, p.DomainID, records)
+				p.Records = append(p.Records, records...)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Decoder) decodeNetFlow9(exporter string, data []byte) (*Packet, error) {
+	if len(data) < netflow9HeaderLength {
+		return nil, ErrPacketTooShort
+	}
+
+	p := &Packet{
+		Version:        VersionNetFlow9,
+		SysUptime:      binary.BigEndian.Uint32(data[4:8]),
+		ExportTime:     time.Unix(int64(binary.BigEndian.Uint32(data[8:12])), 0),
+		SequenceNumber: binary.BigEndian.Uint32(data[12:16]),
+		DomainID:       binary.BigEndian.Uint32(data[16:20]),
+	}
+
+	return p, d.decodeSets(p, exporter, data[netflow9HeaderLength:], false)
+}
+
+func (d *Decoder) decodeIPFIX(exporter string, data []byte) (*Packet, error) {
+	if len(data) < ipfixHeaderLength {
+		return nil, ErrPacketTooShort
+	}
+
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < ipfixHeaderLength || length > len(data) {
+		return nil, ErrPacketTooShort
+	}
+
+	p := &Packet{
+		Version:        VersionIPFIX,
+		ExportTime:     time.Unix(int64(binary.BigEndian.Uint32(data[4:8])), 0),
+		SequenceNumber: binary.BigEndian.Uint32(data[8:12]),
+		DomainID:       binary.BigEndian.Uint32(data[12:16]),
+	}
+
+	return p, d.decodeSets(p, exporter, data[ipfixHeaderLength:length], true)
+}
+
+// Decode a datagram sent by the given exporter
+func (d *Decoder) Decode(exporter string, data []byte) (*Packet, error) {
+	if len(data) < 2 {
+		return nil, ErrPacketTooShort
+	}
+
+	switch version := binary.BigEndian.Uint16(data[0:2]); version {
+	case VersionNetFlow5:
+		return d.decodeNetFlow5(data)
+	case VersionNetFlow9:
+		return d.decodeNetFlow9(exporter, data)
+	case VersionIPFIX:
+		return d.decodeIPFIX(exporter, data)
+	default:
+		return nil, fmt.Errorf("%s: %d", ErrUnknownVersion, version)
+	}
+}
+
+// NewDecoder returns a new NetFlow/IPFIX decoder
+func NewDecoder() *Decoder {
+	return &Decoder{
+		templates: make(map[templateKey]*Template),
+		samplers:  make(map[samplerKey]uint32),
+	}
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func netflow5Packet() []byte {
+	data := make([]byte, netflow5HeaderLength+netflow5RecordLength)
+	binary.BigEndian.PutUint16(data[0:2], VersionNetFlow5)
+	binary.BigEndian.PutUint16(data[2:4], 1)
+	binary.BigEndian.PutUint32(data[4:8], 10000)       // sysuptime
+	binary.BigEndian.PutUint32(data[8:12], 1500000000) // unix secs
+
+	r := data[netflow5HeaderLength:]
+	copy(r[0:4], []byte{192, 168, 0, 1})
+	copy(r[4:8], []byte{192, 168, 0, 2})
+	binary.BigEndian.PutUint16(r[12:14], 3)   // input
+	binary.BigEndian.PutUint32(r[16:20], 10)  // packets
+	binary.BigEndian.PutUint32(r[20:24], 840) // bytes
+	binary.BigEndian.PutUint32(r[24:28], 8000)
+	binary.BigEndian.PutUint32(r[28:32], 9000)
+	binary.BigEndian.PutUint16(r[32:34], 34567)
+	binary.BigEndian.PutUint16(r[34:36], 80)
+	r[38] = 6
+
+	return data
+}
+
+func ipfixPacket(withTemplate bool) []byte {
+	var sets []byte
+
+	if withTemplate {
+		fields := [][2]uint16{
+			{IESourceIPv6Address, 16},
+			{IEDestinationIPv6Address, 16},
+			{IEProtocolIdentifier, 1},
+			{IESourceTransportPort, 2},
+			{IEDestinationTransportPort, 2},
+			{IEOctetDeltaCount, 8},
+			{IEPacketDeltaCount, 8},
+			{IEIngressInterface, 4},
+		}
+
+		template := make([]byte, setHeaderLength+4+len(fields)*4)
+		binary.BigEndian.PutUint16(template[0:2], ipfixTemplateSetID)
+		binary.BigEndian.PutUint16(template[2:4], uint16(len(template)))
+		binary.BigEndian.PutUint16(template[4:6], 256)
+		binary.BigEndian.PutUint16(template[6:8], uint16(len(fields)))
+		for i, f := range fields {
+			binary.BigEndian.PutUint16(template[8+i*4:], f[0])
+			binary.BigEndian.PutUint16(template[10+i*4:], f[1])
+		}
+		sets = append(sets, template...)
+	}
+
+	record := make([]byte, 57)
+	copy(record[0:16], []byte{0x20, 0x01, 0x0d, 0xb8, 15: 1})
+	copy(record[16:32], []byte{0x20, 0x01, 0x0d, 0xb8, 15: 2})
+	record[32] = 17
+	binary.BigEndian.PutUint16(record[33:35], 5353)
+	binary.BigEndian.PutUint16(record[35:37], 53)
+	binary.BigEndian.PutUint64(record[37:45], 1000)
+	binary.BigEndian.PutUint64(record[45:53], 5)
+	binary.BigEndian.PutUint32(record[53:57], 7)
+
+	set := make([]byte, setHeaderLength)
+	binary.BigEndian.PutUint16(set[0:2], 256)
+	binary.BigEndian.PutUint16(set[2:4], uint16(setHeaderLength+len(record)))
+	sets = append(sets, append(set, record...)...)
+
+	header := make([]byte, ipfixHeaderLength)
+	binary.BigEndian.PutUint16(header[0:2], VersionIPFIX)
+	binary.BigEndian.PutUint16(header[2:4], uint16(ipfixHeaderLength+len(sets)))
+	binary.BigEndian.PutUint32(header[4:8], 1500000000)
+
+	return append(header, sets...)
+}
+
+// flowSet returns a NetFlow v9 flowset padded to 32 bits
+func flowSet(id uint16, content []byte) []byte {
+	set := make([]byte, setHeaderLength+len(content)+(4-len(content)%4)%4)
+	binary.BigEndian.PutUint16(set[0:2], id)
+	binary.BigEndian.PutUint16(set[2:4], uint16(len(set)))
+	copy(set[setHeaderLength:], content)
+	return set
+}
+
+func netflow9Packet(sets ...[]byte) []byte {
+	data := make([]byte, netflow9HeaderLength)
+	binary.BigEndian.PutUint16(data[0:2], VersionNetFlow9)
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(sets)))
+	binary.BigEndian.PutUint32(data[8:12], 1500000000)
+	for _, set := range sets {
+		data = append(data, set...)
+	}
+	return data
+}
+
+func TestNetFlow5(t *testing.T) {
+	packet, err := NewDecoder().Decode("10.0.0.1", netflow5Packet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flows := packet.Flows("10.0.0.1", "node-tid", func(ifIndex int64) string {
+		if ifIndex == 3 {
+			return "if-tid"
+		}
+		return ""
+	})
+	if len(flows) != 1 {
+		t.Fatalf("Expected one flow, got %d", len(flows))
+	}
+
+	f := flows[0]
+	if f.NodeTID != "if-tid" {
+		t.Errorf("Flow should be attached to the interface, got %s", f.NodeTID)
+	}
+
+	if f.LayersPath != "IPv4/TCP" || f.Network.A != "192.168.0.1" || f.Network.B != "192.168.0.2" {
+		t.Errorf("Wrong network layer: %s %+v", f.LayersPath, f.Network)
+	}
+
+	if f.Transport.Protocol != flow.FlowProtocol_TCP || f.Transport.A != 34567 || f.Transport.B != 80 {
+		t.Errorf("Wrong transport layer: %+v", f.Transport)
+	}
+
+	if f.Metric.ABPackets != 10 || f.Metric.ABBytes != 840 {
+		t.Errorf("Wrong metric: %+v", f.Metric)
+	}
+
+	if f.Start != 1499999998000 || f.Last != 1499999999000 {
+		t.Errorf("Wrong timestamps: %d %d", f.Start, f.Last)
+	}
+}
+
+func TestIPFIXTemplate(t *testing.T) {
+	decoder := NewDecoder()
+
+	// data before template can't be decoded
+	packet, err := decoder.Decode("10.0.0.1", ipfixPacket(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet.Records) != 0 {
+		t.Fatalf("No record expected without template, got %d", len(packet.Records))
+	}
+
+	if packet, err = decoder.Decode("10.0.0.1", ipfixPacket(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	// template is now known
+	if packet, err = decoder.Decode("10.0.0.1", ipfixPacket(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	flows := packet.Flows("10.0.0.1", "node-tid", nil)
+	if len(flows) != 1 {
+		t.Fatalf("Expected one flow, got %d", len(flows))
+	}
+
+	f := flows[0]
+	if f.NodeTID != "node-tid" {
+		t.Errorf("Wrong node TID: %s", f.NodeTID)
+	}
+
+	if f.LayersPath != "IPv6/UDP" || f.Network.A != "2001:db8::1" || f.Network.B != "2001:db8::2" {
+		t.Errorf("Wrong network layer: %s %+v", f.LayersPath, f.Network)
+	}
+
+	if f.Transport.A != 5353 || f.Transport.B != 53 {
+		t.Errorf("Wrong transport layer: %+v", f.Transport)
+	}
+
+	if f.Metric.ABPackets != 5 || f.Metric.ABBytes != 1000 {
+		t.Errorf("Wrong metric: %+v", f.Metric)
+	}
+
+	// templates are scoped to the exporter
+	if packet, err = decoder.Decode("10.0.0.2", ipfixPacket(false)); err != nil {
+		t.Fatal(err)
+	}
+	if len(packet.Records) != 0 {
+		t.Errorf("Template of another exporter should not be used")
+	}
+}
+
+func TestNetFlow5Sampling(t *testing.T) {
+	data := netflow5Packet()
+	binary.BigEndian.PutUint16(data[22:24], 1<<14|100)
+
+	packet, err := NewDecoder().Decode("10.0.0.1", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := packet.Flows("10.0.0.1", "node-tid", nil)[0]
+	if f.Metric.ABPackets != 1000 || f.Metric.ABBytes != 84000 || f.SamplingRate != 100 || !f.EstimatedMetric {
+		t.Errorf("Metric should be scaled by the sampling interval: %d %+v", f.SamplingRate, f.Metric)
+	}
+}
+
+func TestNetFlow9OptionsSampling(t *testing.T) {
+	template := []byte{1, 0, 0, 6}
+	for _, field := range [][2]uint16{
+		{IESourceIPv4Address, 4},
+		{IEDestinationIPv4Address, 4},
+		{IEProtocolIdentifier, 1},
+		{IEPacketDeltaCount, 4},
+		{IEOctetDeltaCount, 4},
+		{IESamplerID, 1},
+	} {
+		template = append(template, byte(field[0]>>8), byte(field[0]), byte(field[1]>>8), byte(field[1]))
+	}
+
+	// the system scope, then the sampler ID and its interval
+	optionsTemplate := []byte{1, 1, 0, 4, 0, 8, 0, 1, 0, 4}
+	optionsTemplate = append(optionsTemplate, 0, byte(IESamplerID), 0, 1, 0, byte(IESamplingInterval), 0, 4)
+
+	options := []byte{10, 0, 0, 1, 2, 0, 0, 0, 10}
+	record := []byte{192, 168, 0, 1, 192, 168, 0, 2, 17, 0, 0, 0, 5, 0, 0, 1, 0, 2}
+
+	packet, err := NewDecoder().Decode("10.0.0.1", netflow9Packet(
+		flowSet(netflow9TemplateSetID, template),
+		flowSet(netflow9OptionsTemplateSetID, optionsTemplate),
+		flowSet(257, options),
+		flowSet(256, record),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packet.Records) != 1 {
+		t.Fatalf("Options records should not be returned, got %d records", len(packet.Records))
+	}
+
+	f := packet.Flows("10.0.0.1", "node-tid", nil)[0]
+	if f.Metric.ABPackets != 50 || f.Metric.ABBytes != 2560 || f.SamplingRate != 10 {
+		t.Errorf("Metric should be scaled by the sampler interval: %d %+v", f.SamplingRate, f.Metric)
+	}
+}
+
+func TestFlowCacheAccumulate(t *testing.T) {
+	decoder, cache := NewDecoder(), newFlowCache(time.Minute)
+
+	var f *flow.Flow
+	for i := 0; i < 2; i++ {
+		packet, err := decoder.Decode("10.0.0.1", netflow5Packet())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f = packet.Flows("10.0.0.1", "node-tid", nil)[0]
+		if !packet.Records[0].isDelta() {
+			t.Fatal("NetFlow v5 counters should be deltas")
+		}
+		cache.accumulate(f)
+	}
+
+	if f.Metric.ABPackets != 20 || f.Metric.ABBytes != 1680 {
+		t.Errorf("Exports of the same flow should add up: %+v", f.Metric)
+	}
+}
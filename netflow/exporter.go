@@ -0,0 +1,550 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/flow"
+	"github.com/skydive-project/skydive/logging"
+)
+
+// DefaultEnterpriseNumber is the private enterprise number used for the
+// Skydive specific information elements
+const DefaultEnterpriseNumber uint32 = 2312
+
+// ReverseEnterpriseNumber is the enterprise number used by RFC 5103 to
+// report the reverse direction of a biflow
+const ReverseEnterpriseNumber uint32 = 29305
+
+// Skydive enterprise-specific information elements
+const (
+	SkydiveIEFlowUUID     uint16 = 1
+	SkydiveIETrackingID   uint16 = 2
+	SkydiveIEL3TrackingID uint16 = 3
+	SkydiveIEParentUUID   uint16 = 4
+	SkydiveIENodeTID      uint16 = 5
+	SkydiveIELayersPath   uint16 = 6
+	SkydiveIEApplication  uint16 = 7
+	SkydiveIELinkID       uint16 = 8
+	SkydiveIENetworkID    uint16 = 9
+	SkydiveIERTT          uint16 = 10
+	SkydiveIEABSynStart   uint16 = 11
+	SkydiveIEBASynStart   uint16 = 12
+	SkydiveIEABSynTTL     uint16 = 13
+	SkydiveIEBASynTTL     uint16 = 14
+	SkydiveIEABFinStart   uint16 = 15
+	SkydiveIEBAFinStart   uint16 = 16
+	SkydiveIEABRstStart   uint16 = 17
+	SkydiveIEBARstStart   uint16 = 18
+)
+
+const (
+	templateIDIPv4 uint16 = 256 + iota
+	templateIDIPv6
+	templateIDLink
+
+	udpMaxMessageSize = 1420
+	tcpMaxMessageSize = 65535
+
+	exporterQueueSize = 1000
+)
+
+// informationElement describes how a flow field is exported
+type informationElement struct {
+	TemplateField
+	value func(f *flow.Flow) interface{}
+}
+
+// collector describes an IPFIX collector and its transport session
+type collector struct {
+	network      string
+	addr         string
+	conn         net.Conn
+	sequence     uint32
+	lastTemplate time.Time
+}
+
+// Exporter exports flows as IPFIX records to a set of collectors
+type Exporter struct {
+	collectors       []*collector
+	domainID         uint32
+	enterpriseNumber uint32
+	templateRefresh  time.Duration
+	templates        map[uint16][]informationElement
+	queue            chan []*flow.Flow
+	quit             chan bool
+	wg               sync.WaitGroup
+}
+
+func protocolIdentifier(f *flow.Flow) interface{} {
+	if f.Transport != nil {
+		switch f.Transport.Protocol {
+		case flow.FlowProtocol_TCP:
+			return uint64(6)
+		case flow.FlowProtocol_UDP:
+			return uint64(17)
+		case flow.FlowProtocol_SCTP:
+			return uint64(132)
+		}
+	}
+	if f.ICMP != nil && f.Network != nil {
+		if f.Network.Protocol == flow.FlowProtocol_IPV6 {
+			return uint64(58)
+		}
+		return uint64(1)
+	}
+	return uint64(0)
+}
+
+func macAddress(l *flow.FlowLayer, a bool) interface{} {
+	if l == nil {
+		return []byte{}
+	}
+
+	addr := l.B
+	if a {
+		addr = l.A
+	}
+
+	mac, _ := net.ParseMAC(addr)
+	return []byte(mac)
+}
+
+func ipAddress(l *flow.FlowLayer, a bool, ipv4 bool) interface{} {
+	addr := l.B
+	if a {
+		addr = l.A
+	}
+
+	ip := net.ParseIP(addr)
+	if ipv4 {
+		return []byte(ip.To4())
+	}
+	return []byte(ip.To16())
+}
+
+func metric(m *flow.FlowMetric, octets bool, ab bool) interface{} {
+	if m == nil {
+		return uint64(0)
+	}
+
+	switch {
+	case octets && ab:
+		return uint64(m.ABBytes)
+	case octets:
+		return uint64(m.BABytes)
+	case ab:
+		return uint64(m.ABPackets)
+	default:
+		return uint64(m.BAPackets)
+	}
+}
+
+func tcpMetric(f *flow.Flow, field string) interface{} {
+	if f.TCPMetric == nil {
+		return uint64(0)
+	}
+	v, _ := f.TCPMetric.GetFieldInt64(field)
+	return uint64(v)
+}
+
+func (e *Exporter) skydiveElement(id uint16, length uint16, value func(f *flow.Flow) interface{}) informationElement {
+	return informationElement{
+		TemplateField: TemplateField{ID: id, Length: length, EnterpriseNumber: e.enterpriseNumber},
+		value:         value,
+	}
+}
+
+func ianaElement(id uint16, length uint16, value func(f *flow.Flow) interface{}) informationElement {
+	return informationElement{
+		TemplateField: TemplateField{ID: id, Length: length},
+		value:         value,
+	}
+}
+
+func reverseElement(id uint16, length uint16, value func(f *flow.Flow) interface{}) informationElement {
+	return informationElement{
+		TemplateField: TemplateField{ID: id, Length: length, EnterpriseNumber: ReverseEnterpriseNumber},
+		value:         value,
+	}
+}
+
+// buildTemplates returns the IPv4, IPv6 and link only templates. They only
+// differ by the network addresses.
+func (e *Exporter) buildTemplates() {
+	base := []informationElement{
+		ianaElement(IEFlowStartMilliseconds, 8, func(f *flow.Flow) interface{} { return uint64(f.Start) }),
+		ianaElement(IEFlowEndMilliseconds, 8, func(f *flow.Flow) interface{} { return uint64(f.Last) }),
+		ianaElement(IESourceMacAddress, 6, func(f *flow.Flow) interface{} { return macAddress(f.Link, true) }),
+		ianaElement(IEDestinationMacAddress, 6, func(f *flow.Flow) interface{} { return macAddress(f.Link, false) }),
+	}
+
+	transport := []informationElement{
+		ianaElement(IEProtocolIdentifier, 1, protocolIdentifier),
+		ianaElement(IESourceTransportPort, 2, func(f *flow.Flow) interface{} {
+			if f.Transport == nil {
+				return uint64(0)
+			}
+			return uint64(f.Transport.A)
+		}),
+		ianaElement(IEDestinationTransportPort, 2, func(f *flow.Flow) interface{} {
+			if f.Transport == nil {
+				return uint64(0)
+			}
+			return uint64(f.Transport.B)
+		}),
+		ianaElement(IEOctetDeltaCount, 8, func(f *flow.Flow) interface{} { return metric(f.LastUpdateMetric, true, true) }),
+		ianaElement(IEPacketDeltaCount, 8, func(f *flow.Flow) interface{} { return metric(f.LastUpdateMetric, false, true) }),
+		reverseElement(IEOctetDeltaCount, 8, func(f *flow.Flow) interface{} { return metric(f.LastUpdateMetric, true, false) }),
+		reverseElement(IEPacketDeltaCount, 8, func(f *flow.Flow) interface{} { return metric(f.LastUpdateMetric, false, false) }),
+		ianaElement(IEOctetTotalCount, 8, func(f *flow.Flow) interface{} { return metric(f.Metric, true, true) }),
+		ianaElement(IEPacketTotalCount, 8, func(f *flow.Flow) interface{} { return metric(f.Metric, false, true) }),
+		reverseElement(IEOctetTotalCount, 8, func(f *flow.Flow) interface{} { return metric(f.Metric, true, false) }),
+		reverseElement(IEPacketTotalCount, 8, func(f *flow.Flow) interface{} { return metric(f.Metric, false, false) }),
+	}
+
+	skydive := []informationElement{
+		e.skydiveElement(SkydiveIEFlowUUID, variableLength, func(f *flow.Flow) interface{} { return f.UUID }),
+		e.skydiveElement(SkydiveIETrackingID, variableLength, func(f *flow.Flow) interface{} { return f.TrackingID }),
+		e.skydiveElement(SkydiveIEL3TrackingID, variableLength, func(f *flow.Flow) interface{} { return f.L3TrackingID }),
+		e.skydiveElement(SkydiveIEParentUUID, variableLength, func(f *flow.Flow) interface{} { return f.ParentUUID }),
+		e.skydiveElement(SkydiveIENodeTID, variableLength, func(f *flow.Flow) interface{} { return f.NodeTID }),
+		e.skydiveElement(SkydiveIELayersPath, variableLength, func(f *flow.Flow) interface{} { return f.LayersPath }),
+		e.skydiveElement(SkydiveIEApplication, variableLength, func(f *flow.Flow) interface{} { return f.Application }),
+		e.skydiveElement(SkydiveIELinkID, 8, func(f *flow.Flow) interface{} {
+			if f.Link == nil {
+				return uint64(0)
+			}
+			return uint64(f.Link.ID)
+		}),
+		e.skydiveElement(SkydiveIENetworkID, 8, func(f *flow.Flow) interface{} {
+			if f.Network == nil {
+				return uint64(0)
+			}
+			return uint64(f.Network.ID)
+		}),
+		e.skydiveElement(SkydiveIERTT, 8, func(f *flow.Flow) interface{} { return uint64(f.RTT) }),
+		e.skydiveElement(SkydiveIEABSynStart, 8, func(f *flow.Flow) interface{} { return tcpMetric(f, "ABSynStart") }),
+		e.skydiveElement(SkydiveIEBASynStart, 8, func(f *flow.Flow) interface{} { return tcpMetric(f, "BASynStart") }),
+		e.skydiveElement(SkydiveIEABSynTTL, 4, func(f *flow.Flow) interface{} { return tcpMetric(f, "ABSynTTL") }),
+		e.skydiveElement(SkydiveIEBASynTTL, 4, func(f *flow.Flow) interface{} { return tcpMetric(f, "BASynTTL") }),
+		e.skydiveElement(SkydiveIEABFinStart, 8, func(f *flow.Flow) interface{} { return tcpMetric(f, "ABFinStart") }),
+		e.skydiveElement(SkydiveIEBAFinStart, 8, func(f *flow.Flow) interface{} { return tcpMetric(f, "BAFinStart") }),
+		e.skydiveElement(SkydiveIEABRstStart, 8, func(f *flow.Flow) interface{} { return tcpMetric(f, "ABRstStart") }),
+		e.skydiveElement(SkydiveIEBARstStart, 8, func(f *flow.Flow) interface{} { return tcpMetric(f, "BARstStart") }),
+	}
+
+	build := func(addresses ...informationElement) (ies []informationElement) {
+		ies = append(ies, base...)
+		ies = append(ies, addresses...)
+		ies = append(ies, transport...)
+		return append(ies, skydive...)
+	}
+
+	e.templates = map[uint16][]informationElement{
+		templateIDIPv4: build(
+			ianaElement(IESourceIPv4Address, 4, func(f *flow.Flow) interface{} { return ipAddress(f.Network, true, true) }),
+			ianaElement(IEDestinationIPv4Address, 4, func(f *flow.Flow) interface{} { return ipAddress(f.Network, false, true) }),
+		),
+		templateIDIPv6: build(
+			ianaElement(IESourceIPv6Address, 16, func(f *flow.Flow) interface{} { return ipAddress(f.Network, true, false) }),
+			ianaElement(IEDestinationIPv6Address, 16, func(f *flow.Flow) interface{} { return ipAddress(f.Network, false, false) }),
+		),
+		templateIDLink: build(),
+	}
+}
+
+func templateIDForFlow(f *flow.Flow) uint16 {
+	if f.Network != nil {
+		switch f.Network.Protocol {
+		case flow.FlowProtocol_IPV4:
+			return templateIDIPv4
+		case flow.FlowProtocol_IPV6:
+			return templateIDIPv6
+		}
+	}
+	return templateIDLink
+}
+
+func encodeValue(buf *bytes.Buffer, field TemplateField, value interface{}) {
+	switch v := value.(type) {
+	case uint64:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, v)
+		buf.Write(b[8-field.Length:])
+	case []byte:
+		b := make([]byte, field.Length)
+		copy(b, v)
+		buf.Write(b)
+	case string:
+		if len(v) < 255 {
+			buf.WriteByte(byte(len(v)))
+		} else {
+			if len(v) > 0xffff {
+				v = v[:0xffff]
+			}
+			buf.WriteByte(255)
+			binary.Write(buf, binary.BigEndian, uint16(len(v)))
+		}
+		buf.WriteString(v)
+	}
+}
+
+// encodeRecord encodes the flow as a data record of the given template
+func (e *Exporter) encodeRecord(id uint16, f *flow.Flow) []byte {
+	var buf bytes.Buffer
+	for _, ie := range e.templates[id] {
+		encodeValue(&buf, ie.TemplateField, ie.value(f))
+	}
+	return buf.Bytes()
+}
+
+// templateSet returns the template set announcing all the templates
+func (e *Exporter) templateSet() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint16(ipfixTemplateSetID))
+	binary.Write(&buf, binary.BigEndian, uint16(0))
+
+	for _, id := range []uint16{templateIDIPv4, templateIDIPv6, templateIDLink} {
+		ies := e.templates[id]
+		binary.Write(&buf, binary.BigEndian, id)
+		binary.Write(&buf, binary.BigEndian, uint16(len(ies)))
+		for _, ie := range ies {
+			if ie.EnterpriseNumber != 0 {
+				binary.Write(&buf, binary.BigEndian, ie.ID|enterpriseBit)
+				binary.Write(&buf, binary.BigEndian, ie.Length)
+				binary.Write(&buf, binary.BigEndian, ie.EnterpriseNumber)
+			} else {
+				binary.Write(&buf, binary.BigEndian, ie.ID)
+				binary.Write(&buf, binary.BigEndian, ie.Length)
+			}
+		}
+	}
+
+	set := buf.Bytes()
+	binary.BigEndian.PutUint16(set[2:4], uint16(len(set)))
+	return set
+}
+
+// message returns an IPFIX message made of the given sets
+func (e *Exporter) message(sequence uint32, sets ...[]byte) []byte {
+	length := ipfixHeaderLength
+	for _, set := range sets {
+		length += len(set)
+	}
+
+	msg := make([]byte, ipfixHeaderLength, length)
+	binary.BigEndian.PutUint16(msg[0:2], VersionIPFIX)
+	binary.BigEndian.PutUint16(msg[2:4], uint16(length))
+	binary.BigEndian.PutUint32(msg[4:8], uint32(time.Now().Unix()))
+	binary.BigEndian.PutUint32(msg[8:12], sequence)
+	binary.BigEndian.PutUint32(msg[12:16], e.domainID)
+
+	for _, set := range sets {
+		msg = append(msg, set...)
+	}
+	return msg
+}
+
+func dataSet(id uint16, records [][]byte) []byte {
+	length := setHeaderLength
+	for _, record := range records {
+		length += len(record)
+	}
+
+	set := make([]byte, setHeaderLength, length)
+	binary.BigEndian.PutUint16(set[0:2], id)
+	binary.BigEndian.PutUint16(set[2:4], uint16(length))
+	for _, record := range records {
+		set = append(set, record...)
+	}
+	return set
+}
+
+func (c *collector) maxMessageSize() int {
+	if c.network == "udp" {
+		return udpMaxMessageSize
+	}
+	return tcpMaxMessageSize
+}
+
+func (c *collector) write(msg []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write(msg); err != nil {
+		// the transport session will be restarted, with templates, on the next export
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (c *collector) send(e *Exporter, records map[uint16][][]byte) error {
+	if c.conn == nil {
+		conn, err := net.Dial(c.network, c.addr)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+		c.sequence = 0
+		c.lastTemplate = time.Time{}
+	}
+
+	// templates are sent once per TCP session and periodically over UDP
+	if c.lastTemplate.IsZero() || (c.network == "udp" && time.Now().Sub(c.lastTemplate) > e.templateRefresh) {
+		if err := c.write(e.message(c.sequence, e.templateSet())); err != nil {
+			return err
+		}
+		c.lastTemplate = time.Now()
+	}
+
+	maxSize := c.maxMessageSize() - ipfixHeaderLength - setHeaderLength
+	for id, recs := range records {
+		for len(recs) > 0 {
+			size, n := 0, 0
+			for n < len(recs) && (n == 0 || size+len(recs[n]) <= maxSize) {
+				size += len(recs[n])
+				n++
+			}
+
+			if err := c.write(e.message(c.sequence, dataSet(id, recs[:n]))); err != nil {
+				return err
+			}
+			c.sequence += uint32(n)
+			recs = recs[n:]
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) export(flows []*flow.Flow) {
+	records := make(map[uint16][][]byte)
+	for _, f := range flows {
+		id := templateIDForFlow(f)
+		records[id] = append(records[id], e.encodeRecord(id, f))
+	}
+
+	for _, c := range e.collectors {
+		if err := c.send(e, records); err != nil {
+			logging.GetLogger().Errorf("Unable to export flows to IPFIX collector %s://%s: %s", c.network, c.addr, err)
+		}
+	}
+}
+
+func (e *Exporter) run() {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case <-e.quit:
+			for _, c := range e.collectors {
+				if c.conn != nil {
+					c.conn.Close()
+				}
+			}
+			return
+		case flows := <-e.queue:
+			e.export(flows)
+		}
+	}
+}
+
+// Export queues the flows to be sent to the collectors
+func (e *Exporter) Export(flows []*flow.Flow) {
+	// the caller may reuse the slice
+	fls := make([]*flow.Flow, len(flows))
+	copy(fls, flows)
+
+	select {
+	case e.queue <- fls:
+	default:
+		logging.GetLogger().Errorf("IPFIX export queue full, dropping %d flows", len(flows))
+	}
+}
+
+// Start the exporter
+func (e *Exporter) Start() {
+	e.wg.Add(1)
+	go e.run()
+}
+
+// Stop the exporter
+func (e *Exporter) Stop() {
+	e.quit <- true
+	e.wg.Wait()
+}
+
+// NewExporter returns a new IPFIX exporter sending flows to the given
+// collectors, using udp://host:port or tcp://host:port addresses
+func NewExporter(collectors []string, domainID uint32, enterpriseNumber uint32, templateRefresh time.Duration) (*Exporter, error) {
+	e := &Exporter{
+		domainID:         domainID,
+		enterpriseNumber: enterpriseNumber,
+		templateRefresh:  templateRefresh,
+		queue:            make(chan []*flow.Flow, exporterQueueSize),
+		quit:             make(chan bool),
+	}
+
+	for _, addr := range collectors {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch u.Scheme {
+		case "udp", "tcp":
+		default:
+			return nil, fmt.Errorf("Unsupported IPFIX collector protocol: %s", addr)
+		}
+
+		e.collectors = append(e.collectors, &collector{network: u.Scheme, addr: u.Host})
+	}
+
+	e.buildTemplates()
+
+	return e, nil
+}
+
+// NewExporterFromConfig returns a new IPFIX exporter based on configuration,
+// nil if no collector is defined
+func NewExporterFromConfig() (*Exporter, error) {
+	collectors := config.GetStringSlice("analyzer.flow.ipfix.collectors")
+	if len(collectors) == 0 {
+		return nil, nil
+	}
+
+	domainID := uint32(config.GetInt("analyzer.flow.ipfix.domain_id"))
+	enterpriseNumber := uint32(config.GetInt("analyzer.flow.ipfix.enterprise_number"))
+	templateRefresh := time.Duration(config.GetInt("analyzer.flow.ipfix.template_refresh")) * time.Second
+
+	return NewExporter(collectors, domainID, enterpriseNumber, templateRefresh)
+}
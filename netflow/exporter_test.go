@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package netflow
+
+import (
+	"testing"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func TestExporterRecords(t *testing.T) {
+	e, err := NewExporter([]string{"udp://127.0.0.1:4739"}, 1, DefaultEnterpriseNumber, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &flow.Flow{
+		UUID:             "uuid",
+		TrackingID:       "tracking",
+		NodeTID:          "tid",
+		LayersPath:       "Ethernet/IPv4/TCP",
+		Link:             &flow.FlowLayer{Protocol: flow.FlowProtocol_ETHERNET, A: "00:11:22:33:44:55", B: "66:77:88:99:aa:bb"},
+		Network:          &flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: "192.168.0.1", B: "192.168.0.2"},
+		Transport:        &flow.TransportLayer{Protocol: flow.FlowProtocol_TCP, A: 34567, B: 80},
+		Metric:           &flow.FlowMetric{ABBytes: 1000, ABPackets: 10, BABytes: 2000, BAPackets: 20},
+		LastUpdateMetric: &flow.FlowMetric{ABBytes: 100, ABPackets: 1},
+		Start:            1500000000000,
+		Last:             1500000001000,
+	}
+
+	id := templateIDForFlow(f)
+	if id != templateIDIPv4 {
+		t.Fatalf("Expected IPv4 template, got %d", id)
+	}
+
+	decoder := NewDecoder()
+	if _, err := decoder.Decode("exporter", e.message(0, e.templateSet())); err != nil {
+		t.Fatal(err)
+	}
+
+	packet, err := decoder.Decode("exporter", e.message(0, dataSet(id, [][]byte{e.encodeRecord(id, f)})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packet.Records) != 1 {
+		t.Fatalf("Expected one record, got %d", len(packet.Records))
+	}
+
+	r := packet.Records[0]
+	for ie, expected := range map[uint16]uint64{
+		IEOctetDeltaCount:          100,
+		IEPacketDeltaCount:         1,
+		IEOctetTotalCount:          1000,
+		IEPacketTotalCount:         10,
+		IEProtocolIdentifier:       6,
+		IESourceTransportPort:      34567,
+		IEDestinationTransportPort: 80,
+		IEFlowStartMilliseconds:    1500000000000,
+		IEFlowEndMilliseconds:      1500000001000,
+	} {
+		if v, _ := r.Uint(ie); v != expected {
+			t.Errorf("Wrong value for information element %d, expected %d, got %d", ie, expected, v)
+		}
+	}
+
+	flows := packet.Flows("exporter", "", nil)
+	if flows[0].Network.A != "192.168.0.1" || flows[0].Network.B != "192.168.0.2" {
+		t.Errorf("Wrong network layer: %+v", flows[0].Network)
+	}
+
+	if flows[0].Link.A != "00:11:22:33:44:55" || flows[0].Link.B != "66:77:88:99:aa:bb" {
+		t.Errorf("Wrong link layer: %+v", flows[0].Link)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package netflow
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	cache "github.com/pmylund/go-cache"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/flow"
+)
+
+// InterfaceResolver returns the TID of the graph node matching the given
+// exporter interface index
+type InterfaceResolver func(ifIndex int64) string
+
+// timestamps returns the start and last timestamps in milliseconds of a record
+func (p *Packet) timestamps(r *Record) (start int64, last int64) {
+	export := common.UnixMillis(p.ExportTime)
+	start, last = export, export
+
+	if v, ok := r.Uint(IEFlowStartMilliseconds); ok {
+		start = int64(v)
+	} else if v, ok := r.Uint(IEFlowStartSeconds); ok {
+		start = int64(v) * 1000
+	} else if v, ok := r.Uint(IEFlowStartSysUpTime); ok && p.SysUptime != 0 {
+		start = export - int64(p.SysUptime) + int64(v)
+	}
+
+	if v, ok := r.Uint(IEFlowEndMilliseconds); ok {
+		last = int64(v)
+	} else if v, ok := r.Uint(IEFlowEndSeconds); ok {
+		last = int64(v) * 1000
+	} else if v, ok := r.Uint(IEFlowEndSysUpTime); ok && p.SysUptime != 0 {
+		last = export - int64(p.SysUptime) + int64(v)
+	}
+
+	if last < start {
+		last = start
+	}
+
+	return
+}
+
+func counter(r *Record, ids ...uint16) int64 {
+	for _, id := range ids {
+		if v, ok := r.Uint(id); ok {
+			return int64(v)
+		}
+	}
+	return 0
+}
+
+// Flow returns a flow built from a data record. The ingress interface index of
+// the record is used to find the TID of the node the flow is attached to,
+// defaulting to nodeTID.
+func (p *Packet) Flow(r *Record, exporter string, nodeTID string, resolver InterfaceResolver) *flow.Flow {
+	f := flow.NewFlow()
+
+	ifIndex := counter(r, IEIngressInterface)
+	if resolver != nil {
+		if tid := resolver(ifIndex); tid != "" {
+			nodeTID = tid
+		}
+	}
+
+	start, last := p.timestamps(r)
+	f.Init(start, nodeTID, flow.FlowUUIDs{})
+	f.Last = last
+
+	var path []string
+
+	src, srcOk := r.Bytes(IESourceMacAddress)
+	dst, dstOk := r.Bytes(IEDestinationMacAddress)
+	if srcOk || dstOk {
+		f.Link = &flow.FlowLayer{
+			Protocol: flow.FlowProtocol_ETHERNET,
+			A:        net.HardwareAddr(src).String(),
+			B:        net.HardwareAddr(dst).String(),
+			ID:       counter(r, IEVlanID),
+		}
+		path = append(path, "Ethernet")
+	}
+
+	if src, ok := r.Bytes(IESourceIPv4Address); ok {
+		dst, _ := r.Bytes(IEDestinationIPv4Address)
+		f.Network = &flow.FlowLayer{
+			Protocol: flow.FlowProtocol_IPV4,
+			A:        net.IP(src).String(),
+			B:        net.IP(dst).String(),
+		}
+		path = append(path, "IPv4")
+	} else if src, ok := r.Bytes(IESourceIPv6Address); ok {
+		dst, _ := r.Bytes(IEDestinationIPv6Address)
+		f.Network = &flow.FlowLayer{
+			Protocol: flow.FlowProtocol_IPV6,
+			A:        net.IP(src).String(),
+			B:        net.IP(dst).String(),
+		}
+		path = append(path, "IPv6")
+	}
+
+	if f.Network != nil {
+		portA, portB := counter(r, IESourceTransportPort), counter(r, IEDestinationTransportPort)
+
+		switch counter(r, IEProtocolIdentifier) {
+		case 6:
+			f.Transport = &flow.TransportLayer{Protocol: flow.FlowProtocol_TCP, A: portA, B: portB}
+			path = append(path, "TCP")
+		case 17:
+			f.Transport = &flow.TransportLayer{Protocol: flow.FlowProtocol_UDP, A: portA, B: portB}
+			path = append(path, "UDP")
+		case 132:
+			f.Transport = &flow.TransportLayer{Protocol: flow.FlowProtocol_SCTP, A: portA, B: portB}
+			path = append(path, "SCTP")
+		case 1:
+			// type and code are encoded in the destination port when the
+			// dedicated element is missing
+			typeCode := counter(r, IEICMPTypeCodeIPv4, IEDestinationTransportPort)
+			f.ICMP = &flow.ICMPLayer{
+				Type: flow.ICMPV4TypeToFlowICMPType(uint8(typeCode >> 8)),
+				Code: uint32(typeCode & 0xff),
+			}
+			path = append(path, "ICMPv4")
+		case 58:
+			typeCode := counter(r, IEICMPTypeCodeIPv6, IEDestinationTransportPort)
+			f.ICMP = &flow.ICMPLayer{
+				Type: flow.ICMPV6TypeToFlowICMPType(uint8(typeCode >> 8)),
+				Code: uint32(typeCode & 0xff),
+			}
+			path = append(path, "ICMPv6")
+		}
+	}
+
+	for i, layer := range path {
+		if i > 0 {
+			f.LayersPath += "/"
+		}
+		f.LayersPath += layer
+		f.Application = layer
+	}
+
+	sampling := int64(r.SamplingInterval)
+	if sampling > 1 {
+		f.SamplingRate = sampling
+		f.EstimatedMetric = true
+	} else {
+		sampling = 1
+	}
+
+	f.Metric = &flow.FlowMetric{
+		ABBytes:   counter(r, IEOctetDeltaCount, IEOctetTotalCount) * sampling,
+		ABPackets: counter(r, IEPacketDeltaCount, IEPacketTotalCount) * sampling,
+		Start:     f.Start,
+		Last:      f.Last,
+	}
+
+	// the key identifies the record for the exporter, exporting again the same
+	// flow with the same start time will update the previous one.
+	key := exporter + "/" + strconv.FormatUint(uint64(p.DomainID), 10) + "/" + strconv.FormatInt(ifIndex, 10)
+
+	f.UpdateUUID(key, flow.FlowOpts{})
+
+	return f
+}
+
+// isDelta returns whether the counters of a record only account for the
+// traffic since the previous export of the flow
+func (r *Record) isDelta() bool {
+	if _, ok := r.Fields[IEOctetDeltaCount]; ok {
+		return true
+	}
+	_, ok := r.Fields[IEPacketDeltaCount]
+	return ok
+}
+
+// flowCache keeps the metrics of the flows exported with delta counters so
+// that the successive exports of a flow add up instead of replacing each
+// other in the flow table
+type flowCache struct {
+	*cache.Cache
+}
+
+// accumulate adds the metric of the previous exports of the flow
+func (c *flowCache) accumulate(f *flow.Flow) {
+	if v, found := c.Get(f.UUID); found {
+		prev := v.(*flow.FlowMetric)
+		f.Metric.ABPackets += prev.ABPackets
+		f.Metric.ABBytes += prev.ABBytes
+		f.Metric.BAPackets += prev.BAPackets
+		f.Metric.BABytes += prev.BABytes
+		if prev.Start < f.Start {
+			f.Start, f.Metric.Start = prev.Start, prev.Start
+		}
+	}
+
+	metric := *f.Metric
+	c.Set(f.UUID, &metric, cache.DefaultExpiration)
+}
+
+func newFlowCache(expire time.Duration) *flowCache {
+	return &flowCache{Cache: cache.New(expire, expire)}
+}
+
+// Flows returns all the flows of a packet
+func (p *Packet) Flows(exporter string, nodeTID string, resolver InterfaceResolver) []*flow.Flow {
+	flows := make([]*flow.Flow, len(p.Records))
+	for i, r := range p.Records {
+		flows[i] = p.Flow(r, exporter, nodeTID, resolver)
+	}
+	return flows
+}
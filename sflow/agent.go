@@ -27,6 +27,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -35,6 +36,8 @@ import (
 	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/flow"
 	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/topology"
+	"github.com/skydive-project/skydive/topology/graph"
 )
 
 const (
@@ -56,6 +59,9 @@ type SFlowAgent struct {
 	Conn       *net.UDPConn
 	BPFFilter  string
 	HeaderSize uint32
+	Graph      *graph.Graph
+	NodeID     graph.Identifier
+	counters   map[int64]*topology.InterfaceMetric
 }
 
 // SFlowAgentAllocator describes an SFlow agent allocator to manage multiple SFlow agent probe
@@ -103,6 +109,11 @@ func (sfa *SFlowAgent) feedFlowTable() {
 				// records each generating Packets.
 				sfa.FlowTable.FeedWithSFlowSample(&sample, bpf)
 			}
+
+			// counters are published on the capture node, if any
+			if sfa.Graph != nil && len(sflowPacket.CounterSamples) > 0 {
+				sfa.updateInterfaceMetrics(sflowPacket.CounterSamples, time.Now())
+			}
 		}
 	}
 }
@@ -145,20 +156,29 @@ func (sfa *SFlowAgent) Stop() {
 	}
 }
 
-// NewSFlowAgent creates a new sFlow agent which will populate the given flowtable
-func NewSFlowAgent(u string, a *common.ServiceAddress, ft *flow.Table, bpfFilter string, headerSize uint32) *SFlowAgent {
+// NewSFlowAgent creates a new sFlow agent which will populate the given flowtable.
+// If a node is given, counter samples are reported as interface metrics on it.
+func NewSFlowAgent(u string, a *common.ServiceAddress, ft *flow.Table, bpfFilter string, headerSize uint32, g *graph.Graph, n *graph.Node) *SFlowAgent {
 	if headerSize == 0 {
 		headerSize = flow.DefaultCaptureLength
 	}
 
-	return &SFlowAgent{
+	sfa := &SFlowAgent{
 		UUID:       u,
 		Addr:       a.Addr,
 		Port:       a.Port,
 		FlowTable:  ft,
 		BPFFilter:  bpfFilter,
 		HeaderSize: headerSize,
+		counters:   make(map[int64]*topology.InterfaceMetric),
 	}
+
+	if g != nil && n != nil {
+		sfa.Graph = g
+		sfa.NodeID = n.ID
+	}
+
+	return sfa
 }
 
 func (a *SFlowAgentAllocator) release(uuid string) {
@@ -192,7 +212,7 @@ func (a *SFlowAgentAllocator) ReleaseAll() {
 }
 
 // Alloc allocates a new sFlow agent
-func (a *SFlowAgentAllocator) Alloc(uuid string, ft *flow.Table, bpfFilter string, headerSize uint32, addr *common.ServiceAddress) (agent *SFlowAgent, _ error) {
+func (a *SFlowAgentAllocator) Alloc(uuid string, ft *flow.Table, bpfFilter string, headerSize uint32, addr *common.ServiceAddress, g *graph.Graph, n *graph.Node) (agent *SFlowAgent, _ error) {
 	a.Lock()
 	defer a.Unlock()
 
@@ -210,7 +230,7 @@ func (a *SFlowAgentAllocator) Alloc(uuid string, ft *flow.Table, bpfFilter strin
 			return nil, errors.New("failed to allocate sflow port: " + err.Error())
 		}
 	}
-	s := NewSFlowAgent(uuid, addr, ft, bpfFilter, headerSize)
+	s := NewSFlowAgent(uuid, addr, ft, bpfFilter, headerSize, g, n)
 
 	a.agents = append(a.agents, s)
 
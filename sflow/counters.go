@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package sflow
+
+import (
+	"time"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/topology"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// UpdateInterfaceMetricFromCounterSample fills the interface metric with the
+// counters of a sample. It returns false if the sample doesn't contain any
+// interface counters record.
+func UpdateInterfaceMetricFromCounterSample(metric *topology.InterfaceMetric, sample *layers.SFlowCounterSample) (updated bool) {
+	for _, rec := range sample.Records {
+		switch record := rec.(type) {
+		case layers.SFlowGenericInterfaceCounters:
+			updated = true
+
+			metric.RxBytes = int64(record.IfInOctets)
+			metric.RxPackets = int64(record.IfInUcastPkts) + int64(record.IfInMulticastPkts) + int64(record.IfInBroadcastPkts)
+			metric.Multicast = int64(record.IfInMulticastPkts)
+			metric.RxDropped = int64(record.IfInDiscards)
+			metric.RxErrors = int64(record.IfInErrors)
+			metric.TxBytes = int64(record.IfOutOctets)
+			metric.TxPackets = int64(record.IfOutUcastPkts) + int64(record.IfOutMulticastPkts) + int64(record.IfOutBroadcastPkts)
+			metric.TxDropped = int64(record.IfOutDiscards)
+			metric.TxErrors = int64(record.IfOutErrors)
+		case layers.SFlowEthernetCounters:
+			updated = true
+
+			metric.RxCrcErrors = int64(record.FCSErrors)
+			metric.RxFrameErrors = int64(record.AlignmentErrors)
+			metric.RxLengthErrors = int64(record.FrameTooLongs)
+			metric.Collisions = int64(record.SingleCollisionFrames) + int64(record.MultipleCollisionFrames) +
+				int64(record.LateCollisions) + int64(record.ExcessiveCollisions)
+			metric.TxCarrierErrors = int64(record.CarrierSenseErrors)
+			metric.TxAbortedErrors = int64(record.ExcessiveCollisions)
+		}
+	}
+
+	return
+}
+
+// nodeForInterface returns the node an interface index is reported on, the
+// capture node or one of its children with this index, nil if none.
+// Graph lock has to be held.
+func (sfa *SFlowAgent) nodeForInterface(n *graph.Node, ifIndex int64) *graph.Node {
+	if i, err := n.GetFieldInt64("IfIndex"); err == nil && i == ifIndex {
+		return n
+	}
+
+	return sfa.Graph.LookupFirstChild(n, graph.Metadata{"IfIndex": ifIndex})
+}
+
+// updateInterfaceMetrics publishes the counter samples as interface metrics.
+// Counters of interfaces that can't be found in the graph are kept until
+// the interfaces show up.
+func (sfa *SFlowAgent) updateInterfaceMetrics(samples []layers.SFlowCounterSample, now time.Time) {
+	updated := false
+	for i := range samples {
+		// generic and ethernet counters of an interface can be sent in
+		// different samples
+		ifIndex := int64(samples[i].SourceIDIndex)
+
+		metric, ok := sfa.counters[ifIndex]
+		if !ok {
+			metric = &topology.InterfaceMetric{}
+		}
+
+		if UpdateInterfaceMetricFromCounterSample(metric, &samples[i]) {
+			sfa.counters[ifIndex] = metric
+			updated = true
+		}
+	}
+
+	if !updated {
+		return
+	}
+
+	sfa.Graph.Lock()
+	defer sfa.Graph.Unlock()
+
+	n := sfa.Graph.GetNode(sfa.NodeID)
+	if n == nil {
+		return
+	}
+
+	unow := common.UnixMillis(now)
+	for ifIndex, metric := range sfa.counters {
+		node := sfa.nodeForInterface(n, ifIndex)
+		if node == nil {
+			continue
+		}
+
+		// do not alter the counters of the agent
+		currMetric := *metric
+		currMetric.Last = unow
+
+		var lastUpdateMetric *topology.InterfaceMetric
+
+		field, _ := node.GetField("Metric")
+		prevMetric, ok := field.(*topology.InterfaceMetric)
+		if ok {
+			lastUpdateMetric = currMetric.Sub(prevMetric).(*topology.InterfaceMetric)
+		}
+
+		// nothing changed since last update
+		if lastUpdateMetric != nil && lastUpdateMetric.IsZero() {
+			continue
+		}
+
+		tr := sfa.Graph.StartMetadataTransaction(node)
+		tr.AddMetadata("Metric", &currMetric)
+		if lastUpdateMetric != nil {
+			lastUpdateMetric.Start = prevMetric.Last
+			lastUpdateMetric.Last = unow
+			tr.AddMetadata("LastUpdateMetric", lastUpdateMetric)
+		}
+		tr.Commit()
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package sflow
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/topology"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+func genericCounters(ifIndex uint32, inOctets uint64, outOctets uint64) layers.SFlowCounterSample {
+	return layers.SFlowCounterSample{
+		SourceIDIndex: layers.SFlowSourceValue(ifIndex),
+		Records: []layers.SFlowRecord{
+			layers.SFlowGenericInterfaceCounters{
+				IfIndex:           ifIndex,
+				IfInOctets:        inOctets,
+				IfInUcastPkts:     10,
+				IfInMulticastPkts: 2,
+				IfInBroadcastPkts: 1,
+				IfInDiscards:      3,
+				IfInErrors:        4,
+				IfOutOctets:       outOctets,
+				IfOutUcastPkts:    20,
+				IfOutDiscards:     5,
+				IfOutErrors:       6,
+			},
+		},
+	}
+}
+
+func TestUpdateInterfaceMetricFromCounterSample(t *testing.T) {
+	sample := genericCounters(1, 1000, 2000)
+	sample.Records = append(sample.Records, layers.SFlowEthernetCounters{
+		AlignmentErrors:       1,
+		FCSErrors:             2,
+		SingleCollisionFrames: 3,
+		LateCollisions:        4,
+		ExcessiveCollisions:   5,
+		CarrierSenseErrors:    6,
+		FrameTooLongs:         7,
+	})
+
+	metric := &topology.InterfaceMetric{}
+	if !UpdateInterfaceMetricFromCounterSample(metric, &sample) {
+		t.Fatal("Metric should be updated by interface counters")
+	}
+
+	expected := &topology.InterfaceMetric{
+		RxBytes:         1000,
+		RxPackets:       13,
+		Multicast:       2,
+		RxDropped:       3,
+		RxErrors:        4,
+		TxBytes:         2000,
+		TxPackets:       20,
+		TxDropped:       5,
+		TxErrors:        6,
+		RxCrcErrors:     2,
+		RxFrameErrors:   1,
+		RxLengthErrors:  7,
+		Collisions:      12,
+		TxCarrierErrors: 6,
+		TxAbortedErrors: 5,
+	}
+
+	if !reflect.DeepEqual(expected, metric) {
+		t.Errorf("Expected metric %+v, got %+v", expected, metric)
+	}
+
+	sample = layers.SFlowCounterSample{Records: []layers.SFlowRecord{layers.SFlowProcessorCounters{}}}
+	if UpdateInterfaceMetricFromCounterSample(metric, &sample) {
+		t.Error("Metric should not be updated without interface counters")
+	}
+}
+
+func newTestAgent(t *testing.T) (*SFlowAgent, *graph.Node, *graph.Node) {
+	b, err := graph.NewMemoryBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := graph.NewGraphFromConfig(b, common.UnknownService)
+
+	bridge := g.NewNode(graph.GenID(), graph.Metadata{"Type": "ovsbridge"})
+	intf := g.NewNode(graph.GenID(), graph.Metadata{"Type": "ovsport", "IfIndex": int64(2)})
+	topology.AddOwnershipLink(g, bridge, intf, nil)
+
+	sfa := &SFlowAgent{
+		Graph:    g,
+		NodeID:   bridge.ID,
+		counters: make(map[int64]*topology.InterfaceMetric),
+	}
+
+	return sfa, bridge, intf
+}
+
+func nodeMetric(sfa *SFlowAgent, n *graph.Node) *topology.InterfaceMetric {
+	sfa.Graph.RLock()
+	defer sfa.Graph.RUnlock()
+
+	field, _ := n.GetField("Metric")
+	metric, _ := field.(*topology.InterfaceMetric)
+	return metric
+}
+
+func TestUpdateInterfaceMetrics(t *testing.T) {
+	sfa, bridge, intf := newTestAgent(t)
+
+	now := time.Now()
+	sfa.updateInterfaceMetrics([]layers.SFlowCounterSample{genericCounters(2, 1000, 2000), genericCounters(3, 5000, 5000)}, now)
+
+	metric := nodeMetric(sfa, intf)
+	if metric == nil || metric.RxBytes != 1000 || metric.TxBytes != 2000 || metric.Last != common.UnixMillis(now) {
+		t.Fatalf("Interface metric should be set from its counters, got %+v", metric)
+	}
+
+	// the counters of an unknown interface are not reported on the bridge
+	if metric := nodeMetric(sfa, bridge); metric != nil {
+		t.Errorf("Bridge metric should not be set, got %+v", metric)
+	}
+
+	later := now.Add(10 * time.Second)
+	sfa.updateInterfaceMetrics([]layers.SFlowCounterSample{genericCounters(2, 1500, 2000)}, later)
+
+	sfa.Graph.RLock()
+	field, _ := intf.GetField("LastUpdateMetric")
+	sfa.Graph.RUnlock()
+
+	lastUpdate, ok := field.(*topology.InterfaceMetric)
+	if !ok || lastUpdate.RxBytes != 500 || lastUpdate.TxBytes != 0 || lastUpdate.Start != common.UnixMillis(now) || lastUpdate.Last != common.UnixMillis(later) {
+		t.Errorf("Last update metric should be the difference of the counters, got %+v", field)
+	}
+}
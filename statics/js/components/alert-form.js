@@ -36,7 +36,7 @@ Vue.component('alert-form', {
         </div>\
         <div class="form-group">\
           <label for="alert-action">Action</label>\
-          <a><i class="fa fa-question help-text" aria-hidden="true" title="URL to trigger. Can be a webhook or a local file(file://)"></i></a>\
+          <a><i class="fa fa-question help-text" aria-hidden="true" title="URL to trigger. Can be a webhook, a local file(file://), an email(smtp://) or syslog(syslog://)"></i></a>\
           <input id="alert-action" type="text" class="form-control input-sm" v-model="action" />\
         </div>\
         <button type="submit" id="start-alert" class="btn btn-primary">Start</button>\
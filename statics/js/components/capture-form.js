@@ -62,7 +62,7 @@ Vue.component('capture-form', {
                 <option v-for="option in options" :value="option.type">{{ option.type }} ({{option.desc}})</option>\
               </select>\
             </div>\
-            <div class="form-group" v-if="captureType == \'sflow\'">\
+            <div class="form-group" v-if="captureType == \'sflow\' || captureType == \'netflow\' || captureType == \'ipfix\'">\
               <label for="port">Port</label>\
               <input id="port" type="number" class="form-control input-sm" v-model.number="port" min="0"/>\
             </div>\
@@ -151,6 +151,8 @@ Vue.component('capture-form', {
           {"type": "pcap", "desc": "Packet Capture library based probe"},
           {"type": "pcapsocket", "desc": "Socket reading PCAP format data"},
           {"type": "sflow", "desc": "Socket reading sFlow frames"},
+          {"type": "netflow", "desc": "Socket reading NetFlow v5/v9 frames"},
+          {"type": "ipfix", "desc": "Socket reading IPFIX frames"},
           {"type": "ebpf", "desc": "Flow capture within kernel - experimental"},
           {"type": "ovsmirror", "desc": "Leverages mirroring to capture - experimental"}
         ];
@@ -278,6 +278,17 @@ func FormatFilter(filter *filters.Filter, mapKey string) elastic.Query {
 		return elastic.NewRegexpQuery(prefix+f.Key, value)
 	}
 
+	if f := filter.IPV6RangeFilter; f != nil {
+		// ignore the error at this point it should have been catched earlier
+		regex, _ := common.IPV6CIDRToRegex(f.Value)
+
+		// remove anchors as ES matches the whole string and doesn't support them
+		value := strings.TrimPrefix(regex, "^")
+		value = strings.TrimSuffix(value, "$")
+
+		return elastic.NewRegexpQuery(prefix+f.Key, value)
+	}
+
 	if f := filter.GtInt64Filter; f != nil {
 		return elastic.NewRangeQuery(prefix + f.Key).Gt(f.Value)
 	}
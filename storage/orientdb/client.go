@@ -257,6 +257,13 @@ func FilterToExpression(f *filters.Filter, formatter func(string) string) string
 		return fmt.Sprintf(`%s MATCHES "%s"`, formatter(f.IPV4RangeFilter.Key), strings.Replace(regex, `\`, `\\`, -1))
 	}
 
+	if f.IPV6RangeFilter != nil {
+		// ignore the error at this point it should have been catched earlier
+		regex, _ := common.IPV6CIDRToRegex(f.IPV6RangeFilter.Value)
+
+		return fmt.Sprintf(`%s MATCHES "%s"`, formatter(f.IPV6RangeFilter.Key), strings.Replace(regex, `\`, `\\`, -1))
+	}
+
 	return ""
 }
 
@@ -1884,6 +1884,75 @@ func TestFlowsWithIpv4Range(t *testing.T) {
 	RunTest(t, test)
 }
 
+func TestFlowsWithIpv6Range(t *testing.T) {
+	if !common.IPv6Supported() {
+		t.Skipf("Platform doesn't support IPv6")
+	}
+
+	test := &Test{
+		setupCmds: []helper.Cmd{
+			{"ovs-vsctl add-br br-ipr6", true},
+
+			{"ip netns add src-ipr6", true},
+			{"ip link add src-ipr6-eth0 type veth peer name ipr6-src-eth0 netns src-ipr6", true},
+			{"ip link set src-ipr6-eth0 up", true},
+			{"ip netns exec src-ipr6 ip link set ipr6-src-eth0 up", true},
+			{"ip netns exec src-ipr6 ip address add fd49:37c8:5230:1::33/64 dev ipr6-src-eth0", true},
+
+			{"ip netns add dst-ipr6", true},
+			{"ip link add dst-ipr6-eth0 type veth peer name ipr6-dst-eth0 netns dst-ipr6", true},
+			{"ip link set dst-ipr6-eth0 up", true},
+			{"ip netns exec dst-ipr6 ip link set ipr6-dst-eth0 up", true},
+			{"ip netns exec dst-ipr6 ip address add fd49:37c8:5230:1::34/64 dev ipr6-dst-eth0", true},
+
+			{"ovs-vsctl add-port br-ipr6 src-ipr6-eth0", true},
+			{"ovs-vsctl add-port br-ipr6 dst-ipr6-eth0", true},
+		},
+
+		tearDownCmds: []helper.Cmd{
+			{"ovs-vsctl del-br br-ipr6", true},
+			{"ip link del dst-ipr6-eth0", true},
+			{"ip link del src-ipr6-eth0", true},
+			{"ip netns del src-ipr6", true},
+			{"ip netns del dst-ipr6", true},
+		},
+
+		captures: []TestCapture{
+			{gremlin: g.G.V().Has("Name", "ipr6-src-eth0")},
+		},
+
+		injections: []TestInjection{{
+			from:  g.G.V().Has("Name", "ipr6-src-eth0"),
+			to:    g.G.V().Has("Name", "ipr6-dst-eth0"),
+			count: 10,
+			ipv6:  true,
+		}},
+
+		mode: Replay,
+
+		checks: []CheckFunction{func(c *CheckContext) error {
+			gremlin := c.gremlin.Flows().Has("Network", g.Ipv6Range("fd49:37c8:5230::/48"), "LayersPath", "Ethernet/IPv6/ICMPv6")
+			flows, err := c.gh.GetFlows(gremlin)
+			if err != nil {
+				return err
+			}
+			if len(flows) != 1 {
+				return fmt.Errorf("Expected one flow, got %+v", flows)
+			}
+
+			gremlin = c.gremlin.Flows().Has("Network", g.Ipv6Range("fd49:37c8:5231::/48"))
+			if flows, err = c.gh.GetFlows(gremlin); err != nil {
+				return err
+			}
+			if len(flows) != 0 {
+				return fmt.Errorf("Expected no flow, got %+v", flows)
+			}
+			return nil
+		}},
+	}
+	RunTest(t, test)
+}
+
 func TestOvsMirror(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
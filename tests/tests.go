@@ -304,7 +304,7 @@ func RunTest(t *testing.T, test *Test) {
 	t.Log("Checking captures are correctly set up")
 	err = common.Retry(func() error {
 		for _, capture := range captures {
-			nodes, err := context.gh.GetNodes(capture.GremlinQuery)
+			nodes, err := context.gh.WithBindings(capture.Bindings).GetNodes(capture.GremlinQuery)
 			if err != nil {
 				return err
 			}
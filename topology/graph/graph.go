@@ -23,10 +23,14 @@
 package graph
 
 import (
+	"container/heap"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"time"
 
 	"github.com/nu7hatch/gouuid"
@@ -1030,6 +1034,336 @@ func (g *Graph) LookupShortestPath(n *Node, m GraphElementMatcher, em GraphEleme
 	return retNodes
 }
 
+// WeightAggregation defines how the weights of the nodes and edges of a path
+// are combined to compare paths
+type WeightAggregation int
+
+const (
+	// SumWeight selects the path having the lowest sum of weights, like latencies or costs
+	SumWeight WeightAggregation = iota
+	// MinWeight selects the path whose lowest weight is the highest, like speeds or MTUs
+	MinWeight
+)
+
+func (a WeightAggregation) String() string {
+	if a == MinWeight {
+		return "min"
+	}
+	return "sum"
+}
+
+// WeightAggregationByName returns the weight aggregation for the given name
+func WeightAggregationByName(name string) (WeightAggregation, error) {
+	switch name {
+	case "sum":
+		return SumWeight, nil
+	case "min":
+		return MinWeight, nil
+	}
+	return SumWeight, errors.New("WeightAggregation unknown")
+}
+
+// weight returns the numeric value of the key, ok is false if the element
+// doesn't have it
+func (e *graphElement) weight(key string) (_ float64, ok bool) {
+	v, err := e.GetField(key)
+	if err != nil {
+		return 0, false
+	}
+
+	f, err := common.ToFloat64(v)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// weightedCandidate is a node reached by LookupWeightedShortestPath along
+// with the path leading to it
+type weightedCandidate struct {
+	node     *Node
+	previous *weightedCandidate
+	weight   float64
+	hops     int
+}
+
+// weightedQueue is a priority queue of candidates, the best weight first,
+// then the lowest number of hops
+type weightedQueue struct {
+	candidates []*weightedCandidate
+	better     func(a, b float64) bool
+}
+
+func (q *weightedQueue) Len() int { return len(q.candidates) }
+
+func (q *weightedQueue) Less(i, j int) bool {
+	a, b := q.candidates[i], q.candidates[j]
+	return q.better(a.weight, b.weight) || (a.weight == b.weight && a.hops < b.hops)
+}
+
+func (q *weightedQueue) Swap(i, j int) {
+	q.candidates[i], q.candidates[j] = q.candidates[j], q.candidates[i]
+}
+
+func (q *weightedQueue) Push(x interface{}) {
+	q.candidates = append(q.candidates, x.(*weightedCandidate))
+}
+
+func (q *weightedQueue) Pop() interface{} {
+	last := len(q.candidates) - 1
+	c := q.candidates[last]
+	q.candidates[last] = nil
+	q.candidates = q.candidates[:last]
+	return c
+}
+
+// LookupWeightedShortestPath returns the best path from the node n to the
+// nodes matching m, based on Dijkstra algorithm, according to the value of
+// the key in the metadata of the nodes and the edges of the path. Elements
+// without the key are ignored, negative weights are considered as 0 when
+// summing. Paths having the same weight are compared by number of hops.
+// The lookup is aborted with the error of the context once done.
+func (g *Graph) LookupWeightedShortestPath(ctx context.Context, n *Node, m GraphElementMatcher, em GraphElementMatcher, key string, aggregation WeightAggregation) ([]*Node, error) {
+	neutral := float64(0)
+	aggregate := func(a, b float64) float64 { return a + math.Max(b, 0) }
+	better := func(a, b float64) bool { return a < b }
+	if aggregation == MinWeight {
+		neutral = math.Inf(1)
+		aggregate = math.Min
+		better = func(a, b float64) bool { return a > b }
+	}
+
+	weight := func(e *graphElement) float64 {
+		if w, ok := e.weight(key); ok {
+			return w
+		}
+		return neutral
+	}
+
+	first := &weightedCandidate{node: n, weight: aggregate(neutral, weight(&n.graphElement))}
+	queue := &weightedQueue{candidates: []*weightedCandidate{first}, better: better}
+	best := map[Identifier]*weightedCandidate{n.ID: first}
+	done := make(map[Identifier]bool)
+
+	for queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// a node may have been pushed several times, only its best
+		// candidate is processed
+		u := heap.Pop(queue).(*weightedCandidate)
+		if done[u.node.ID] || best[u.node.ID] != u {
+			continue
+		}
+		done[u.node.ID] = true
+
+		if u.node.MatchMetadata(m) {
+			var path []*Node
+			for c := u; c != nil; c = c.previous {
+				path = append([]*Node{c.node}, path...)
+			}
+			return path, nil
+		}
+
+		for _, e := range g.backend.GetNodeEdges(u.node, g.context, em) {
+			parents, children := g.backend.GetEdgeNodes(e, g.context, nil, nil)
+			for _, v := range append(parents, children...) {
+				if v.ID == u.node.ID || done[v.ID] {
+					continue
+				}
+
+				w := aggregate(aggregate(u.weight, weight(&e.graphElement)), weight(&v.graphElement))
+				if c, ok := best[v.ID]; !ok || better(w, c.weight) || (w == c.weight && u.hops+1 < c.hops) {
+					c = &weightedCandidate{node: v, previous: u, weight: w, hops: u.hops + 1}
+					best[v.ID] = c
+					heap.Push(queue, c)
+				}
+			}
+		}
+	}
+
+	return []*Node{}, nil
+}
+
+// getUniqueNeighborNodes returns the neighbors of a node, a neighbor linked
+// by several edges being returned only once
+func (g *Graph) getUniqueNeighborNodes(n *Node, em GraphElementMatcher) (nodes []*Node) {
+	seen := make(map[Identifier]bool)
+	for _, v := range g.getNeighborNodes(n, em) {
+		if v.ID != n.ID && !seen[v.ID] {
+			seen[v.ID] = true
+			nodes = append(nodes, v)
+		}
+	}
+	return nodes
+}
+
+func pathKey(path []*Node) string {
+	var key string
+	for _, n := range path {
+		key += string(n.ID) + "/"
+	}
+	return key
+}
+
+func sortPaths(paths [][]*Node) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) < len(paths[j])
+	})
+}
+
+// LookupAllPaths returns all the loop free paths from the node n to the
+// nodes matching m, going through at most maxDepth edges, or without limit
+// if maxDepth is 0. Paths end at the first node matching m and are sorted
+// by length. The lookup is aborted with the error of the context once done.
+func (g *Graph) LookupAllPaths(ctx context.Context, n *Node, m GraphElementMatcher, em GraphElementMatcher, maxDepth int) ([][]*Node, error) {
+	paths := [][]*Node{}
+	onPath := make(map[Identifier]bool)
+
+	var err error
+	var walk func(path []*Node)
+	walk = func(path []*Node) {
+		if err != nil {
+			return
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		node := path[len(path)-1]
+		if node.MatchMetadata(m) {
+			p := make([]*Node, len(path))
+			copy(p, path)
+			paths = append(paths, p)
+			return
+		}
+
+		if maxDepth > 0 && len(path) > maxDepth {
+			return
+		}
+
+		onPath[node.ID] = true
+		for _, v := range g.getUniqueNeighborNodes(node, em) {
+			if !onPath[v.ID] {
+				walk(append(path, v))
+			}
+		}
+		delete(onPath, node.ID)
+	}
+	walk([]*Node{n})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortPaths(paths)
+
+	return paths, nil
+}
+
+// lookupShortestPathExcluding returns the shortest path, in number of edges,
+// from the node n to the first node matching m, without going through the
+// removed nodes and links
+func (g *Graph) lookupShortestPathExcluding(n *Node, m GraphElementMatcher, em GraphElementMatcher, removedNodes map[Identifier]bool, removedLinks map[[2]Identifier]bool) []*Node {
+	previous := map[Identifier]*Node{n.ID: nil}
+	queue := []*Node{n}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if node.MatchMetadata(m) {
+			var path []*Node
+			for ; node != nil; node = previous[node.ID] {
+				path = append([]*Node{node}, path...)
+			}
+			return path
+		}
+
+		for _, v := range g.getUniqueNeighborNodes(node, em) {
+			if _, ok := previous[v.ID]; ok || removedNodes[v.ID] || removedLinks[[2]Identifier{node.ID, v.ID}] {
+				continue
+			}
+			previous[v.ID] = node
+			queue = append(queue, v)
+		}
+	}
+
+	return nil
+}
+
+// LookupKShortestPaths returns at most k loop free paths from the node n to
+// the first node matching m, sorted by length, based on Yen algorithm. The
+// lookup is aborted with the error of the context once done.
+func (g *Graph) LookupKShortestPaths(ctx context.Context, n *Node, m GraphElementMatcher, em GraphElementMatcher, k int) ([][]*Node, error) {
+	paths := [][]*Node{}
+	if k <= 0 {
+		return paths, nil
+	}
+
+	path := g.lookupShortestPathExcluding(n, m, em, nil, nil)
+	if path == nil {
+		return paths, nil
+	}
+	paths = append(paths, path)
+
+	known := map[string]bool{pathKey(path): true}
+	var candidates [][]*Node
+
+	for len(paths) < k {
+		last := paths[len(paths)-1]
+
+		for i := 0; i < len(last)-1; i++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			root := last[:i+1]
+			rootKey := pathKey(root)
+
+			// remove the links already used by the paths sharing the same root
+			removedLinks := make(map[[2]Identifier]bool)
+			for _, p := range paths {
+				if len(p) > i+1 && pathKey(p[:i+1]) == rootKey {
+					removedLinks[[2]Identifier{p[i].ID, p[i+1].ID}] = true
+					removedLinks[[2]Identifier{p[i+1].ID, p[i].ID}] = true
+				}
+			}
+
+			// remove the root nodes so that the path stays loop free
+			removedNodes := make(map[Identifier]bool)
+			for _, r := range root[:i] {
+				removedNodes[r.ID] = true
+			}
+
+			spur := g.lookupShortestPathExcluding(last[i], m, em, removedNodes, removedLinks)
+			if spur == nil {
+				continue
+			}
+
+			candidate := make([]*Node, 0, i+len(spur))
+			candidate = append(candidate, root[:i]...)
+			candidate = append(candidate, spur...)
+
+			if key := pathKey(candidate); !known[key] {
+				known[key] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		sortPaths(candidates)
+		paths = append(paths, candidates[0])
+		candidates = candidates[1:]
+	}
+
+	return paths, nil
+}
+
 // LookupParents returns the associated parents edge of a node
 func (g *Graph) LookupParents(n *Node, f GraphElementMatcher, em GraphElementMatcher) (nodes []*Node) {
 	for _, e := range g.backend.GetNodeEdges(n, g.context, em) {
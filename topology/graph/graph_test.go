@@ -23,7 +23,9 @@
 package graph
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -377,6 +379,162 @@ func TestComplexPath(t *testing.T) {
 	}
 }
 
+func TestWeightedPath(t *testing.T) {
+	g := newGraph(t)
+
+	validatePath := func(nodes []*Node, expected string) bool {
+		var values []string
+
+		for _, n := range nodes {
+			value, _ := n.GetFieldInt64("Value")
+			values = append(values, strconv.FormatInt(value, 10))
+		}
+
+		return expected == strings.Join(values, "/")
+	}
+
+	// n1 -- n2 -- n4
+	//  \          /|
+	//   \-- n3 --/ |
+	//    \---------/
+	n1 := g.NewNode(GenID(), Metadata{"Value": 1, "MTU": 1500})
+	n2 := g.NewNode(GenID(), Metadata{"Value": 2, "MTU": 1500})
+	n3 := g.NewNode(GenID(), Metadata{"Value": 3, "MTU": 1400})
+	n4 := g.NewNode(GenID(), Metadata{"Value": 4, "MTU": 1500})
+
+	g.Link(n1, n2, Metadata{"Type": "Layer2", "Latency": 10, "Speed": 1000})
+	g.Link(n2, n4, Metadata{"Type": "Layer2", "Latency": 10, "Speed": 1000})
+	g.Link(n1, n3, Metadata{"Type": "Layer2", "Latency": 1, "Speed": 100})
+	g.Link(n3, n4, Metadata{"Type": "Layer2", "Latency": 1, "Speed": 100})
+	g.Link(n1, n4, Metadata{"Type": "Layer3", "Latency": 50, "Speed": 10})
+
+	r, _ := g.LookupWeightedShortestPath(context.Background(), n1, Metadata{"Value": 4}, nil, "Latency", SumWeight)
+	if !validatePath(r, "1/3/4") {
+		t.Errorf("Wrong nodes returned: %v", r)
+	}
+
+	r, _ = g.LookupWeightedShortestPath(context.Background(), n1, Metadata{"Value": 4}, nil, "Speed", MinWeight)
+	if !validatePath(r, "1/2/4") {
+		t.Errorf("Wrong nodes returned: %v", r)
+	}
+
+	// same MTU on both paths, the shortest one is returned
+	r, _ = g.LookupWeightedShortestPath(context.Background(), n1, Metadata{"Value": 4}, nil, "MTU", MinWeight)
+	if !validatePath(r, "1/4") {
+		t.Errorf("Wrong nodes returned: %v", r)
+	}
+
+	r, _ = g.LookupWeightedShortestPath(context.Background(), n1, Metadata{"Value": 4}, Metadata{"Type": "Layer2"}, "MTU", MinWeight)
+	if !validatePath(r, "1/2/4") {
+		t.Errorf("Wrong nodes returned: %v", r)
+	}
+
+	// no weight, hop count only
+	r, _ = g.LookupWeightedShortestPath(context.Background(), n4, Metadata{"Value": 1}, nil, "Unknown", SumWeight)
+	if !validatePath(r, "4/1") {
+		t.Errorf("Wrong nodes returned: %v", r)
+	}
+
+	r, _ = g.LookupWeightedShortestPath(context.Background(), n1, Metadata{"Value": 55}, nil, "Latency", SumWeight)
+	if len(r) > 0 {
+		t.Errorf("Shouldn't have true returned: %v", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := g.LookupWeightedShortestPath(ctx, n1, Metadata{"Value": 4}, nil, "Latency", SumWeight); err != context.Canceled {
+		t.Errorf("Lookup should have been canceled, returned: %v", err)
+	}
+}
+
+func TestAllPaths(t *testing.T) {
+	g := newGraph(t)
+
+	pathsToString := func(paths [][]*Node) string {
+		var s []string
+		for _, nodes := range paths {
+			var values []string
+			for _, n := range nodes {
+				value, _ := n.GetFieldInt64("Value")
+				values = append(values, strconv.FormatInt(value, 10))
+			}
+			s = append(s, strings.Join(values, "/"))
+		}
+		// paths of the same length can be returned in any order
+		sort.Strings(s)
+		return strings.Join(s, " ")
+	}
+
+	// n1 -- n2 -- n4 -- n5
+	//  \          /|
+	//   \-- n3 --/ |
+	//    \---------/ (Layer3)
+	n1 := g.NewNode(GenID(), Metadata{"Value": 1})
+	n2 := g.NewNode(GenID(), Metadata{"Value": 2})
+	n3 := g.NewNode(GenID(), Metadata{"Value": 3})
+	n4 := g.NewNode(GenID(), Metadata{"Value": 4})
+	n5 := g.NewNode(GenID(), Metadata{"Value": 5})
+
+	g.Link(n1, n2, Metadata{"Type": "Layer2"})
+	g.Link(n2, n4, Metadata{"Type": "Layer2"})
+	g.Link(n1, n3, Metadata{"Type": "Layer2"})
+	g.Link(n3, n4, Metadata{"Type": "Layer2"})
+	g.Link(n1, n4, Metadata{"Type": "Layer3"})
+	g.Link(n4, n5, Metadata{"Type": "Layer2"})
+
+	r, _ := g.LookupAllPaths(context.Background(), n1, Metadata{"Value": 5}, nil, 0)
+	if s := pathsToString(r); s != "1/2/4/5 1/3/4/5 1/4/5" {
+		t.Errorf("Wrong paths returned: %s", s)
+	}
+
+	r, _ = g.LookupAllPaths(context.Background(), n1, Metadata{"Value": 5}, nil, 2)
+	if s := pathsToString(r); s != "1/4/5" {
+		t.Errorf("Wrong paths returned: %s", s)
+	}
+
+	r, _ = g.LookupAllPaths(context.Background(), n1, Metadata{"Value": 5}, Metadata{"Type": "Layer2"}, 0)
+	if s := pathsToString(r); s != "1/2/4/5 1/3/4/5" {
+		t.Errorf("Wrong paths returned: %s", s)
+	}
+
+	r, _ = g.LookupAllPaths(context.Background(), n1, Metadata{"Value": 55}, nil, 0)
+	if len(r) != 0 {
+		t.Errorf("Shouldn't have returned paths: %v", r)
+	}
+
+	r, _ = g.LookupKShortestPaths(context.Background(), n1, Metadata{"Value": 5}, nil, 1)
+	if s := pathsToString(r); s != "1/4/5" {
+		t.Errorf("Wrong paths returned: %s", s)
+	}
+
+	r, _ = g.LookupKShortestPaths(context.Background(), n1, Metadata{"Value": 5}, nil, 2)
+	if len(r) != 2 || len(r[1]) != 4 {
+		t.Errorf("Wrong paths returned: %s", pathsToString(r))
+	}
+
+	r, _ = g.LookupKShortestPaths(context.Background(), n1, Metadata{"Value": 5}, nil, 10)
+	if s := pathsToString(r); s != "1/2/4/5 1/3/4/5 1/4/5" {
+		t.Errorf("Wrong paths returned: %s", s)
+	}
+
+	r, _ = g.LookupKShortestPaths(context.Background(), n5, Metadata{"Value": 1}, Metadata{"Type": "Layer2"}, 10)
+	if s := pathsToString(r); s != "5/4/2/1 5/4/3/1" {
+		t.Errorf("Wrong paths returned: %s", s)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := g.LookupAllPaths(ctx, n1, Metadata{"Value": 5}, nil, 0); err != context.Canceled {
+		t.Errorf("Lookup should have been canceled, returned: %v", err)
+	}
+
+	if _, err := g.LookupKShortestPaths(ctx, n1, Metadata{"Value": 5}, nil, 10); err != context.Canceled {
+		t.Errorf("Lookup should have been canceled, returned: %v", err)
+	}
+}
+
 func TestMetadata(t *testing.T) {
 	g := newGraph(t)
 
@@ -23,6 +23,7 @@
 package traversal
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -70,12 +71,16 @@ type GraphTraversal struct {
 	currentStepContext GraphStepContext
 	lockGraph          bool
 	as                 map[string]*GraphTraversalAs
+	trackPaths         bool
+	ctx                context.Context
+	maxElements        int
 }
 
 // GraphTraversalV traversal steps on nodes
 type GraphTraversalV struct {
 	GraphTraversal *GraphTraversal
 	nodes          []*graph.Node
+	paths          [][]interface{}
 	error          error
 }
 
@@ -83,6 +88,7 @@ type GraphTraversalV struct {
 type GraphTraversalE struct {
 	GraphTraversal *GraphTraversal
 	edges          []*graph.Edge
+	paths          [][]interface{}
 	error          error
 }
 
@@ -104,6 +110,29 @@ type GraphTraversalValue struct {
 type GraphTraversalAs struct {
 	GraphTraversal *GraphTraversal
 	nodes          []*graph.Node
+	paths          [][]interface{}
+}
+
+// AnonymousTraversal describes a traversal applied to the result of a step
+type AnonymousTraversal interface {
+	Exec(last GraphTraversalStep) (GraphTraversalStep, error)
+}
+
+// AnonymousTraversalFunc allows to use a function as an anonymous traversal
+type AnonymousTraversalFunc func(last GraphTraversalStep) (GraphTraversalStep, error)
+
+// Exec calls the function
+func (f AnonymousTraversalFunc) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return f(last)
+}
+
+// RepeatOptions describes how the Repeat step loops
+type RepeatOptions struct {
+	Times      int64              // maximum number of loops, 0 for no limit
+	Until      AnonymousTraversal // stops the loop for the nodes the traversal returns something for
+	UntilFirst bool               // evaluates Until on the nodes before the first loop
+	Emit       bool               // returns the nodes reached at every loop
+	EmitFirst  bool               // returns the nodes before the first loop as well
 }
 
 func KeyValueToFilter(k string, v interface{}) (*filters.Filter, error) {
@@ -224,6 +253,18 @@ func KeyValueToFilter(k string, v interface{}) (*filters.Filter, error) {
 		}
 
 		return &filters.Filter{IPV4RangeFilter: rf}, nil
+	case *IPV6RangeGraphElementMatcher:
+		cidr, ok := v.value.(string)
+		if !ok {
+			return nil, errors.New("Ipv6Range value has to be a string")
+		}
+
+		rf, err := filters.NewIPV6RangeFilter(k, cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &filters.Filter{IPV6RangeFilter: rf}, nil
 	default:
 		i, err := common.ToInt64(v)
 		if err != nil {
@@ -434,6 +475,16 @@ func IPV4Range(s interface{}) *IPV4RangeGraphElementMatcher {
 	return &IPV4RangeGraphElementMatcher{value: s}
 }
 
+// IPV6RangeGraphElementMatcher matches ipv6 contained in an ipv6 range
+type IPV6RangeGraphElementMatcher struct {
+	value interface{}
+}
+
+// IPV6Range step
+func IPV6Range(s interface{}) *IPV6RangeGraphElementMatcher {
+	return &IPV6RangeGraphElementMatcher{value: s}
+}
+
 // Since describes a list of metadata that match since seconds
 type Since struct {
 	Seconds int64
@@ -448,6 +499,56 @@ func NewGraphTraversal(g *graph.Graph, lockGraph bool) *GraphTraversal {
 	}
 }
 
+// WithContext sets the context aborting the traversal when done and the
+// maximum number of elements a step can return, 0 meaning no limit
+func (t *GraphTraversal) WithContext(ctx context.Context, maxElements int) *GraphTraversal {
+	t.ctx = ctx
+	t.maxElements = maxElements
+	return t
+}
+
+// checkAbort returns an error if the traversal has to be aborted, because its
+// context is done or because a step returns more than count elements
+func (t *GraphTraversal) checkAbort(count int) error {
+	if t.maxElements > 0 && count > t.maxElements {
+		return ErrTooManyElements
+	}
+
+	if t.ctx != nil {
+		switch t.ctx.Err() {
+		case context.Canceled:
+			return ErrQueryCanceled
+		case context.DeadlineExceeded:
+			return ErrQueryTimeout
+		}
+	}
+
+	return nil
+}
+
+// abortError returns the error aborting the traversal for the given error
+// of a graph lookup
+func (t *GraphTraversal) abortError(err error) error {
+	if abortErr := t.checkAbort(0); abortErr != nil {
+		return abortErr
+	}
+	return err
+}
+
+// lookupContext returns the context given to the graph lookups
+func (t *GraphTraversal) lookupContext() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// subTraversal returns a traversal on the given graph sharing the context
+// and the limits of the current one
+func (t *GraphTraversal) subTraversal(g *graph.Graph) *GraphTraversal {
+	return NewGraphTraversal(g, t.lockGraph).WithContext(t.ctx, t.maxElements)
+}
+
 func (t *GraphTraversal) CurrentStepContext() GraphStepContext {
 	return t.currentStepContext
 }
@@ -536,7 +637,14 @@ func (t *GraphTraversal) Context(s ...interface{}) *GraphTraversal {
 		return &GraphTraversal{error: err}
 	}
 
-	return &GraphTraversal{Graph: g}
+	return &GraphTraversal{Graph: g, trackPaths: t.trackPaths, ctx: t.ctx, maxElements: t.maxElements}
+}
+
+// TrackPaths records the nodes and edges each element of the traversal
+// went through, so that they can be retrieved using the Path step
+func (t *GraphTraversal) TrackPaths() *GraphTraversal {
+	t.trackPaths = true
+	return t
 }
 
 // V step : [node ID]
@@ -585,7 +693,19 @@ func (t *GraphTraversal) V(s ...interface{}) *GraphTraversalV {
 		nodes = nodeRange
 	}
 
-	return &GraphTraversalV{GraphTraversal: t, nodes: nodes}
+	if err := t.checkAbort(len(nodes)); err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
+	tv := &GraphTraversalV{GraphTraversal: t, nodes: nodes}
+	if t.trackPaths {
+		tv.paths = make([][]interface{}, len(nodes))
+		for i, n := range nodes {
+			tv.paths[i] = []interface{}{n}
+		}
+	}
+
+	return tv
 }
 
 // NewGraphTraversalV returns a new traversal step
@@ -626,6 +746,34 @@ func (tv *GraphTraversalV) MarshalJSON() ([]byte, error) {
 	return json.Marshal(values)
 }
 
+// extendPath returns a copy of the path followed by the element, nil when
+// paths are not tracked
+func extendPath(path []interface{}, e interface{}) []interface{} {
+	if path == nil {
+		return nil
+	}
+
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, e)
+}
+
+// path returns the path followed to the node at the given index
+func (tv *GraphTraversalV) path(i int) []interface{} {
+	if tv.paths == nil {
+		return nil
+	}
+	return tv.paths[i]
+}
+
+// addNode appends a node and the path followed to reach it
+func (tv *GraphTraversalV) addNode(n *graph.Node, path []interface{}) {
+	tv.nodes = append(tv.nodes, n)
+	if path != nil {
+		tv.paths = append(tv.paths, path)
+	}
+}
+
 // GetNodes returns the step nodes
 func (tv *GraphTraversalV) GetNodes() (nodes []*graph.Node) {
 	return tv.nodes
@@ -736,7 +884,7 @@ func (tv *GraphTraversalV) As(keys ...interface{}) *GraphTraversalV {
 		return &GraphTraversalV{error: errors.New("As parameter have to be a string key")}
 	}
 
-	tv.GraphTraversal.as[key] = &GraphTraversalAs{nodes: tv.nodes}
+	tv.GraphTraversal.as[key] = &GraphTraversalAs{nodes: tv.nodes, paths: tv.paths}
 
 	return tv
 }
@@ -764,6 +912,7 @@ func (tv *GraphTraversalV) Select(keys ...interface{}) *GraphTraversalV {
 		}
 
 		ntv.nodes = append(ntv.nodes, as.nodes...)
+		ntv.paths = append(ntv.paths, as.paths...)
 	}
 
 	return ntv
@@ -815,7 +964,19 @@ func (t *GraphTraversal) E(s ...interface{}) *GraphTraversalE {
 		edges = edgeRange
 	}
 
-	return &GraphTraversalE{GraphTraversal: t, edges: edges}
+	if err := t.checkAbort(len(edges)); err != nil {
+		return &GraphTraversalE{error: err}
+	}
+
+	te := &GraphTraversalE{GraphTraversal: t, edges: edges}
+	if t.trackPaths {
+		te.paths = make([][]interface{}, len(edges))
+		for i, e := range edges {
+			te.paths[i] = []interface{}{e}
+		}
+	}
+
+	return te
 }
 
 // NewGraphTraversalE creates a new graph traversal Edges
@@ -856,6 +1017,22 @@ func (te *GraphTraversalE) MarshalJSON() ([]byte, error) {
 	return json.Marshal(values)
 }
 
+// path returns the path followed to the edge at the given index
+func (te *GraphTraversalE) path(i int) []interface{} {
+	if te.paths == nil {
+		return nil
+	}
+	return te.paths[i]
+}
+
+// addEdge appends an edge and the path followed to reach it
+func (te *GraphTraversalE) addEdge(e *graph.Edge, path []interface{}) {
+	te.edges = append(te.edges, e)
+	if path != nil {
+		te.paths = append(te.paths, path)
+	}
+}
+
 // G returns the GraphTraversal
 func (te *GraphTraversalE) G() *GraphTraversal {
 	return te.GraphTraversal
@@ -882,6 +1059,208 @@ func ParseSortParameter(keys ...interface{}) (order common.SortOrder, sortBy str
 	return order, sortBy, err
 }
 
+// FieldGetter describes an element whose fields can be retrieved by key
+type FieldGetter interface {
+	GetField(key string) (interface{}, error)
+}
+
+// groupKey returns the value of the key as a string so that it can be used
+// as a JSON object key
+func groupKey(e FieldGetter, key string) (string, bool) {
+	v, err := e.GetField(key)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%v", v), true
+}
+
+// GroupCountElements returns the number of elements per value of the key.
+// Elements without the key are ignored.
+func (t *GraphTraversal) GroupCountElements(elements []FieldGetter, s ...interface{}) (map[string]int, error) {
+	if len(s) != 1 {
+		return nil, errors.New("GroupCount requires 1 parameter")
+	}
+
+	key, ok := s[0].(string)
+	if !ok {
+		return nil, errors.New("GroupCount parameter has to be a string key")
+	}
+
+	t.RLock()
+	defer t.RUnlock()
+
+	counts := make(map[string]int)
+	for _, e := range elements {
+		if k, ok := groupKey(e, key); ok {
+			counts[k]++
+		}
+	}
+
+	return counts, nil
+}
+
+// GroupElements groups the elements per value of the key given as first
+// parameter. The value of a group is either its elements, the values of the
+// key given as second parameter or the result of the traversal given as
+// second parameter. newStep returns the step made of the elements of a group.
+func (t *GraphTraversal) GroupElements(elements []FieldGetter, newStep func(group []int) GraphTraversalStep, s ...interface{}) (map[string]interface{}, error) {
+	if len(s) == 0 || len(s) > 2 {
+		return nil, errors.New("Group requires a key and optionally a value parameter")
+	}
+
+	key, ok := s[0].(string)
+	if !ok {
+		return nil, errors.New("Group key has to be a string")
+	}
+
+	var by interface{}
+	if len(s) > 1 {
+		switch s[1].(type) {
+		case string, AnonymousTraversal:
+			by = s[1]
+		default:
+			return nil, errors.New("Group value has to be a string key or a traversal")
+		}
+	}
+
+	groups := make(map[string][]int)
+
+	t.RLock()
+	for i, e := range elements {
+		if k, ok := groupKey(e, key); ok {
+			groups[k] = append(groups[k], i)
+		}
+	}
+	t.RUnlock()
+
+	values := make(map[string]interface{})
+	for k, group := range groups {
+		switch by := by.(type) {
+		case string:
+			fields := []interface{}{}
+			t.RLock()
+			for _, i := range group {
+				if v, err := elements[i].GetField(by); err == nil {
+					fields = append(fields, v)
+				}
+			}
+			t.RUnlock()
+			values[k] = fields
+		case AnonymousTraversal:
+			// the graph is not locked here as each step of the traversal locks it
+			step, err := by.Exec(newStep(group))
+			if err != nil {
+				return nil, err
+			}
+			if err = step.Error(); err != nil {
+				return nil, err
+			}
+
+			if value, ok := step.(*GraphTraversalValue); ok {
+				values[k] = value.value
+			} else {
+				values[k] = step.Values()
+			}
+		default:
+			values[k] = newStep(group).Values()
+		}
+	}
+
+	return values, nil
+}
+
+// ValueMapElements returns a row per element made of the values of the
+// given keys. The keys an element doesn't have are omitted from its row.
+func (t *GraphTraversal) ValueMapElements(elements []FieldGetter, s ...interface{}) ([]interface{}, error) {
+	if len(s) == 0 {
+		return nil, errors.New("ValueMap requires at least one key")
+	}
+
+	keys := make([]string, len(s))
+	for i, k := range s {
+		key, ok := k.(string)
+		if !ok {
+			return nil, errors.New("ValueMap keys have to be strings")
+		}
+		keys[i] = key
+	}
+
+	t.RLock()
+	defer t.RUnlock()
+
+	rows := make([]interface{}, len(elements))
+	for i, e := range elements {
+		row := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			if v, err := e.GetField(key); err == nil {
+				row[key] = v
+			}
+		}
+		rows[i] = row
+	}
+
+	return rows, nil
+}
+
+// ProjectElements returns a row per element whose columns are given by pairs
+// of label and value. The value of a column is either the value of a key of
+// the element or the result of a traversal applied to the element. newStep
+// returns the step made of the elements of the given indexes.
+func (t *GraphTraversal) ProjectElements(elements []FieldGetter, newStep func(group []int) GraphTraversalStep, s ...interface{}) ([]interface{}, error) {
+	if len(s) == 0 || len(s)%2 != 0 {
+		return nil, errors.New("Project requires pairs of label and value parameters")
+	}
+
+	labels := make([]string, len(s)/2)
+	for i := range labels {
+		label, ok := s[2*i].(string)
+		if !ok {
+			return nil, errors.New("Project labels have to be strings")
+		}
+		labels[i] = label
+
+		switch s[2*i+1].(type) {
+		case string, AnonymousTraversal:
+		default:
+			return nil, errors.New("Project value has to be a string key or a traversal")
+		}
+	}
+
+	rows := make([]interface{}, len(elements))
+	for i, e := range elements {
+		row := make(map[string]interface{}, len(labels))
+		for j, label := range labels {
+			switch by := s[2*j+1].(type) {
+			case string:
+				t.RLock()
+				v, err := e.GetField(by)
+				t.RUnlock()
+				if err == nil {
+					row[label] = v
+				}
+			case AnonymousTraversal:
+				// the graph is not locked here as each step of the traversal locks it
+				step, err := by.Exec(newStep([]int{i}))
+				if err != nil {
+					return nil, err
+				}
+				if err = step.Error(); err != nil {
+					return nil, err
+				}
+
+				if value, ok := step.(*GraphTraversalValue); ok {
+					row[label] = value.value
+				} else {
+					row[label] = step.Values()
+				}
+			}
+		}
+		rows[i] = row
+	}
+
+	return rows, nil
+}
+
 // Sort step
 func (tv *GraphTraversalV) Sort(keys ...interface{}) *GraphTraversalV {
 	if tv.error != nil {
@@ -897,9 +1276,29 @@ func (tv *GraphTraversalV) Sort(keys ...interface{}) *GraphTraversalV {
 		sortBy = defaultSortBy
 	}
 
-	graph.SortNodes(tv.nodes, sortBy, sortOrder)
+	if tv.paths == nil {
+		graph.SortNodes(tv.nodes, sortBy, sortOrder)
+		return tv
+	}
 
-	return tv
+	// sort a copy of the nodes and reorder the paths accordingly, a node
+	// may appear several times with different paths
+	paths := make(map[*graph.Node][][]interface{})
+	for i, n := range tv.nodes {
+		paths[n] = append(paths[n], tv.paths[i])
+	}
+
+	nodes := make([]*graph.Node, len(tv.nodes))
+	copy(nodes, tv.nodes)
+	graph.SortNodes(nodes, sortBy, sortOrder)
+
+	ntv := &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: []*graph.Node{}}
+	for _, n := range nodes {
+		ntv.addNode(n, paths[n][0])
+		paths[n] = paths[n][1:]
+	}
+
+	return ntv
 }
 
 // Dedup step : deduplicate output
@@ -930,7 +1329,7 @@ func (tv *GraphTraversalV) Dedup(s ...interface{}) *GraphTraversalV {
 	defer tv.GraphTraversal.RUnlock()
 
 nodeLoop:
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
 		if it.Done() {
 			break
 		}
@@ -959,7 +1358,7 @@ nodeLoop:
 			continue
 		}
 
-		ntv.nodes = append(ntv.nodes, n)
+		ntv.addNode(n, tv.path(i))
 		if !skip {
 			visited[kvisited] = true
 		}
@@ -1014,6 +1413,10 @@ func (tv *GraphTraversalV) ShortestPathTo(m graph.Metadata, e graph.Metadata) *G
 
 	visited := make(map[graph.Identifier]bool)
 	for _, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(sp.paths)); err != nil {
+			return &GraphTraversalShortestPath{error: err}
+		}
+
 		if _, ok := visited[n.ID]; !ok {
 			path := tv.GraphTraversal.Graph.LookupShortestPath(n, m, e)
 			if len(path) > 0 {
@@ -1024,6 +1427,106 @@ func (tv *GraphTraversalV) ShortestPathTo(m graph.Metadata, e graph.Metadata) *G
 	return sp
 }
 
+// WeightedShortestPathTo step : the best path to the nodes matching the
+// metadata according to the weights stored in the key of the nodes and edges
+func (tv *GraphTraversalV) WeightedShortestPathTo(m graph.Metadata, e graph.Metadata, key string, aggregation graph.WeightAggregation) *GraphTraversalShortestPath {
+	if tv.error != nil {
+		return &GraphTraversalShortestPath{error: tv.error}
+	}
+
+	sp := &GraphTraversalShortestPath{GraphTraversal: tv.GraphTraversal, paths: [][]*graph.Node{}}
+
+	tv.GraphTraversal.RLock()
+	defer tv.GraphTraversal.RUnlock()
+
+	visited := make(map[graph.Identifier]bool)
+	for _, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(sp.paths)); err != nil {
+			return &GraphTraversalShortestPath{error: err}
+		}
+
+		if _, ok := visited[n.ID]; !ok {
+			visited[n.ID] = true
+			path, err := tv.GraphTraversal.Graph.LookupWeightedShortestPath(tv.GraphTraversal.lookupContext(), n, m, e, key, aggregation)
+			if err != nil {
+				return &GraphTraversalShortestPath{error: tv.GraphTraversal.abortError(err)}
+			}
+			if len(path) > 0 {
+				sp.paths = append(sp.paths, path)
+			}
+		}
+	}
+	return sp
+}
+
+// AllPathsTo step : all the loop free paths, of at most maxDepth edges, to
+// the nodes matching the metadata
+func (tv *GraphTraversalV) AllPathsTo(m graph.Metadata, maxDepth int64, e graph.Metadata) *GraphTraversalShortestPath {
+	if tv.error != nil {
+		return &GraphTraversalShortestPath{error: tv.error}
+	}
+
+	if maxDepth < 0 {
+		return &GraphTraversalShortestPath{error: errors.New("Max depth parameter has to be a positive integer")}
+	}
+
+	sp := &GraphTraversalShortestPath{GraphTraversal: tv.GraphTraversal, paths: [][]*graph.Node{}}
+
+	tv.GraphTraversal.RLock()
+	defer tv.GraphTraversal.RUnlock()
+
+	visited := make(map[graph.Identifier]bool)
+	for _, n := range tv.nodes {
+		if _, ok := visited[n.ID]; !ok {
+			visited[n.ID] = true
+			paths, err := tv.GraphTraversal.Graph.LookupAllPaths(tv.GraphTraversal.lookupContext(), n, m, e, int(maxDepth))
+			if err != nil {
+				return &GraphTraversalShortestPath{error: tv.GraphTraversal.abortError(err)}
+			}
+			sp.paths = append(sp.paths, paths...)
+
+			if err := tv.GraphTraversal.checkAbort(len(sp.paths)); err != nil {
+				return &GraphTraversalShortestPath{error: err}
+			}
+		}
+	}
+	return sp
+}
+
+// KShortestPathsTo step : the k shortest loop free paths to the node matching
+// the metadata
+func (tv *GraphTraversalV) KShortestPathsTo(m graph.Metadata, k int64, e graph.Metadata) *GraphTraversalShortestPath {
+	if tv.error != nil {
+		return &GraphTraversalShortestPath{error: tv.error}
+	}
+
+	if k <= 0 {
+		return &GraphTraversalShortestPath{error: errors.New("K parameter has to be a strictly positive integer")}
+	}
+
+	sp := &GraphTraversalShortestPath{GraphTraversal: tv.GraphTraversal, paths: [][]*graph.Node{}}
+
+	tv.GraphTraversal.RLock()
+	defer tv.GraphTraversal.RUnlock()
+
+	visited := make(map[graph.Identifier]bool)
+	for _, n := range tv.nodes {
+		if _, ok := visited[n.ID]; !ok {
+			visited[n.ID] = true
+			paths, err := tv.GraphTraversal.Graph.LookupKShortestPaths(tv.GraphTraversal.lookupContext(), n, m, e, int(k))
+			if err != nil {
+				return &GraphTraversalShortestPath{error: tv.GraphTraversal.abortError(err)}
+			}
+			sp.paths = append(sp.paths, paths...)
+
+			if err := tv.GraphTraversal.checkAbort(len(sp.paths)); err != nil {
+				return &GraphTraversalShortestPath{error: err}
+			}
+		}
+	}
+	return sp
+}
+
 // Has step
 func (tv *GraphTraversalV) Has(s ...interface{}) *GraphTraversalV {
 	if tv.error != nil {
@@ -1054,12 +1557,12 @@ func (tv *GraphTraversalV) Has(s ...interface{}) *GraphTraversalV {
 	tv.GraphTraversal.RLock()
 	defer tv.GraphTraversal.RUnlock()
 
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
 		if it.Done() {
 			break
 		}
 		if (filter == nil || filter.Eval(n)) && it.Next() {
-			ntv.nodes = append(ntv.nodes, n)
+			ntv.addNode(n, tv.path(i))
 		}
 	}
 
@@ -1084,37 +1587,91 @@ func (tv *GraphTraversalV) HasNot(s string) *GraphTraversalV {
 	tv.GraphTraversal.RLock()
 	defer tv.GraphTraversal.RUnlock()
 
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
 		if it.Done() {
 			break
 		}
 		if (filter == nil || filter.Eval(n)) && it.Next() {
-			ntv.nodes = append(ntv.nodes, n)
+			ntv.addNode(n, tv.path(i))
 		}
 	}
 
 	return ntv
 }
 
-// Both step
-func (tv *GraphTraversalV) Both(s ...interface{}) *GraphTraversalV {
+// filterTraversals keeps the nodes for which all the traversals, or at least
+// one of them when any is set, return something, or the opposite when not is
+// set. The graph is not locked here as each step of the traversals locks it.
+func (tv *GraphTraversalV) filterTraversals(name string, single, any, not bool, s ...interface{}) *GraphTraversalV {
 	if tv.error != nil {
 		return tv
 	}
 
-	metadata, err := ParamsToMetadataFilter(s...)
+	traversals, err := paramsToTraversals(name, single, s...)
 	if err != nil {
 		return &GraphTraversalV{error: err}
 	}
 
 	ntv := &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: []*graph.Node{}}
-	it := tv.GraphTraversal.currentStepContext.PaginationRange.Iterator()
-
-	tv.GraphTraversal.RLock()
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(0); err != nil {
+			return &GraphTraversalV{error: err}
+		}
+
+		ok, err := matchTraversals(traversals, NewGraphTraversalV(tv.GraphTraversal, []*graph.Node{n}), any)
+		if err != nil {
+			return &GraphTraversalV{error: err}
+		}
+		if ok != not {
+			ntv.addNode(n, tv.path(i))
+		}
+	}
+
+	return ntv
+}
+
+// Where step : nodes for which the traversal returns something
+func (tv *GraphTraversalV) Where(s ...interface{}) *GraphTraversalV {
+	return tv.filterTraversals("Where", true, false, false, s...)
+}
+
+// And step : nodes for which all the traversals return something
+func (tv *GraphTraversalV) And(s ...interface{}) *GraphTraversalV {
+	return tv.filterTraversals("And", false, false, false, s...)
+}
+
+// Or step : nodes for which at least one of the traversals returns something
+func (tv *GraphTraversalV) Or(s ...interface{}) *GraphTraversalV {
+	return tv.filterTraversals("Or", false, true, false, s...)
+}
+
+// Not step : nodes for which the traversal returns nothing
+func (tv *GraphTraversalV) Not(s ...interface{}) *GraphTraversalV {
+	return tv.filterTraversals("Not", true, false, true, s...)
+}
+
+// Both step
+func (tv *GraphTraversalV) Both(s ...interface{}) *GraphTraversalV {
+	if tv.error != nil {
+		return tv
+	}
+
+	metadata, err := ParamsToMetadataFilter(s...)
+	if err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
+	ntv := &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: []*graph.Node{}}
+	it := tv.GraphTraversal.currentStepContext.PaginationRange.Iterator()
+
+	tv.GraphTraversal.RLock()
 	defer tv.GraphTraversal.RUnlock()
 
 nodeloop:
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+			return &GraphTraversalV{error: err}
+		}
 		for _, e := range tv.GraphTraversal.Graph.GetNodeEdges(n, nil) {
 			var nodes []*graph.Node
 			if e.GetChild() == n.ID {
@@ -1127,12 +1684,16 @@ nodeloop:
 				if it.Done() {
 					break nodeloop
 				} else if it.Next() {
-					ntv.nodes = append(ntv.nodes, node)
+					ntv.addNode(node, extendPath(tv.path(i), node))
 				}
 			}
 		}
 	}
 
+	if err := tv.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
 	return ntv
 }
 
@@ -1145,6 +1706,81 @@ func (tv *GraphTraversalV) Count(s ...interface{}) *GraphTraversalValue {
 	return NewGraphTraversalValue(tv.GraphTraversal, len(tv.nodes))
 }
 
+// fieldGetters returns the nodes as elements of the grouping steps
+func (tv *GraphTraversalV) fieldGetters() []FieldGetter {
+	elements := make([]FieldGetter, len(tv.nodes))
+	for i, n := range tv.nodes {
+		elements[i] = n
+	}
+	return elements
+}
+
+// newStep returns a step made of the nodes at the given indexes, along with
+// their paths
+func (tv *GraphTraversalV) newStep(group []int) GraphTraversalStep {
+	ntv := &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: make([]*graph.Node, 0, len(group))}
+	for _, j := range group {
+		ntv.addNode(tv.nodes[j], tv.path(j))
+	}
+	return ntv
+}
+
+// GroupCount step : number of nodes per value of the key
+func (tv *GraphTraversalV) GroupCount(s ...interface{}) *GraphTraversalValue {
+	if tv.error != nil {
+		return NewGraphTraversalValueFromError(tv.error)
+	}
+
+	counts, err := tv.GraphTraversal.GroupCountElements(tv.fieldGetters(), s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(tv.GraphTraversal, counts)
+}
+
+// Group step : nodes grouped per value of the key, [values key or traversal]
+func (tv *GraphTraversalV) Group(s ...interface{}) *GraphTraversalValue {
+	if tv.error != nil {
+		return NewGraphTraversalValueFromError(tv.error)
+	}
+
+	groups, err := tv.GraphTraversal.GroupElements(tv.fieldGetters(), tv.newStep, s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(tv.GraphTraversal, groups)
+}
+
+// ValueMap step : a row of the values of the given keys per node
+func (tv *GraphTraversalV) ValueMap(s ...interface{}) *GraphTraversalValue {
+	if tv.error != nil {
+		return NewGraphTraversalValueFromError(tv.error)
+	}
+
+	rows, err := tv.GraphTraversal.ValueMapElements(tv.fieldGetters(), s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(tv.GraphTraversal, rows)
+}
+
+// Project step : a row per node of label and key or traversal pairs
+func (tv *GraphTraversalV) Project(s ...interface{}) *GraphTraversalValue {
+	if tv.error != nil {
+		return NewGraphTraversalValueFromError(tv.error)
+	}
+
+	rows, err := tv.GraphTraversal.ProjectElements(tv.fieldGetters(), tv.newStep, s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(tv.GraphTraversal, rows)
+}
+
 // Range step
 func (tv *GraphTraversalV) Range(s ...interface{}) *GraphTraversalV {
 	if tv.error != nil {
@@ -1163,11 +1799,11 @@ func (tv *GraphTraversalV) Range(s ...interface{}) *GraphTraversalV {
 		if !ok {
 			return &GraphTraversalV{error: fmt.Errorf("%s is not an integer", s[1])}
 		}
-		var nodes []*graph.Node
+		ntv := &GraphTraversalV{GraphTraversal: tv.GraphTraversal}
 		for ; from < int64(len(tv.nodes)) && from < to; from++ {
-			nodes = append(nodes, tv.nodes[from])
+			ntv.addNode(tv.nodes[from], tv.path(int(from)))
 		}
-		return &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: nodes}
+		return ntv
 	}
 
 	return &GraphTraversalV{error: errors.New("2 parameters must be provided to 'range'")}
@@ -1196,16 +1832,23 @@ func (tv *GraphTraversalV) Out(s ...interface{}) *GraphTraversalV {
 	defer tv.GraphTraversal.RUnlock()
 
 nodeloop:
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+			return &GraphTraversalV{error: err}
+		}
 		for _, child := range tv.GraphTraversal.Graph.LookupChildren(n, metadata, nil) {
 			if it.Done() {
 				break nodeloop
 			} else if it.Next() {
-				ntv.nodes = append(ntv.nodes, child)
+				ntv.addNode(child, extendPath(tv.path(i), child))
 			}
 		}
 	}
 
+	if err := tv.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
 	return ntv
 }
 
@@ -1227,18 +1870,25 @@ func (tv *GraphTraversalV) OutE(s ...interface{}) *GraphTraversalE {
 	defer tv.GraphTraversal.RUnlock()
 
 nodeloop:
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(nte.edges)); err != nil {
+			return &GraphTraversalE{error: err}
+		}
 		for _, e := range tv.GraphTraversal.Graph.GetNodeEdges(n, metadata) {
 			if e.GetParent() == n.ID {
 				if it.Done() {
 					break nodeloop
 				} else {
-					nte.edges = append(nte.edges, e)
+					nte.addEdge(e, extendPath(tv.path(i), e))
 				}
 			}
 		}
 	}
 
+	if err := tv.GraphTraversal.checkAbort(len(nte.edges)); err != nil {
+		return &GraphTraversalE{error: err}
+	}
+
 	return nte
 }
 
@@ -1260,16 +1910,23 @@ func (tv *GraphTraversalV) BothE(s ...interface{}) *GraphTraversalE {
 	defer tv.GraphTraversal.RUnlock()
 
 nodeloop:
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(nte.edges)); err != nil {
+			return &GraphTraversalE{error: err}
+		}
 		for _, e := range tv.GraphTraversal.Graph.GetNodeEdges(n, metadata) {
 			if it.Done() {
 				break nodeloop
 			} else if it.Next() {
-				nte.edges = append(nte.edges, e)
+				nte.addEdge(e, extendPath(tv.path(i), e))
 			}
 		}
 	}
 
+	if err := tv.GraphTraversal.checkAbort(len(nte.edges)); err != nil {
+		return &GraphTraversalE{error: err}
+	}
+
 	return nte
 }
 
@@ -1291,16 +1948,23 @@ func (tv *GraphTraversalV) In(s ...interface{}) *GraphTraversalV {
 	defer tv.GraphTraversal.RUnlock()
 
 nodeloop:
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+			return &GraphTraversalV{error: err}
+		}
 		for _, parent := range tv.GraphTraversal.Graph.LookupParents(n, metadata, nil) {
 			if it.Done() {
 				break nodeloop
 			} else {
-				ntv.nodes = append(ntv.nodes, parent)
+				ntv.addNode(parent, extendPath(tv.path(i), parent))
 			}
 		}
 	}
 
+	if err := tv.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
 	return ntv
 }
 
@@ -1322,21 +1986,213 @@ func (tv *GraphTraversalV) InE(s ...interface{}) *GraphTraversalE {
 	defer tv.GraphTraversal.RUnlock()
 
 nodeloop:
-	for _, n := range tv.nodes {
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(len(nte.edges)); err != nil {
+			return &GraphTraversalE{error: err}
+		}
 		for _, e := range tv.GraphTraversal.Graph.GetNodeEdges(n, metadata) {
 			if e.GetChild() == n.ID {
 				if it.Done() {
 					break nodeloop
 				} else if it.Next() {
-					nte.edges = append(nte.edges, e)
+					nte.addEdge(e, extendPath(tv.path(i), e))
 				}
 			}
 		}
 	}
 
+	if err := tv.GraphTraversal.checkAbort(len(nte.edges)); err != nil {
+		return &GraphTraversalE{error: err}
+	}
+
 	return nte
 }
 
+// matchTraversal returns whether the traversal returns something when
+// applied to the step
+func matchTraversal(at AnonymousTraversal, last GraphTraversalStep) (bool, error) {
+	step, err := at.Exec(last)
+	if err != nil {
+		return false, err
+	}
+	if err = step.Error(); err != nil {
+		return false, err
+	}
+	return len(step.Values()) > 0, nil
+}
+
+// matchTraversals returns whether all the traversals, or at least one of
+// them when any is set, return something when applied to the step
+func matchTraversals(traversals []AnonymousTraversal, last GraphTraversalStep, any bool) (bool, error) {
+	for _, at := range traversals {
+		ok, err := matchTraversal(at, last)
+		if err != nil {
+			return false, err
+		}
+		if ok == any {
+			return any, nil
+		}
+	}
+	return !any, nil
+}
+
+// paramsToTraversals checks that the parameters of the step are anonymous
+// traversals
+func paramsToTraversals(name string, single bool, s ...interface{}) ([]AnonymousTraversal, error) {
+	if len(s) == 0 || (single && len(s) != 1) {
+		if single {
+			return nil, fmt.Errorf("%s requires 1 traversal parameter", name)
+		}
+		return nil, fmt.Errorf("%s requires at least 1 traversal parameter", name)
+	}
+
+	traversals := make([]AnonymousTraversal, len(s))
+	for i, param := range s {
+		at, ok := param.(AnonymousTraversal)
+		if !ok {
+			return nil, fmt.Errorf("%s parameters have to be traversals", name)
+		}
+		traversals[i] = at
+	}
+	return traversals, nil
+}
+
+// partition splits the nodes between the ones the traversal returns
+// something for and the others
+func (tv *GraphTraversalV) partition(at AnonymousTraversal) (matched *GraphTraversalV, others *GraphTraversalV, err error) {
+	matched = &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: []*graph.Node{}}
+	others = &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: []*graph.Node{}}
+	for i, n := range tv.nodes {
+		if err := tv.GraphTraversal.checkAbort(0); err != nil {
+			return nil, nil, err
+		}
+
+		ok, err := matchTraversal(at, NewGraphTraversalV(tv.GraphTraversal, []*graph.Node{n}))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ok {
+			matched.addNode(n, tv.path(i))
+		} else {
+			others.addNode(n, tv.path(i))
+		}
+	}
+	return
+}
+
+// Repeat step : applies the traversal to the nodes, then to the nodes it
+// returns and so on, until Times loops are done or until the nodes match
+// Until. A node is traversed only once, at the first loop reaching it, so
+// that the loop ends on topologies having cycles.
+func (tv *GraphTraversalV) Repeat(at AnonymousTraversal, opts RepeatOptions) *GraphTraversalV {
+	if tv.error != nil {
+		return tv
+	}
+
+	if opts.Times < 0 {
+		return &GraphTraversalV{error: errors.New("Times parameter has to be a positive integer")}
+	}
+
+	ntv := &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: []*graph.Node{}}
+
+	emitted := make(map[graph.Identifier]bool)
+	emit := func(frontier *GraphTraversalV) {
+		for i, n := range frontier.nodes {
+			if !emitted[n.ID] {
+				emitted[n.ID] = true
+				ntv.addNode(n, frontier.path(i))
+			}
+		}
+	}
+
+	visited := make(map[graph.Identifier]bool)
+	for _, n := range tv.nodes {
+		visited[n.ID] = true
+	}
+
+	frontier := tv
+	if opts.Emit && opts.EmitFirst {
+		emit(frontier)
+	}
+
+	if opts.Until != nil && opts.UntilFirst {
+		matched, others, err := frontier.partition(opts.Until)
+		if err != nil {
+			return &GraphTraversalV{error: err}
+		}
+		emit(matched)
+		frontier = others
+	}
+
+	// the graph is not locked here as each step of the traversal locks it
+	for loop := int64(0); len(frontier.nodes) > 0 && (opts.Times == 0 || loop < opts.Times); loop++ {
+		if err := tv.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+			return &GraphTraversalV{error: err}
+		}
+
+		step, err := at.Exec(frontier)
+		if err != nil {
+			return &GraphTraversalV{error: err}
+		}
+
+		next, ok := step.(*GraphTraversalV)
+		if !ok {
+			return &GraphTraversalV{error: errors.New("Repeat traversal has to return nodes")}
+		}
+		if next.error != nil {
+			return &GraphTraversalV{error: next.error}
+		}
+
+		frontier = &GraphTraversalV{GraphTraversal: tv.GraphTraversal, nodes: []*graph.Node{}}
+		for i, n := range next.nodes {
+			if !visited[n.ID] {
+				visited[n.ID] = true
+				frontier.addNode(n, next.path(i))
+			}
+		}
+
+		if opts.Until != nil {
+			matched, others, err := frontier.partition(opts.Until)
+			if err != nil {
+				return &GraphTraversalV{error: err}
+			}
+			emit(matched)
+			frontier = others
+		}
+
+		if opts.Emit {
+			emit(frontier)
+		}
+	}
+
+	// nodes reached by the last loop
+	if opts.Times > 0 {
+		emit(frontier)
+	}
+
+	return ntv
+}
+
+// Path step : returns, for each node, the nodes and edges followed to reach
+// it when paths are tracked, the node alone otherwise
+func (tv *GraphTraversalV) Path() *GraphTraversalValue {
+	if tv.error != nil {
+		return NewGraphTraversalValueFromError(tv.error)
+	}
+
+	paths := make([]interface{}, len(tv.nodes))
+	for i, n := range tv.nodes {
+		if path := tv.path(i); path != nil {
+			paths[i] = path
+		} else {
+			paths[i] = []interface{}{n}
+		}
+	}
+
+	return NewGraphTraversalValue(tv.GraphTraversal, paths)
+}
+
 // SubGraph step, node/edge out
 func (tv *GraphTraversalV) SubGraph(s ...interface{}) *GraphTraversal {
 	if tv.error != nil {
@@ -1369,7 +2225,7 @@ func (tv *GraphTraversalV) SubGraph(s ...interface{}) *GraphTraversal {
 
 	ng := graph.NewGraph(tv.GraphTraversal.Graph.GetHost(), memory, common.UnknownService)
 
-	return NewGraphTraversal(ng, tv.GraphTraversal.lockGraph)
+	return tv.GraphTraversal.subTraversal(ng)
 }
 
 // SubGraph step, node/edge out
@@ -1405,7 +2261,7 @@ func (sp *GraphTraversalShortestPath) SubGraph(s ...interface{}) *GraphTraversal
 
 	ng := graph.NewGraph(sp.GraphTraversal.Graph.GetHost(), memory, common.UnknownService)
 
-	return NewGraphTraversal(ng, sp.GraphTraversal.lockGraph)
+	return sp.GraphTraversal.subTraversal(ng)
 }
 
 // Count step
@@ -1417,6 +2273,81 @@ func (te *GraphTraversalE) Count(s ...interface{}) *GraphTraversalValue {
 	return NewGraphTraversalValue(te.GraphTraversal, len(te.edges))
 }
 
+// fieldGetters returns the edges as elements of the grouping steps
+func (te *GraphTraversalE) fieldGetters() []FieldGetter {
+	elements := make([]FieldGetter, len(te.edges))
+	for i, e := range te.edges {
+		elements[i] = e
+	}
+	return elements
+}
+
+// newStep returns a step made of the edges at the given indexes, along with
+// their paths
+func (te *GraphTraversalE) newStep(group []int) GraphTraversalStep {
+	nte := &GraphTraversalE{GraphTraversal: te.GraphTraversal, edges: make([]*graph.Edge, 0, len(group))}
+	for _, j := range group {
+		nte.addEdge(te.edges[j], te.path(j))
+	}
+	return nte
+}
+
+// GroupCount step : number of edges per value of the key
+func (te *GraphTraversalE) GroupCount(s ...interface{}) *GraphTraversalValue {
+	if te.error != nil {
+		return NewGraphTraversalValueFromError(te.error)
+	}
+
+	counts, err := te.GraphTraversal.GroupCountElements(te.fieldGetters(), s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(te.GraphTraversal, counts)
+}
+
+// Group step : edges grouped per value of the key, [values key or traversal]
+func (te *GraphTraversalE) Group(s ...interface{}) *GraphTraversalValue {
+	if te.error != nil {
+		return NewGraphTraversalValueFromError(te.error)
+	}
+
+	groups, err := te.GraphTraversal.GroupElements(te.fieldGetters(), te.newStep, s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(te.GraphTraversal, groups)
+}
+
+// ValueMap step : a row of the values of the given keys per edge
+func (te *GraphTraversalE) ValueMap(s ...interface{}) *GraphTraversalValue {
+	if te.error != nil {
+		return NewGraphTraversalValueFromError(te.error)
+	}
+
+	rows, err := te.GraphTraversal.ValueMapElements(te.fieldGetters(), s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(te.GraphTraversal, rows)
+}
+
+// Project step : a row per edge of label and key or traversal pairs
+func (te *GraphTraversalE) Project(s ...interface{}) *GraphTraversalValue {
+	if te.error != nil {
+		return NewGraphTraversalValueFromError(te.error)
+	}
+
+	rows, err := te.GraphTraversal.ProjectElements(te.fieldGetters(), te.newStep, s...)
+	if err != nil {
+		return NewGraphTraversalValueFromError(err)
+	}
+
+	return NewGraphTraversalValue(te.GraphTraversal, rows)
+}
+
 // Range step
 func (te *GraphTraversalE) Range(s ...interface{}) *GraphTraversalE {
 	if te.error != nil {
@@ -1433,11 +2364,11 @@ func (te *GraphTraversalE) Range(s ...interface{}) *GraphTraversalE {
 		if !ok {
 			return &GraphTraversalE{error: fmt.Errorf("%s is not an integer", s[1])}
 		}
-		var edges []*graph.Edge
+		nte := &GraphTraversalE{GraphTraversal: te.GraphTraversal}
 		for ; from < int64(len(te.edges)) && from < to; from++ {
-			edges = append(edges, te.edges[from])
+			nte.addEdge(te.edges[from], te.path(int(from)))
 		}
-		return &GraphTraversalE{GraphTraversal: te.GraphTraversal, edges: edges}
+		return nte
 
 	default:
 		return &GraphTraversalE{GraphTraversal: te.GraphTraversal, error: errors.New("2 parameters must be provided to 'range'")}
@@ -1475,7 +2406,7 @@ func (te *GraphTraversalE) Dedup(keys ...interface{}) *GraphTraversalE {
 	te.GraphTraversal.RLock()
 	defer te.GraphTraversal.RUnlock()
 
-	for _, e := range te.edges {
+	for i, e := range te.edges {
 		kvisited = e.ID
 		if key != "" {
 			if v, ok := e.Metadata()[key]; ok {
@@ -1484,7 +2415,7 @@ func (te *GraphTraversalE) Dedup(keys ...interface{}) *GraphTraversalE {
 		}
 
 		if _, ok := visited[kvisited]; !ok {
-			ntv.edges = append(ntv.edges, e)
+			ntv.addEdge(e, te.path(i))
 			visited[kvisited] = true
 		}
 	}
@@ -1521,12 +2452,12 @@ func (te *GraphTraversalE) Has(s ...interface{}) *GraphTraversalE {
 	te.GraphTraversal.RLock()
 	defer te.GraphTraversal.RUnlock()
 
-	for _, e := range te.edges {
+	for i, e := range te.edges {
 		if it.Done() {
 			break
 		}
 		if (filter == nil || filter.Eval(e)) && it.Next() {
-			nte.edges = append(nte.edges, e)
+			nte.addEdge(e, te.path(i))
 		}
 	}
 
@@ -1551,18 +2482,65 @@ func (te *GraphTraversalE) HasNot(s string) *GraphTraversalE {
 	te.GraphTraversal.RLock()
 	defer te.GraphTraversal.RUnlock()
 
-	for _, e := range te.edges {
+	for i, e := range te.edges {
 		if it.Done() {
 			break
 		}
 		if (filter == nil || filter.Eval(e)) && it.Next() {
-			nte.edges = append(nte.edges, e)
+			nte.addEdge(e, te.path(i))
+		}
+	}
+
+	return nte
+}
+
+// filterTraversals keeps the edges for which all the traversals, or at least
+// one of them when any is set, return something, or the opposite when not is
+// set. The graph is not locked here as each step of the traversals locks it.
+func (te *GraphTraversalE) filterTraversals(name string, single, any, not bool, s ...interface{}) *GraphTraversalE {
+	if te.error != nil {
+		return te
+	}
+
+	traversals, err := paramsToTraversals(name, single, s...)
+	if err != nil {
+		return &GraphTraversalE{error: err}
+	}
+
+	nte := &GraphTraversalE{GraphTraversal: te.GraphTraversal, edges: []*graph.Edge{}}
+	for i, e := range te.edges {
+		ok, err := matchTraversals(traversals, NewGraphTraversalE(te.GraphTraversal, []*graph.Edge{e}), any)
+		if err != nil {
+			return &GraphTraversalE{error: err}
+		}
+		if ok != not {
+			nte.addEdge(e, te.path(i))
 		}
 	}
 
 	return nte
 }
 
+// Where step : edges for which the traversal returns something
+func (te *GraphTraversalE) Where(s ...interface{}) *GraphTraversalE {
+	return te.filterTraversals("Where", true, false, false, s...)
+}
+
+// And step : edges for which all the traversals return something
+func (te *GraphTraversalE) And(s ...interface{}) *GraphTraversalE {
+	return te.filterTraversals("And", false, false, false, s...)
+}
+
+// Or step : edges for which at least one of the traversals returns something
+func (te *GraphTraversalE) Or(s ...interface{}) *GraphTraversalE {
+	return te.filterTraversals("Or", false, true, false, s...)
+}
+
+// Not step : edges for which the traversal returns nothing
+func (te *GraphTraversalE) Not(s ...interface{}) *GraphTraversalE {
+	return te.filterTraversals("Not", true, false, true, s...)
+}
+
 // InV step, node in
 func (te *GraphTraversalE) InV(s ...interface{}) *GraphTraversalV {
 	if te.error != nil {
@@ -1580,17 +2558,24 @@ func (te *GraphTraversalE) InV(s ...interface{}) *GraphTraversalV {
 	te.GraphTraversal.RLock()
 	defer te.GraphTraversal.RUnlock()
 
-	for _, e := range te.edges {
+	for i, e := range te.edges {
+		if err := te.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+			return &GraphTraversalV{error: err}
+		}
 		parents, _ := te.GraphTraversal.Graph.GetEdgeNodes(e, metadata, nil)
 		for _, parent := range parents {
 			if it.Done() {
 				break
 			} else if it.Next() {
-				ntv.nodes = append(ntv.nodes, parent)
+				ntv.addNode(parent, extendPath(te.path(i), parent))
 			}
 		}
 	}
 
+	if err := te.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
 	return ntv
 }
 
@@ -1611,17 +2596,24 @@ func (te *GraphTraversalE) OutV(s ...interface{}) *GraphTraversalV {
 	te.GraphTraversal.RLock()
 	defer te.GraphTraversal.RUnlock()
 
-	for _, e := range te.edges {
+	for i, e := range te.edges {
+		if err := te.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+			return &GraphTraversalV{error: err}
+		}
 		_, children := te.GraphTraversal.Graph.GetEdgeNodes(e, nil, metadata)
 		for _, child := range children {
 			if it.Done() {
 				break
 			} else if it.Next() {
-				ntv.nodes = append(ntv.nodes, child)
+				ntv.addNode(child, extendPath(te.path(i), child))
 			}
 		}
 	}
 
+	if err := te.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
 	return ntv
 }
 
@@ -1642,27 +2634,53 @@ func (te *GraphTraversalE) BothV(s ...interface{}) *GraphTraversalV {
 	te.GraphTraversal.RLock()
 	defer te.GraphTraversal.RUnlock()
 
-	for _, e := range te.edges {
+	for i, e := range te.edges {
+		if err := te.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+			return &GraphTraversalV{error: err}
+		}
 		parents, children := te.GraphTraversal.Graph.GetEdgeNodes(e, metadata, metadata)
 		for _, parent := range parents {
 			if it.Done() {
 				break
 			} else if it.Next() {
-				ntv.nodes = append(ntv.nodes, parent)
+				ntv.addNode(parent, extendPath(te.path(i), parent))
 			}
 		}
 		for _, child := range children {
 			if it.Done() {
 				break
 			} else if it.Next() {
-				ntv.nodes = append(ntv.nodes, child)
+				ntv.addNode(child, extendPath(te.path(i), child))
 			}
 		}
 	}
 
+	if err := te.GraphTraversal.checkAbort(len(ntv.nodes)); err != nil {
+		return &GraphTraversalV{error: err}
+	}
+
 	return ntv
 }
 
+// Path step : returns, for each edge, the nodes and edges followed to reach
+// it when paths are tracked, the edge alone otherwise
+func (te *GraphTraversalE) Path() *GraphTraversalValue {
+	if te.error != nil {
+		return NewGraphTraversalValueFromError(te.error)
+	}
+
+	paths := make([]interface{}, len(te.edges))
+	for i, e := range te.edges {
+		if path := te.path(i); path != nil {
+			paths[i] = path
+		} else {
+			paths[i] = []interface{}{e}
+		}
+	}
+
+	return NewGraphTraversalValue(te.GraphTraversal, paths)
+}
+
 // SubGraph step, node/edge out
 func (te *GraphTraversalE) SubGraph(s ...interface{}) *GraphTraversal {
 	if te.error != nil {
@@ -1698,7 +2716,7 @@ func (te *GraphTraversalE) SubGraph(s ...interface{}) *GraphTraversal {
 
 	ng := graph.NewGraph(te.GraphTraversal.Graph.GetHost(), memory, common.UnknownService)
 
-	return NewGraphTraversal(ng, te.GraphTraversal.lockGraph)
+	return te.GraphTraversal.subTraversal(ng)
 }
 
 // NewGraphTraversalValue creates a new traversal value step
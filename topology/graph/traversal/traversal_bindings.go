@@ -0,0 +1,266 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package traversal
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// Binding is a named parameter of a query, written $name, whose value is
+// given when the query is executed
+type Binding string
+
+// Bindings maps the names of the bindings of a query to their values
+type Bindings map[string]interface{}
+
+// GremlinTraversalStepBinder is implemented by the steps holding parameters
+// or state out of an embedded GremlinTraversalContext, like the steps of the
+// extensions. Bind returns a copy of the step, that its execution can modify,
+// where the bindings are replaced by their values.
+type GremlinTraversalStepBinder interface {
+	Bind(bindings Bindings) (GremlinTraversalStep, error)
+}
+
+func (b Binding) String() string {
+	return "$" + string(b)
+}
+
+// bindingValue returns the value bound to the given name
+func (b Bindings) bindingValue(name Binding) (interface{}, error) {
+	value, ok := b[string(name)]
+	if !ok {
+		return nil, fmt.Errorf("No value bound to %s", name)
+	}
+	return normalizeValue(value), nil
+}
+
+// normalizeValue converts the integral numbers to int64, as the numbers of
+// the queries, the ones decoded from JSON being float64
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < math.MaxInt64 {
+			return int64(v)
+		}
+	}
+	return value
+}
+
+// bindList binds a list of values, the lists bound to a name being expanded
+func (b Bindings) bindList(list []interface{}) ([]interface{}, error) {
+	var values []interface{}
+	for _, param := range list {
+		name, ok := param.(Binding)
+		if !ok {
+			values = append(values, param)
+			continue
+		}
+
+		value, err := b.bindingValue(name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				values = append(values, normalizeValue(item))
+			}
+		case []string:
+			for _, item := range v {
+				values = append(values, item)
+			}
+		default:
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// BindParams returns a copy of the parameters where the bindings are
+// replaced by their values
+func (b Bindings) BindParams(params []interface{}) ([]interface{}, error) {
+	if params == nil {
+		return nil, nil
+	}
+
+	bound := make([]interface{}, len(params))
+	for i, param := range params {
+		value, err := b.bindParam(param)
+		if err != nil {
+			return nil, err
+		}
+		bound[i] = value
+	}
+	return bound, nil
+}
+
+func (b Bindings) bindParam(param interface{}) (_ interface{}, err error) {
+	switch p := param.(type) {
+	case Binding:
+		return b.bindingValue(p)
+	case *GremlinTraversalAnonymous:
+		steps, err := bindSteps(p.steps, b)
+		if err != nil {
+			return nil, err
+		}
+		return &GremlinTraversalAnonymous{steps: steps}, nil
+	case graph.Metadata:
+		m := graph.Metadata{}
+		for k, v := range p {
+			if m[k], err = b.bindParam(v); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case *WithinGraphElementMatcher:
+		list, err := b.bindList(p.List)
+		return Within(list...), err
+	case *WithoutGraphElementMatcher:
+		list, err := b.bindList(p.list)
+		return Without(list...), err
+	case *NEGraphElementMatcher:
+		value, err := b.bindParam(p.value)
+		return Ne(value), err
+	case *LTGraphElementMatcher:
+		value, err := b.bindParam(p.value)
+		return Lt(value), err
+	case *GTGraphElementMatcher:
+		value, err := b.bindParam(p.value)
+		return Gt(value), err
+	case *LTEGraphElementMatcher:
+		value, err := b.bindParam(p.value)
+		return Lte(value), err
+	case *GTEGraphElementMatcher:
+		value, err := b.bindParam(p.value)
+		return Gte(value), err
+	case *IPV4RangeGraphElementMatcher:
+		value, err := b.bindParam(p.value)
+		return IPV4Range(value), err
+	case *IPV6RangeGraphElementMatcher:
+		value, err := b.bindParam(p.value)
+		return IPV6Range(value), err
+	case *InsideGraphElementMatcher:
+		from, to, err := b.bindRange(p.from, p.to)
+		return Inside(from, to), err
+	case *OutsideGraphElementMatcher:
+		from, to, err := b.bindRange(p.from, p.to)
+		return Outside(from, to), err
+	case *BetweenGraphElementMatcher:
+		from, to, err := b.bindRange(p.from, p.to)
+		return Between(from, to), err
+	}
+	return param, nil
+}
+
+// BindContext returns a copy of a step context where the bindings of the
+// parameters are replaced by their values
+func (b Bindings) BindContext(context GremlinTraversalContext) (GremlinTraversalContext, error) {
+	params, err := b.BindParams(context.Params)
+	if err != nil {
+		return context, err
+	}
+	context.Params = params
+	return context, nil
+}
+
+func (b Bindings) bindRange(from, to interface{}) (_ interface{}, _ interface{}, err error) {
+	if from, err = b.bindParam(from); err != nil {
+		return nil, nil, err
+	}
+	if to, err = b.bindParam(to); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// bindStep returns a copy of the step, the bindings of its parameters being
+// replaced by their values. The steps are copied even without bindings as
+// the Reduce optimisations modify them, so that a parsed sequence can be
+// executed several times, concurrently. The steps implementing
+// GremlinTraversalStepBinder copy themselves, otherwise the parameters of
+// the embedded GremlinTraversalContext are bound and the exported slices
+// copied.
+func bindStep(step GremlinTraversalStep, bindings Bindings) (GremlinTraversalStep, error) {
+	if binder, ok := step.(GremlinTraversalStepBinder); ok {
+		return binder.Bind(bindings)
+	}
+
+	v := reflect.ValueOf(step)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return step, nil
+	}
+
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+
+	for i := 0; i < c.Elem().NumField(); i++ {
+		if f := c.Elem().Field(i); f.Kind() == reflect.Slice && !f.IsNil() && f.CanSet() {
+			f.Set(reflect.AppendSlice(reflect.MakeSlice(f.Type(), 0, f.Len()), f))
+		}
+	}
+
+	if f := c.Elem().FieldByName("GremlinTraversalContext"); f.IsValid() && f.CanSet() {
+		context, ok := f.Interface().(GremlinTraversalContext)
+		if ok {
+			context, err := bindings.BindContext(context)
+			if err != nil {
+				return nil, err
+			}
+			f.Set(reflect.ValueOf(context))
+		}
+	}
+
+	return c.Interface().(GremlinTraversalStep), nil
+}
+
+func bindSteps(steps []GremlinTraversalStep, bindings Bindings) ([]GremlinTraversalStep, error) {
+	bound := make([]GremlinTraversalStep, len(steps))
+	for i, step := range steps {
+		s, err := bindStep(step, bindings)
+		if err != nil {
+			return nil, err
+		}
+		bound[i] = s
+	}
+	return bound, nil
+}
+
+// Bind returns a copy of the sequence where the bindings are replaced by
+// the given values
+func (s *GremlinTraversalSequence) Bind(bindings Bindings) (*GremlinTraversalSequence, error) {
+	steps, err := bindSteps(s.steps, bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GremlinTraversalSequence{steps: steps, extensions: s.extensions}, nil
+}
@@ -27,3 +27,11 @@ type GremlinTraversalExtension interface {
 	ScanIdent(s string) (Token, bool)
 	ParseStep(t Token, p GremlinTraversalContext) (GremlinTraversalStep, error)
 }
+
+// PushDownStep is implemented by the steps whose execution can be delegated
+// to another component, like the flow storage or the agents
+type PushDownStep interface {
+	// PushDown returns where the last execution of the step was delegated,
+	// empty if the step was executed locally
+	PushDown() string
+}
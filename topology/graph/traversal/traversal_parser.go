@@ -23,24 +23,27 @@
 package traversal
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pmylund/go-cache"
 	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/topology/graph"
 )
 
 type (
 	// GremlinTraversalSequence describes a sequence of steps
 	GremlinTraversalSequence struct {
-		GraphTraversal *GraphTraversal
-		steps          []GremlinTraversalStep
-		extensions     []GremlinTraversalExtension
+		steps      []GremlinTraversalStep
+		extensions []GremlinTraversalExtension
 	}
 
 	// GremlinTraversalStep describes a step
@@ -170,13 +173,109 @@ type (
 	GremlinTraversalStepSelect struct {
 		GremlinTraversalContext
 	}
+	// GremlinTraversalStepRepeat step
+	GremlinTraversalStepRepeat struct {
+		GremlinTraversalContext
+		options RepeatOptions
+	}
+	// GremlinTraversalStepTimes step, modulator of the Repeat step
+	GremlinTraversalStepTimes struct {
+		GremlinTraversalContext
+		modulating bool
+	}
+	// GremlinTraversalStepUntil step, modulator of the Repeat step
+	GremlinTraversalStepUntil struct {
+		GremlinTraversalContext
+		modulating bool
+	}
+	// GremlinTraversalStepEmit step, modulator of the Repeat step
+	GremlinTraversalStepEmit struct {
+		GremlinTraversalContext
+		modulating bool
+	}
+
+	// GremlinTraversalStepGroup step
+	GremlinTraversalStepGroup struct {
+		GremlinTraversalContext
+		by []*GremlinTraversalStepBy
+	}
+	// GremlinTraversalStepGroupCount step
+	GremlinTraversalStepGroupCount struct {
+		GremlinTraversalContext
+		by *GremlinTraversalStepBy
+	}
+	// GremlinTraversalStepValueMap step
+	GremlinTraversalStepValueMap struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepProject step
+	GremlinTraversalStepProject struct {
+		GremlinTraversalContext
+		by []*GremlinTraversalStepBy
+	}
+	// GremlinTraversalStepBy step, modulator of the Group, GroupCount and Project steps
+	GremlinTraversalStepBy struct {
+		GremlinTraversalContext
+	}
+
+	// GremlinTraversalStepWhere step
+	GremlinTraversalStepWhere struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepAnd step
+	GremlinTraversalStepAnd struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepOr step
+	GremlinTraversalStepOr struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepNot step
+	GremlinTraversalStepNot struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepPath step
+	GremlinTraversalStepPath struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepAllPathsTo step
+	GremlinTraversalStepAllPathsTo struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepKShortestPathsTo step
+	GremlinTraversalStepKShortestPathsTo struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepExplain step
+	GremlinTraversalStepExplain struct {
+		GremlinTraversalContext
+	}
+	// GremlinTraversalStepProfile step
+	GremlinTraversalStepProfile struct {
+		GremlinTraversalContext
+	}
+
+	// GremlinTraversalAnonymous describes a sequence of steps given as a
+	// parameter of a step, applied to the result of the previous step
+	GremlinTraversalAnonymous struct {
+		steps []GremlinTraversalStep
+	}
 )
 
 var (
 	// ErrExecutionError execution error
 	ErrExecutionError = errors.New("Error while executing the query")
+	// ErrQueryCanceled query canceled, by the client for instance
+	ErrQueryCanceled = errors.New("Query canceled")
+	// ErrQueryTimeout query aborted as it exceeded its deadline
+	ErrQueryTimeout = errors.New("Query aborted, timeout exceeded")
+	// ErrTooManyElements query aborted as a step returned too many elements
+	ErrTooManyElements = errors.New("Query aborted, too many elements returned")
 )
 
+// maxCachedQueries is the maximum number of parsed queries kept by a parser
+const maxCachedQueries = 1000
+
 // GremlinTraversalParser describes a parser of gremlin graph expression
 // The mechanism is based on Reduce and Exec steps
 type GremlinTraversalParser struct {
@@ -188,6 +287,7 @@ type GremlinTraversalParser struct {
 		n   int
 	}
 	extensions []GremlinTraversalExtension
+	cache      *cache.Cache
 }
 
 func invokeStepFnc(last GraphTraversalStep, name string, gremlinStep GremlinTraversalStep) (GraphTraversalStep, error) {
@@ -650,20 +750,58 @@ func (s *GremlinTraversalStepBothE) Reduce(next GremlinTraversalStep) GremlinTra
 	return next
 }
 
+// shortestPathParams returns the parameters of the ShortestPathTo step : the
+// node metadata, then optionally the edge metadata, a weight key and the
+// weight aggregation name
+func shortestPathParams(params []interface{}) (m graph.Metadata, e graph.Metadata, key string, aggregation graph.WeightAggregation, err error) {
+	if len(params) == 0 || len(params) > 4 {
+		return nil, nil, "", aggregation, fmt.Errorf("ShortestPathTo predicate accepts only 1 to 4 parameters : %v", params)
+	}
+
+	m, ok := params[0].(graph.Metadata)
+	if !ok {
+		return nil, nil, "", aggregation, fmt.Errorf("ShortestPathTo first parameter has to be a metadata : %v", params)
+	}
+	params = params[1:]
+
+	if len(params) > 0 {
+		if e, ok = params[0].(graph.Metadata); ok {
+			params = params[1:]
+		}
+	}
+
+	if len(params) > 0 {
+		if key, ok = params[0].(string); !ok {
+			return nil, nil, "", aggregation, fmt.Errorf("ShortestPathTo weight key has to be a string : %v", params)
+		}
+		params = params[1:]
+	}
+
+	if len(params) > 0 {
+		name, ok := params[0].(string)
+		if !ok || len(params) > 1 {
+			return nil, nil, "", aggregation, fmt.Errorf("ShortestPathTo weight aggregation has to be 'sum' or 'min' : %v", params)
+		}
+		if aggregation, err = graph.WeightAggregationByName(name); err != nil {
+			return nil, nil, "", aggregation, fmt.Errorf("ShortestPathTo weight aggregation has to be 'sum' or 'min' : %v", params)
+		}
+	}
+
+	return m, e, key, aggregation, nil
+}
+
 // Exec ShortestPathTo step
 func (s *GremlinTraversalStepShortestPathTo) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
 	switch last.(type) {
 	case *GraphTraversalV:
-		if _, ok := s.Params[0].(graph.Metadata); !ok {
-			return nil, ErrExecutionError
+		m, e, key, aggregation, err := shortestPathParams(s.Params)
+		if err != nil {
+			return nil, err
 		}
-		if len(s.Params) > 1 {
-			if _, ok := s.Params[1].(graph.Metadata); !ok {
-				return nil, ErrExecutionError
-			}
-			return last.(*GraphTraversalV).ShortestPathTo(s.Params[0].(graph.Metadata), s.Params[1].(graph.Metadata)), nil
+		if key != "" {
+			return last.(*GraphTraversalV).WeightedShortestPathTo(m, e, key, aggregation), nil
 		}
-		return last.(*GraphTraversalV).ShortestPathTo(s.Params[0].(graph.Metadata), nil), nil
+		return last.(*GraphTraversalV).ShortestPathTo(m, e), nil
 	}
 
 	return nil, ErrExecutionError
@@ -844,25 +982,427 @@ func (s *GremlinTraversalStepSelect) Reduce(next GremlinTraversalStep) GremlinTr
 	return next
 }
 
+// modulate applies the Times, Until or Emit step to the Repeat step
+func (s *GremlinTraversalStepRepeat) modulate(step GremlinTraversalStep, before bool) bool {
+	switch step := step.(type) {
+	case *GremlinTraversalStepTimes:
+		s.options.Times = step.Params[0].(int64)
+	case *GremlinTraversalStepUntil:
+		s.options.Until = step.Params[0].(AnonymousTraversal)
+		s.options.UntilFirst = before
+	case *GremlinTraversalStepEmit:
+		s.options.Emit = true
+		s.options.EmitFirst = before
+	default:
+		return false
+	}
+	return true
+}
+
+// Exec Repeat step
+func (s *GremlinTraversalStepRepeat) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).Repeat(s.Params[0].(AnonymousTraversal), s.options), nil
+	}
+
+	return nil, ErrExecutionError
+}
+
+// Reduce Repeat step
+func (s *GremlinTraversalStepRepeat) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	if s.modulate(next, false) {
+		return s
+	}
+
+	return next
+}
+
+// reduceRepeatModulator applies a modulator placed before a Repeat step
+func reduceRepeatModulator(modulator GremlinTraversalStep, next GremlinTraversalStep) bool {
+	if repeatStep, ok := next.(*GremlinTraversalStepRepeat); ok {
+		return repeatStep.modulate(modulator, true)
+	}
+	return false
+}
+
+// execRepeatModulator returns the previous step as the modulator has
+// already been applied to the Repeat step
+func execRepeatModulator(last GraphTraversalStep, name string, modulating bool) (GraphTraversalStep, error) {
+	if !modulating {
+		return nil, fmt.Errorf("'%s' has to be used with a 'Repeat' step", name)
+	}
+	return last, nil
+}
+
+// Exec Times step
+func (s *GremlinTraversalStepTimes) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return execRepeatModulator(last, "Times", s.modulating)
+}
+
+// Reduce Times step
+func (s *GremlinTraversalStepTimes) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	s.modulating = reduceRepeatModulator(s, next)
+	return next
+}
+
+// Exec Until step
+func (s *GremlinTraversalStepUntil) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return execRepeatModulator(last, "Until", s.modulating)
+}
+
+// Reduce Until step
+func (s *GremlinTraversalStepUntil) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	s.modulating = reduceRepeatModulator(s, next)
+	return next
+}
+
+// Exec Emit step
+func (s *GremlinTraversalStepEmit) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return execRepeatModulator(last, "Emit", s.modulating)
+}
+
+// Reduce Emit step
+func (s *GremlinTraversalStepEmit) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	s.modulating = reduceRepeatModulator(s, next)
+	return next
+}
+
+// Exec Group step
+func (s *GremlinTraversalStepGroup) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	// the first By step gives the key, the second one the values
+	s.Params = nil
+	for _, by := range s.by {
+		s.Params = append(s.Params, by.Params[0])
+	}
+
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).Group(s.Params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).Group(s.Params...), nil
+	}
+
+	return invokeStepFnc(last, "Group", s)
+}
+
+// Reduce Group step
+func (s *GremlinTraversalStepGroup) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	if byStep, ok := next.(*GremlinTraversalStepBy); ok {
+		for _, by := range s.by {
+			if by == byStep {
+				return s
+			}
+		}
+		if len(s.by) < 2 {
+			s.by = append(s.by, byStep)
+			return s
+		}
+	}
+
+	return next
+}
+
+// Exec GroupCount step
+func (s *GremlinTraversalStepGroupCount) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	if s.by != nil {
+		s.Params = s.by.Params
+	}
+
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).GroupCount(s.Params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).GroupCount(s.Params...), nil
+	}
+
+	return invokeStepFnc(last, "GroupCount", s)
+}
+
+// Reduce GroupCount step
+func (s *GremlinTraversalStepGroupCount) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	if byStep, ok := next.(*GremlinTraversalStepBy); ok && (s.by == nil || s.by == byStep) && len(s.Params) == 0 {
+		s.by = byStep
+		return s
+	}
+
+	return next
+}
+
+// Exec ValueMap step
+func (s *GremlinTraversalStepValueMap) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).ValueMap(s.Params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).ValueMap(s.Params...), nil
+	}
+
+	return invokeStepFnc(last, "ValueMap", s)
+}
+
+// Reduce ValueMap step
+func (s *GremlinTraversalStepValueMap) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec Project step
+func (s *GremlinTraversalStepProject) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	// each label is followed by the value given by its By step, the label
+	// being used as key when there is no By step for it
+	var params []interface{}
+	for i, label := range s.Params {
+		if i < len(s.by) {
+			params = append(params, label, s.by[i].Params[0])
+		} else {
+			params = append(params, label, label)
+		}
+	}
+
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).Project(params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).Project(params...), nil
+	}
+
+	// the parameters of the step are kept untouched as an anonymous traversal
+	// can be executed several times
+	projection := &GremlinTraversalStepProject{GremlinTraversalContext: s.GremlinTraversalContext}
+	projection.Params = params
+	return invokeStepFnc(last, "Project", projection)
+}
+
+// Reduce Project step
+func (s *GremlinTraversalStepProject) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	if byStep, ok := next.(*GremlinTraversalStepBy); ok {
+		for _, by := range s.by {
+			if by == byStep {
+				return s
+			}
+		}
+		if len(s.by) < len(s.Params) {
+			s.by = append(s.by, byStep)
+			return s
+		}
+	}
+
+	return next
+}
+
+// Exec By step
+func (s *GremlinTraversalStepBy) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return nil, errors.New("'By' has to be used with a 'Group', 'GroupCount' or 'Project' step")
+}
+
+// Reduce By step
+func (s *GremlinTraversalStepBy) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec Where step
+func (s *GremlinTraversalStepWhere) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).Where(s.Params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).Where(s.Params...), nil
+	}
+
+	return invokeStepFnc(last, "Where", s)
+}
+
+// Reduce Where step
+func (s *GremlinTraversalStepWhere) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec And step
+func (s *GremlinTraversalStepAnd) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).And(s.Params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).And(s.Params...), nil
+	}
+
+	return invokeStepFnc(last, "And", s)
+}
+
+// Reduce And step
+func (s *GremlinTraversalStepAnd) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec Or step
+func (s *GremlinTraversalStepOr) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).Or(s.Params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).Or(s.Params...), nil
+	}
+
+	return invokeStepFnc(last, "Or", s)
+}
+
+// Reduce Or step
+func (s *GremlinTraversalStepOr) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec Not step
+func (s *GremlinTraversalStepNot) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).Not(s.Params...), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).Not(s.Params...), nil
+	}
+
+	return invokeStepFnc(last, "Not", s)
+}
+
+// Reduce Not step
+func (s *GremlinTraversalStepNot) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec Path step
+func (s *GremlinTraversalStepPath) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).Path(), nil
+	case *GraphTraversalE:
+		return last.(*GraphTraversalE).Path(), nil
+	}
+
+	return invokeStepFnc(last, "Path", s)
+}
+
+// Reduce Path step
+func (s *GremlinTraversalStepPath) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// pathsToParams returns the parameters of the AllPathsTo and
+// KShortestPathsTo steps, already checked by the parser
+func pathsToParams(params []interface{}) (graph.Metadata, int64, graph.Metadata) {
+	var e graph.Metadata
+	if len(params) > 2 {
+		e = params[2].(graph.Metadata)
+	}
+	return params[0].(graph.Metadata), params[1].(int64), e
+}
+
+// Exec AllPathsTo step
+func (s *GremlinTraversalStepAllPathsTo) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).AllPathsTo(pathsToParams(s.Params)), nil
+	}
+
+	return nil, ErrExecutionError
+}
+
+// Reduce AllPathsTo step
+func (s *GremlinTraversalStepAllPathsTo) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec KShortestPathsTo step
+func (s *GremlinTraversalStepKShortestPathsTo) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	switch last.(type) {
+	case *GraphTraversalV:
+		return last.(*GraphTraversalV).KShortestPathsTo(pathsToParams(s.Params)), nil
+	}
+
+	return nil, ErrExecutionError
+}
+
+// Reduce KShortestPathsTo step
+func (s *GremlinTraversalStepKShortestPathsTo) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec Explain step, handled by the sequence when it is the last step
+func (s *GremlinTraversalStepExplain) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return nil, errors.New("Explain has to be the last step")
+}
+
+// Reduce Explain step
+func (s *GremlinTraversalStepExplain) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec Profile step, handled by the sequence when it is the last step
+func (s *GremlinTraversalStepProfile) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return nil, errors.New("Profile has to be the last step")
+}
+
+// Reduce Profile step
+func (s *GremlinTraversalStepProfile) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	return next
+}
+
+// Exec anonymous traversal steps, starting from the given step
+func (a *GremlinTraversalAnonymous) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return execSteps(a.steps, last)
+}
+
 // Exec sequence step
 func (s *GremlinTraversalSequence) Exec(g *graph.Graph, lockGraph bool) (GraphTraversalStep, error) {
-	var step GremlinTraversalStep
-	var last GraphTraversalStep
-	var err error
+	return s.ExecContext(context.Background(), g, lockGraph)
+}
+
+// ExecContext executes the sequence, aborting it when the context is done,
+// when the gremlin.query_timeout deadline is exceeded or when a step returns
+// more than gremlin.max_elements elements
+func (s *GremlinTraversalSequence) ExecContext(ctx context.Context, g *graph.Graph, lockGraph bool) (GraphTraversalStep, error) {
+	if timeout := config.GetInt("gremlin.query_timeout"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
 
-	s.GraphTraversal = NewGraphTraversal(g, lockGraph)
-	last = s.GraphTraversal
+	// the steps are copied as they are modified by the execution, an error
+	// being returned if a binding has no value
+	steps, err := bindSteps(s.steps, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < len(s.steps); {
-		step = s.steps[i]
+	gt := NewGraphTraversal(g, lockGraph).WithContext(ctx, config.GetInt("gremlin.max_elements"))
+
+	// paths are recorded only when requested as it has a cost
+	for _, step := range steps {
+		if _, ok := step.(*GremlinTraversalStepPath); ok {
+			gt.TrackPaths()
+			break
+		}
+	}
 
-		for i = i + 1; i < len(s.steps); i = i + 1 {
-			if next := step.Reduce(s.steps[i]); next != step {
-				break
+	if n := len(steps); n > 0 {
+		switch steps[n-1].(type) {
+		case *GremlinTraversalStepExplain:
+			return NewGraphTraversalValue(gt, explainSteps(steps[:n-1])), nil
+		case *GremlinTraversalStepProfile:
+			profiles, err := profileSteps(steps[:n-1], gt)
+			if err != nil {
+				return nil, err
 			}
+			return NewGraphTraversalValue(gt, profiles), nil
 		}
+	}
+
+	return execSteps(steps, gt)
+}
 
-		if last, err = step.Exec(last); err != nil {
+func execSteps(steps []GremlinTraversalStep, last GraphTraversalStep) (GraphTraversalStep, error) {
+	var err error
+
+	for _, rs := range reduceSteps(steps) {
+		if last, err = rs.step.Exec(last); err != nil {
 			return nil, err
 		}
 
@@ -882,11 +1422,14 @@ func (s *GremlinTraversalSequence) Exec(g *graph.Graph, lockGraph bool) (GraphTr
 // AddTraversalExtension registers a new gremlin traversal extension
 func (p *GremlinTraversalParser) AddTraversalExtension(e GremlinTraversalExtension) {
 	p.extensions = append(p.extensions, e)
+	p.cache.Flush()
 }
 
 // NewGremlinTraversalParser creates a new gremlin language parser on the graph
 func NewGremlinTraversalParser() *GremlinTraversalParser {
-	return &GremlinTraversalParser{}
+	return &GremlinTraversalParser{
+		cache: cache.New(5*time.Minute, 10*time.Minute),
+	}
 }
 
 func (p *GremlinTraversalParser) parseStepParams() ([]interface{}, error) {
@@ -913,6 +1456,8 @@ func (p *GremlinTraversalParser) parseStepParams() ([]interface{}, error) {
 			}
 		case STRING:
 			params = append(params, lit)
+		case BINDING:
+			params = append(params, Binding(lit))
 		case METADATA:
 			metadataParams, err := p.parseStepParams()
 			if err != nil {
@@ -1034,6 +1579,15 @@ func (p *GremlinTraversalParser) parseStepParams() ([]interface{}, error) {
 				return nil, fmt.Errorf("One parameter expected with IPV4RANGE: %v", ipParams)
 			}
 			params = append(params, IPV4Range(ipParams[0]))
+		case IPV6RANGE:
+			ipParams, err := p.parseStepParams()
+			if err != nil {
+				return nil, err
+			}
+			if len(ipParams) != 1 {
+				return nil, fmt.Errorf("One parameter expected with IPV6RANGE: %v", ipParams)
+			}
+			params = append(params, IPV6Range(ipParams[0]))
 		case FOREVER:
 			params = append(params, &ForeverPredicate{})
 		case NOW:
@@ -1043,7 +1597,16 @@ func (p *GremlinTraversalParser) parseStepParams() ([]interface{}, error) {
 		case FALSE:
 			params = append(params, false)
 		default:
-			return nil, fmt.Errorf("Unexpected token while parsing parameters, got: %s", lit)
+			// any other keyword starts an anonymous traversal
+			if tok <= G {
+				return nil, fmt.Errorf("Unexpected token while parsing parameters, got: %s", lit)
+			}
+			p.unscan()
+			anonymous, err := p.parseAnonymousTraversal()
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, anonymous)
 		}
 		tok, lit = p.scanIgnoreWhitespace()
 	}
@@ -1051,6 +1614,43 @@ func (p *GremlinTraversalParser) parseStepParams() ([]interface{}, error) {
 	return params, nil
 }
 
+// parseAnonymousTraversal parses dot-delimited steps until the end of the
+// step parameter
+func (p *GremlinTraversalParser) parseAnonymousTraversal() (*GremlinTraversalAnonymous, error) {
+	anonymous := &GremlinTraversalAnonymous{}
+
+	for {
+		step, err := p.parserStep()
+		if err != nil {
+			return nil, err
+		}
+		anonymous.steps = append(anonymous.steps, step)
+
+		if tok, _ := p.scanIgnoreWhitespace(); tok != DOT {
+			p.unscan()
+			return anonymous, checkModulators(anonymous.steps)
+		}
+	}
+}
+
+// checkModulators verifies the By steps are supported by the steps they
+// modulate, GroupCount only counting by key
+func checkModulators(steps []GremlinTraversalStep) error {
+	for i := 1; i < len(steps); i++ {
+		by, ok := steps[i].(*GremlinTraversalStepBy)
+		if !ok {
+			continue
+		}
+
+		if _, ok := steps[i-1].(*GremlinTraversalStepGroupCount); ok {
+			if _, ok := by.Params[0].(string); !ok {
+				return fmt.Errorf("GroupCount can only be modulated by a string key : %v", by.Params)
+			}
+		}
+	}
+	return nil
+}
+
 func (p *GremlinTraversalParser) parserStep() (GremlinTraversalStep, error) {
 	tok, lit := p.scanIgnoreWhitespace()
 	if tok == IDENT {
@@ -1140,8 +1740,8 @@ func (p *GremlinTraversalParser) parserStep() (GremlinTraversalStep, error) {
 			return nil, fmt.Errorf("HasKey accepts only one parameter of type string : %v", params)
 		}
 	case SHORTESTPATHTO:
-		if len(params) == 0 || len(params) > 2 {
-			return nil, fmt.Errorf("ShortestPathTo predicate accepts only 1 or 2 parameters : %v", params)
+		if _, _, _, _, err := shortestPathParams(params); err != nil {
+			return nil, err
 		}
 		return &GremlinTraversalStepShortestPathTo{gremlinStepContext}, nil
 	case BOTH:
@@ -1211,6 +1811,139 @@ func (p *GremlinTraversalParser) parserStep() (GremlinTraversalStep, error) {
 		}
 
 		return &GremlinTraversalStepSelect{gremlinStepContext}, nil
+	case REPEAT:
+		if len(params) != 1 {
+			return nil, fmt.Errorf("Repeat requires 1 parameter : %v", params)
+		}
+		if _, ok := params[0].(*GremlinTraversalAnonymous); !ok {
+			return nil, fmt.Errorf("Repeat parameter has to be a traversal : %v", params)
+		}
+		return &GremlinTraversalStepRepeat{GremlinTraversalContext: gremlinStepContext}, nil
+	case TIMES:
+		if len(params) != 1 {
+			return nil, fmt.Errorf("Times requires 1 parameter : %v", params)
+		}
+		if times, ok := params[0].(int64); !ok || times < 0 {
+			return nil, fmt.Errorf("Times parameter has to be a positive integer : %v", params)
+		}
+		return &GremlinTraversalStepTimes{GremlinTraversalContext: gremlinStepContext}, nil
+	case UNTIL:
+		if len(params) != 1 {
+			return nil, fmt.Errorf("Until requires 1 parameter : %v", params)
+		}
+		if _, ok := params[0].(*GremlinTraversalAnonymous); !ok {
+			return nil, fmt.Errorf("Until parameter has to be a traversal : %v", params)
+		}
+		return &GremlinTraversalStepUntil{GremlinTraversalContext: gremlinStepContext}, nil
+	case EMIT:
+		if len(params) != 0 {
+			return nil, fmt.Errorf("Emit accepts no parameter : %v", params)
+		}
+		return &GremlinTraversalStepEmit{GremlinTraversalContext: gremlinStepContext}, nil
+	case GROUP:
+		if len(params) != 0 {
+			return nil, fmt.Errorf("Group accepts no parameter, use By steps : %v", params)
+		}
+		return &GremlinTraversalStepGroup{GremlinTraversalContext: gremlinStepContext}, nil
+	case GROUPCOUNT:
+		switch len(params) {
+		case 0:
+		case 1:
+			if _, ok := params[0].(string); !ok {
+				return nil, fmt.Errorf("GroupCount parameter has to be a string key : %v", params)
+			}
+		default:
+			return nil, fmt.Errorf("GroupCount accepts at most one parameter : %v", params)
+		}
+		return &GremlinTraversalStepGroupCount{GremlinTraversalContext: gremlinStepContext}, nil
+	case VALUEMAP:
+		if len(params) == 0 {
+			return nil, fmt.Errorf("ValueMap requires at least one key : %v", params)
+		}
+		for _, param := range params {
+			if _, ok := param.(string); !ok {
+				return nil, fmt.Errorf("ValueMap keys have to be strings : %v", params)
+			}
+		}
+		return &GremlinTraversalStepValueMap{GremlinTraversalContext: gremlinStepContext}, nil
+	case PROJECT:
+		if len(params) == 0 {
+			return nil, fmt.Errorf("Project requires at least one label : %v", params)
+		}
+		for _, param := range params {
+			if _, ok := param.(string); !ok {
+				return nil, fmt.Errorf("Project labels have to be strings : %v", params)
+			}
+		}
+		return &GremlinTraversalStepProject{GremlinTraversalContext: gremlinStepContext}, nil
+	case BY:
+		if len(params) != 1 {
+			return nil, fmt.Errorf("By requires 1 parameter : %v", params)
+		}
+		switch params[0].(type) {
+		case string, *GremlinTraversalAnonymous:
+		default:
+			return nil, fmt.Errorf("By parameter has to be a string key or a traversal : %v", params)
+		}
+		return &GremlinTraversalStepBy{gremlinStepContext}, nil
+	case WHERE, NOT:
+		if len(params) != 1 {
+			return nil, fmt.Errorf("%s requires 1 parameter : %v", lit, params)
+		}
+		if _, ok := params[0].(*GremlinTraversalAnonymous); !ok {
+			return nil, fmt.Errorf("%s parameter has to be a traversal : %v", lit, params)
+		}
+		if tok == WHERE {
+			return &GremlinTraversalStepWhere{gremlinStepContext}, nil
+		}
+		return &GremlinTraversalStepNot{gremlinStepContext}, nil
+	case AND, OR:
+		if len(params) == 0 {
+			return nil, fmt.Errorf("%s requires at least 1 parameter : %v", lit, params)
+		}
+		for _, param := range params {
+			if _, ok := param.(*GremlinTraversalAnonymous); !ok {
+				return nil, fmt.Errorf("%s parameters have to be traversals : %v", lit, params)
+			}
+		}
+		if tok == AND {
+			return &GremlinTraversalStepAnd{gremlinStepContext}, nil
+		}
+		return &GremlinTraversalStepOr{gremlinStepContext}, nil
+	case PATH:
+		if len(params) != 0 {
+			return nil, fmt.Errorf("Path accepts no parameter : %v", params)
+		}
+		return &GremlinTraversalStepPath{gremlinStepContext}, nil
+	case ALLPATHSTO, KSHORTESTPATHSTO:
+		if len(params) < 2 || len(params) > 3 {
+			return nil, fmt.Errorf("%s accepts only 2 or 3 parameters : %v", lit, params)
+		}
+		if _, ok := params[0].(graph.Metadata); !ok {
+			return nil, fmt.Errorf("%s first parameter has to be a metadata : %v", lit, params)
+		}
+		if _, ok := params[1].(int64); !ok {
+			return nil, fmt.Errorf("%s second parameter has to be an integer : %v", lit, params)
+		}
+		if len(params) > 2 {
+			if _, ok := params[2].(graph.Metadata); !ok {
+				return nil, fmt.Errorf("%s edge parameter has to be a metadata : %v", lit, params)
+			}
+		}
+		if tok == ALLPATHSTO {
+			return &GremlinTraversalStepAllPathsTo{gremlinStepContext}, nil
+		}
+		return &GremlinTraversalStepKShortestPathsTo{gremlinStepContext}, nil
+	case EXPLAIN:
+		if len(params) != 0 {
+			return nil, fmt.Errorf("Explain accepts no parameter : %v", params)
+		}
+		return &GremlinTraversalStepExplain{gremlinStepContext}, nil
+	case PROFILE:
+		if len(params) != 0 {
+			return nil, fmt.Errorf("Profile accepts no parameter : %v", params)
+		}
+		return &GremlinTraversalStepProfile{gremlinStepContext}, nil
 	}
 
 	// extensions
@@ -1260,6 +1993,31 @@ func (p *GremlinTraversalParser) Parse(r io.Reader) (*GremlinTraversalSequence,
 		seq.steps = append(seq.steps, step)
 	}
 
+	if err := checkModulators(seq.steps); err != nil {
+		return nil, err
+	}
+
+	return seq, nil
+}
+
+// ParseQuery parses the query, the sequences being cached by query text.
+// As the sequences are not modified by their execution, a cached sequence can
+// be executed several times, with different bindings. Once maxCachedQueries
+// queries are cached, the other ones are parsed at each call until entries
+// expire.
+func (p *GremlinTraversalParser) ParseQuery(query string) (*GremlinTraversalSequence, error) {
+	if seq, found := p.cache.Get(query); found {
+		return seq.(*GremlinTraversalSequence), nil
+	}
+
+	seq, err := p.Parse(strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cache.ItemCount() < maxCachedQueries {
+		p.cache.Set(query, seq, cache.DefaultExpiration)
+	}
 	return seq, nil
 }
 
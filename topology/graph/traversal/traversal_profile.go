@@ -0,0 +1,169 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package traversal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// StepPlan describes a step as executed, after the Reduce optimisations,
+// along with the steps it absorbed
+type StepPlan struct {
+	Step    string
+	Reduced []string `json:",omitempty"`
+}
+
+// StepProfile describes the execution of a step, the number of elements it
+// received and returned, its duration in nanoseconds and the component the
+// step was delegated to if any
+type StepProfile struct {
+	StepPlan
+	In       int
+	Out      int
+	Duration time.Duration
+	PushDown string `json:",omitempty"`
+}
+
+// reducedStep is a step along with the following steps it absorbed
+type reducedStep struct {
+	step    GremlinTraversalStep
+	reduced []GremlinTraversalStep
+}
+
+// reduceSteps applies the Reduce optimisations, each step being given the
+// opportunity to absorb the steps following it
+func reduceSteps(steps []GremlinTraversalStep) (reduced []reducedStep) {
+	for i := 0; i < len(steps); {
+		rs := reducedStep{step: steps[i]}
+
+		for i = i + 1; i < len(steps); i = i + 1 {
+			if next := rs.step.Reduce(steps[i]); next != rs.step {
+				break
+			}
+			rs.reduced = append(rs.reduced, steps[i])
+		}
+
+		reduced = append(reduced, rs)
+	}
+	return
+}
+
+// stepName returns the name of a step based on its type name, without the
+// GremlinTraversalStep prefix or suffix of the core and extension steps
+func stepName(step GremlinTraversalStep) string {
+	t := reflect.TypeOf(step)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := strings.TrimPrefix(t.Name(), "GremlinTraversalStep")
+	return strings.TrimSuffix(name, "GremlinTraversalStep")
+}
+
+func paramString(param interface{}) string {
+	switch p := param.(type) {
+	case string:
+		return fmt.Sprintf("%q", p)
+	case *GremlinTraversalAnonymous:
+		return p.String()
+	default:
+		return fmt.Sprintf("%v", p)
+	}
+}
+
+// stepString returns the string representation of a step and its parameters
+func stepString(step GremlinTraversalStep) string {
+	var params []string
+	if ctx := step.Context(); ctx != nil {
+		for _, param := range ctx.Params {
+			params = append(params, paramString(param))
+		}
+	}
+	return stepName(step) + "(" + strings.Join(params, ", ") + ")"
+}
+
+// String returns the steps of the anonymous traversal
+func (a *GremlinTraversalAnonymous) String() string {
+	steps := make([]string, len(a.steps))
+	for i, step := range a.steps {
+		steps[i] = stepString(step)
+	}
+	return strings.Join(steps, ".")
+}
+
+func newStepPlan(rs reducedStep) StepPlan {
+	plan := StepPlan{Step: stepString(rs.step)}
+	for _, step := range rs.reduced {
+		plan.Reduced = append(plan.Reduced, stepString(step))
+	}
+	return plan
+}
+
+// elementCount returns the number of elements of a step result
+func elementCount(step GraphTraversalStep) int {
+	switch step.(type) {
+	case nil, *GraphTraversal:
+		return 0
+	}
+	return len(step.Values())
+}
+
+// explainSteps returns the plan of the steps without executing them
+func explainSteps(steps []GremlinTraversalStep) []interface{} {
+	plans := []interface{}{}
+	for _, rs := range reduceSteps(steps) {
+		plans = append(plans, newStepPlan(rs))
+	}
+	return plans
+}
+
+// profileSteps executes the steps and returns the profile of each of them
+func profileSteps(steps []GremlinTraversalStep, last GraphTraversalStep) ([]interface{}, error) {
+	profiles := []interface{}{}
+	for _, rs := range reduceSteps(steps) {
+		profile := StepProfile{StepPlan: newStepPlan(rs), In: elementCount(last)}
+
+		start := time.Now()
+
+		var err error
+		if last, err = rs.step.Exec(last); err != nil {
+			return nil, err
+		}
+
+		if err := last.Error(); err != nil {
+			return nil, err
+		}
+
+		profile.Duration = time.Since(start)
+		profile.Out = elementCount(last)
+
+		if pd, ok := rs.step.(PushDownStep); ok {
+			profile.PushDown = pd.PushDown()
+		}
+
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
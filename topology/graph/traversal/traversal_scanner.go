@@ -41,6 +41,7 @@ const (
 
 	// Literals
 	IDENT
+	BINDING
 
 	// Misc characters
 	COMMA
@@ -91,11 +92,30 @@ const (
 	ASC
 	DESC
 	IPV4RANGE
+	IPV6RANGE
 	SUBGRAPH
 	FOREVER
 	NOW
 	AS
 	SELECT
+	REPEAT
+	TIMES
+	UNTIL
+	EMIT
+	GROUP
+	GROUPCOUNT
+	VALUEMAP
+	PROJECT
+	BY
+	WHERE
+	AND
+	OR
+	NOT
+	PATH
+	ALLPATHSTO
+	KSHORTESTPATHSTO
+	EXPLAIN
+	PROFILE
 
 	TRUE
 	FALSE
@@ -131,6 +151,8 @@ func (s *GremlinTraversalScanner) Scan() (tok Token, lit string) {
 	} else if isLetter(ch) {
 		s.unread()
 		return s.scanIdent()
+	} else if ch == '$' {
+		return s.scanBinding()
 	}
 
 	switch ch {
@@ -198,6 +220,27 @@ func (s *GremlinTraversalScanner) scanString() (tok Token, lit string) {
 	return STRING, buf.String()
 }
 
+func (s *GremlinTraversalScanner) scanBinding() (tok Token, lit string) {
+	var buf bytes.Buffer
+
+	for {
+		if ch := s.read(); ch == eof {
+			break
+		} else if !isLetter(ch) && !isDigit(ch) && ch != '_' {
+			s.unread()
+			break
+		} else {
+			_, _ = buf.WriteRune(ch)
+		}
+	}
+
+	if buf.Len() == 0 {
+		return ILLEGAL, "$"
+	}
+
+	return BINDING, buf.String()
+}
+
 func (s *GremlinTraversalScanner) scanIdent() (tok Token, lit string) {
 	var buf bytes.Buffer
 	buf.WriteRune(s.read())
@@ -294,6 +337,8 @@ func (s *GremlinTraversalScanner) scanIdent() (tok Token, lit string) {
 		return DESC, buf.String()
 	case "IPV4RANGE":
 		return IPV4RANGE, buf.String()
+	case "IPV6RANGE":
+		return IPV6RANGE, buf.String()
 	case "SUBGRAPH":
 		return SUBGRAPH, buf.String()
 	case "FOREVER":
@@ -304,6 +349,42 @@ func (s *GremlinTraversalScanner) scanIdent() (tok Token, lit string) {
 		return AS, buf.String()
 	case "SELECT":
 		return SELECT, buf.String()
+	case "REPEAT":
+		return REPEAT, buf.String()
+	case "TIMES":
+		return TIMES, buf.String()
+	case "UNTIL":
+		return UNTIL, buf.String()
+	case "EMIT":
+		return EMIT, buf.String()
+	case "GROUP":
+		return GROUP, buf.String()
+	case "GROUPCOUNT":
+		return GROUPCOUNT, buf.String()
+	case "VALUEMAP":
+		return VALUEMAP, buf.String()
+	case "PROJECT":
+		return PROJECT, buf.String()
+	case "BY":
+		return BY, buf.String()
+	case "WHERE":
+		return WHERE, buf.String()
+	case "AND":
+		return AND, buf.String()
+	case "OR":
+		return OR, buf.String()
+	case "NOT":
+		return NOT, buf.String()
+	case "PATH":
+		return PATH, buf.String()
+	case "ALLPATHSTO":
+		return ALLPATHSTO, buf.String()
+	case "KSHORTESTPATHSTO":
+		return KSHORTESTPATHSTO, buf.String()
+	case "EXPLAIN":
+		return EXPLAIN, buf.String()
+	case "PROFILE":
+		return PROFILE, buf.String()
 	case "TRUE":
 		return TRUE, buf.String()
 	case "FALSE":
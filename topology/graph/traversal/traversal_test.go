@@ -23,6 +23,10 @@
 package traversal
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -381,103 +385,1107 @@ func TestTraversalIpv4Range(t *testing.T) {
 	}
 }
 
+func TestTraversalIpv6Range(t *testing.T) {
+	g := newGraph(t)
+
+	g.NewNode(graph.GenID(), graph.Metadata{"Value": int64(1), "IPV6": []string{"fe80::1/64", "2001:db8:0:1::1/64"}})
+	g.NewNode(graph.GenID(), graph.Metadata{"Value": int64(2), "IPV6": "2001:db8::2"})
+	g.NewNode(graph.GenID(), graph.Metadata{"Value": int64(3), "IPV4": "192.168.0.34/24"})
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().Has("IPV6", IPV6Range("2001:db8::/32"))
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("2001:db8:0:1::/64"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("fe80::/10"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("2001:db9::/32"))
+	if len(tv.Values()) != 0 {
+		t.Fatalf("Shouldn't return node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV4", IPV6Range("192.168.0.0/16"))
+	if tv.Error() == nil {
+		t.Fatalf("Should return an error as the range is not an IPv6 one")
+	}
+
+	// next traversal test
+	query := `G.V().Has("IPV6", IPV6RANGE("2001:db8::/32"))`
+	res := execTraversalQuery(t, g, query)
+	if len(res.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", res.Values())
+	}
+}
+
 func TestTraversalBoth(t *testing.T) {
 	g := newTransversalGraph(t)
 
-	tr := NewGraphTraversal(g, false)
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().Has("Value", 2).Both()
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+}
+
+func TestTraversalCount(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().Count()
+	if tv.Values()[0] != 4 {
+		t.Fatalf("Should return 4 nodes, returned: %v", tv.Values())
+	}
+}
+
+func TestTraversalShortestPathTo(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	tv := tr.V().Has("Value", int64(1)).ShortestPathTo(graph.Metadata{"Value": int64(3)}, nil)
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	}
+
+	path := tv.Values()[0].([]*graph.Node)
+	if len(path) != 2 {
+		t.Fatalf("Should return a path len of 2, returned: %v", len(path))
+	}
+
+	// next test
+	tv = tr.V().Has("Value", Within(int64(1), int64(2))).ShortestPathTo(graph.Metadata{"Value": int64(3)}, nil)
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 paths, returned: %v", tv.Values())
+	}
+
+	path = tv.Values()[0].([]*graph.Node)
+	if len(path) != 2 {
+		t.Fatalf("Should return a path len of 2, returned: %v", len(path))
+	}
+
+	// next test
+	tv = tr.V().Has("Value", int64(1)).ShortestPathTo(graph.Metadata{"Value": int64(3)}, graph.Metadata{"Direction": "Left"})
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	}
+
+	path = tv.Values()[0].([]*graph.Node)
+	if len(path) != 3 {
+		t.Fatalf("Should return a path len of 3, returned: %v", len(path))
+	}
+
+	// next test, node 1 has the lowest Bytes and is avoided
+	tv = tr.V().Has("Value", int64(2)).WeightedShortestPathTo(graph.Metadata{"Value": int64(4)}, nil, "Bytes", graph.MinWeight)
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	}
+
+	path = tv.Values()[0].([]*graph.Node)
+	if v, _ := path[1].GetFieldInt64("Value"); len(path) != 3 || v != 3 {
+		t.Fatalf("Should return a path going through node 3, returned: %v", path)
+	}
+
+	// next test
+	tv = tr.V().Has("Value", int64(2)).WeightedShortestPathTo(graph.Metadata{"Value": int64(4)}, nil, "Bytes", graph.SumWeight)
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	}
+
+	path = tv.Values()[0].([]*graph.Node)
+	if v, _ := path[1].GetFieldInt64("Value"); len(path) != 3 || v != 3 {
+		t.Fatalf("Should return a path going through node 3, returned: %v", path)
+	}
+}
+
+func TestTraversalAllPathsTo(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	tv := tr.V().Has("Value", int64(1)).AllPathsTo(graph.Metadata{"Value": int64(3)}, 0, nil)
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 paths, returned: %v", tv.Values())
+	}
+
+	path := tv.Values()[0].([]*graph.Node)
+	if len(path) != 2 {
+		t.Fatalf("Should return a path len of 2, returned: %v", len(path))
+	}
+
+	// next test
+	tv = tr.V().Has("Value", int64(1)).AllPathsTo(graph.Metadata{"Value": int64(3)}, 1, nil)
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("Value", int64(1)).AllPathsTo(graph.Metadata{"Value": int64(3)}, 0, graph.Metadata{"Direction": "Left"})
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	}
+
+	path = tv.Values()[0].([]*graph.Node)
+	if len(path) != 3 {
+		t.Fatalf("Should return a path len of 3, returned: %v", len(path))
+	}
+
+	// next test
+	tv = tr.V().Has("Value", int64(1)).KShortestPathsTo(graph.Metadata{"Value": int64(3)}, 2, nil)
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 paths, returned: %v", tv.Values())
+	}
+
+	path = tv.Values()[1].([]*graph.Node)
+	if len(path) != 3 {
+		t.Fatalf("Should return a path len of 3, returned: %v", len(path))
+	}
+}
+
+func TestTraversalBothV(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.E().Has("Name", "e3").BothV()
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+}
+
+func TestTraversalSubGraph(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	tv := tr.E().Has("Direction", "Left").SubGraph().V()
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", tv.Values())
+	}
+
+	te := tr.E().Has("Direction", "Left").SubGraph().E()
+	if len(te.Values()) != 2 {
+		t.Fatalf("Should return 2 edges, returned: %v", te.Values())
+	}
+
+	tv = tr.V().Has("Type", "intf").SubGraph().V()
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+
+	te = tr.V().Has("Type", "intf").SubGraph().E()
+	if len(te.Values()) != 1 {
+		t.Fatalf("Should return 1 edge, returned: %v", te.Values())
+	}
+}
+
+// newLayer2Graph creates a graph with a layer2 loop
+func newLayer2Graph(t *testing.T) *graph.Graph {
+	g := newGraph(t)
+
+	n1 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "eth0", "Type": "veth"})
+	n2 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "br0", "Type": "bridge"})
+	n3 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "eth1", "Type": "device"})
+	n4 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "tap0", "Type": "tap"})
+	n5 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "ns1", "Type": "netns"})
+
+	g.Link(n1, n2, graph.Metadata{"RelationType": "layer2"})
+	g.Link(n2, n4, graph.Metadata{"RelationType": "layer2"})
+	g.Link(n4, n1, graph.Metadata{"RelationType": "layer2"})
+	g.Link(n2, n3, graph.Metadata{"RelationType": "layer2"})
+	g.Link(n5, n1, graph.Metadata{"RelationType": "ownership"})
+
+	return g
+}
+
+func TestTraversalRepeat(t *testing.T) {
+	g := newLayer2Graph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	both := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalV).Both(), nil
+	})
+	device := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalV).Has("Type", "device"), nil
+	})
+
+	// next test
+	tv := tr.V().Has("Name", "eth0").Repeat(both, RepeatOptions{Until: device})
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	if name, _ := tv.Values()[0].(*graph.Node).GetFieldString("Name"); name != "eth1" {
+		t.Fatalf("Should return eth1, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("Name", "eth0").Repeat(both, RepeatOptions{Times: 1})
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", tv.Values())
+	}
+
+	// next test, the loop has to end even without Times or Until
+	tv = tr.V().Has("Name", "eth0").Repeat(both, RepeatOptions{Emit: true, EmitFirst: true})
+	if len(tv.Values()) != 5 {
+		t.Fatalf("Should return 5 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("Name", "eth0").Repeat(both, RepeatOptions{Times: 1, Emit: true})
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("Name", "eth0").Repeat(both, RepeatOptions{Times: -1})
+	if tv.Error() == nil {
+		t.Fatal("Should return an error")
+	}
+}
+
+func TestTraversalRepeatParser(t *testing.T) {
+	g := newLayer2Graph(t)
+
+	// next traversal test
+	query := `G.V().Has("Name", "eth0").Repeat(BothE("RelationType", "layer2").BothV()).Until(Has("Type", "device"))`
+	res := execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+
+	if name, _ := res.Values()[0].(*graph.Node).GetFieldString("Name"); name != "eth1" {
+		t.Fatalf("Should return eth1, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Name", "eth0").Repeat(OutE().Has("RelationType", "layer2").OutV()).Times(2)`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Name", "eth0").Repeat(BothE("RelationType", "layer2").BothV()).Emit()`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Name", "eth0").Emit().Repeat(BothE("RelationType", "layer2").BothV()).Times(1)`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Name", "eth0").Until(Has("Type", "veth")).Repeat(Both())`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Name", "eth0").Repeat(Both()).Until(Has("Type", "device")).Count()`
+	res = execTraversalQuery(t, g, query)
+	if res.Values()[0] != 1 {
+		t.Fatalf("Should return 1, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	for _, query = range []string{
+		`G.V().Repeat(Both(), Both())`,
+		`G.V().Repeat("Name")`,
+		`G.V().Repeat(Both()).Times("2")`,
+		`G.V().Repeat(Both()).Until(Has("Type", "device"), 1)`,
+		`G.V().Repeat(Both()).Emit(Has("Type", "device"))`,
+	} {
+		if _, err := NewGremlinTraversalParser().Parse(strings.NewReader(query)); err == nil {
+			t.Fatalf("%s: should return a parsing error", query)
+		}
+	}
+
+	// next traversal test
+	ts, err := NewGremlinTraversalParser().Parse(strings.NewReader(`G.V().Times(2)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ts.Exec(g, false); err == nil {
+		t.Fatal("Times without Repeat should return an error")
+	}
+}
+
+func TestTraversalGroup(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().GroupCount("Type")
+	if !reflect.DeepEqual(tv.Values(), []interface{}{map[string]int{"intf": 2}}) {
+		t.Fatalf("Should return 2 intf, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.E().GroupCount("Direction")
+	if !reflect.DeepEqual(tv.Values(), []interface{}{map[string]int{"Left": 2}}) {
+		t.Fatalf("Should return 2 Left, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("Value", Within(1, 2)).Sort(common.SortAscending, "Value").Group("Type", "Value")
+	if !reflect.DeepEqual(tv.Values(), []interface{}{map[string]interface{}{"intf": []interface{}{int64(1), int64(2)}}}) {
+		t.Fatalf("Should return intf values, returned: %v", tv.Values())
+	}
+
+	// next test
+	count := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalE).Count(), nil
+	})
+	tv = tr.E().Group("Direction", count)
+	if !reflect.DeepEqual(tv.Values(), []interface{}{map[string]interface{}{"Left": 2}}) {
+		t.Fatalf("Should return 2 Left, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Group()
+	if tv.Error() == nil {
+		t.Fatal("Should return an error")
+	}
+}
+
+func TestTraversalGroupParser(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	// next traversal test
+	query := `G.V().GroupCount("Type")`
+	res := execTraversalQuery(t, g, query)
+	if !reflect.DeepEqual(res.Values(), []interface{}{map[string]int{"intf": 2}}) {
+		t.Fatalf("Should return 2 intf, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().GroupCount().By("Value")`
+	res = execTraversalQuery(t, g, query)
+	if !reflect.DeepEqual(res.Values(), []interface{}{map[string]int{"1": 1, "2": 1, "3": 1, "4": 1}}) {
+		t.Fatalf("Should return 1 node per value, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Group().By("Type").By(Count())`
+	res = execTraversalQuery(t, g, query)
+	if !reflect.DeepEqual(res.Values(), []interface{}{map[string]interface{}{"intf": 2}}) {
+		t.Fatalf("Should return 2 intf, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Group().By("Type").By(Sum("Bytes"))`
+	res = execTraversalQuery(t, g, query)
+	if !reflect.DeepEqual(res.Values(), []interface{}{map[string]interface{}{"intf": float64(3048)}}) {
+		t.Fatalf("Should return the sum of intf bytes, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.E().Group().By("Direction")`
+	res = execTraversalQuery(t, g, query)
+	groups := res.Values()[0].(map[string]interface{})
+	if len(groups) != 1 || len(groups["Left"].([]interface{})) != 2 {
+		t.Fatalf("Should return 2 Left edges, returned: %v", res.Values())
+	}
+
+	b, err := res.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string][]map[string]interface{}
+	if err = json.Unmarshal(b, &decoded); err != nil || len(decoded["Left"]) != 2 {
+		t.Fatalf("Should marshal 2 Left edges, returned: %s", string(b))
+	}
+
+	// next traversal test
+	for _, query = range []string{
+		`G.V().Group("Type")`,
+		`G.V().GroupCount(1)`,
+		`G.V().Group().By(1)`,
+		`G.V().GroupCount().By(Out())`,
+	} {
+		if _, err := NewGremlinTraversalParser().Parse(strings.NewReader(query)); err == nil {
+			t.Fatalf("%s: should return a parsing error", query)
+		}
+	}
+
+	// next traversal test
+	ts, err := NewGremlinTraversalParser().Parse(strings.NewReader(`G.V().By("Type")`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ts.Exec(g, false); err == nil {
+		t.Fatal("By without Group should return an error")
+	}
+}
+
+func TestTraversalValueMap(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().Has("Value", Within(1, 4)).Sort(common.SortAscending, "Value").ValueMap("Value", "Name")
+	expected := []interface{}{
+		map[string]interface{}{"Value": int64(1)},
+		map[string]interface{}{"Value": int64(4), "Name": "Node4"},
+	}
+	if !reflect.DeepEqual(tv.Values(), expected) {
+		t.Fatalf("Should return 2 rows, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.E().Has("Name", "e1").Project("name", "Name", "dir", "Direction", "mode", "Mode")
+	if !reflect.DeepEqual(tv.Values(), []interface{}{map[string]interface{}{"name": "e1", "dir": "Left"}}) {
+		t.Fatalf("Should return 1 row, returned: %v", tv.Values())
+	}
+
+	// next test
+	count := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalV).Out().Count(), nil
+	})
+	tv = tr.V().Has("Value", 1).Project("value", "Value", "out", count)
+	if !reflect.DeepEqual(tv.Values(), []interface{}{map[string]interface{}{"value": int64(1), "out": 3}}) {
+		t.Fatalf("Should return 3 out nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	for _, tv = range []*GraphTraversalValue{
+		tr.V().ValueMap(),
+		tr.V().ValueMap(1),
+		tr.V().Project("value"),
+		tr.V().Project(1, "Value"),
+	} {
+		if tv.Error() == nil {
+			t.Fatal("Should return an error")
+		}
+	}
+}
+
+func TestTraversalProjectParser(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	// next traversal test
+	query := `G.V().Has("Value", 4).ValueMap("Name", "IPV4", "MTU")`
+	res := execTraversalQuery(t, g, query)
+	if !reflect.DeepEqual(res.Values(), []interface{}{map[string]interface{}{"Name": "Node4", "IPV4": "192.168.1.34"}}) {
+		t.Fatalf("Should return the name and IPV4 of Node4, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Value", 1).Project("value", "out").By("Value").By(Out().Count())`
+	res = execTraversalQuery(t, g, query)
+	if !reflect.DeepEqual(res.Values(), []interface{}{map[string]interface{}{"value": int64(1), "out": 3}}) {
+		t.Fatalf("Should return 3 out nodes, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Value", 2).Project("type", "Bytes").By("Type")`
+	res = execTraversalQuery(t, g, query)
+	if !reflect.DeepEqual(res.Values(), []interface{}{map[string]interface{}{"type": "intf", "Bytes": int64(2024)}}) {
+		t.Fatalf("Should use the Bytes key and the label as key, returned: %v", res.Values())
+	}
+
+	b, err := res.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []map[string]interface{}
+	if err = json.Unmarshal(b, &decoded); err != nil || len(decoded) != 1 || decoded[0]["type"] != "intf" {
+		t.Fatalf("Should marshal 1 row, returned: %s", string(b))
+	}
+
+	// next traversal test
+	for _, query = range []string{
+		`G.V().ValueMap()`,
+		`G.V().ValueMap(1)`,
+		`G.V().Project()`,
+		`G.V().Project(1)`,
+	} {
+		if _, err := NewGremlinTraversalParser().Parse(strings.NewReader(query)); err == nil {
+			t.Fatalf("%s: should return a parsing error", query)
+		}
+	}
+}
+
+func TestTraversalBoolean(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false)
+
+	intf := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalV).Has("Type", "intf"), nil
+	})
+	bytes := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalV).Has("Bytes", Gt(2000)), nil
+	})
+
+	// next test
+	tv := tr.V().Or(intf, bytes)
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().And(intf, bytes)
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Not(intf)
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Where(bytes)
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Or("Type")
+	if tv.Error() == nil {
+		t.Fatal("Should return an error")
+	}
+}
+
+func TestTraversalBooleanParser(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	// next traversal test
+	query := `G.V().Or(Has("Type", "intf"), Has("Bytes", Gt(2000)))`
+	res := execTraversalQuery(t, g, query)
+	if len(res.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().And(Has("Type", "intf"), Has("Bytes", Gt(2000)))`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+
+	// next traversal test, nodes without any outgoing edge
+	query = `G.V().Not(OutE())`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+
+	if name, _ := res.Values()[0].(*graph.Node).GetFieldString("Name"); name != "Node4" {
+		t.Fatalf("Should return Node4, returned: %v", res.Values())
+	}
+
+	// next traversal test, nodes having an outgoing edge to Node4
+	query = `G.V().Where(Out().Has("Name", "Node4"))`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.E().Or(Has("Direction", "Left"), Has("Mode", "Direct"))`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 3 {
+		t.Fatalf("Should return 3 edges, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.E().Not(InV().Has("Type", "intf"))`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 edge, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Not(Or(Has("Type", "intf"), Has("Name", "Node4")))`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	for _, query = range []string{
+		`G.V().Where(Out(), In())`,
+		`G.V().Not("Type")`,
+		`G.V().And()`,
+		`G.V().Or(Out(), "Type")`,
+	} {
+		if _, err := NewGremlinTraversalParser().Parse(strings.NewReader(query)); err == nil {
+			t.Fatalf("%s: should return a parsing error", query)
+		}
+	}
+}
+
+func TestTraversalPath(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	tr := NewGraphTraversal(g, false).TrackPaths()
 
-	// next test
-	tv := tr.V().Has("Value", 2).Both()
-	if len(tv.Values()) != 2 {
-		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	tv := tr.V().Has("Value", int64(1)).OutE().OutV().Path()
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 paths, returned: %v", tv.Values())
+	}
+
+	for _, value := range tv.Values() {
+		path := value.([]interface{})
+		if len(path) != 3 {
+			t.Fatalf("Should return a path len of 3, returned: %v", path)
+		}
+
+		if v, _ := path[0].(*graph.Node).GetFieldInt64("Value"); v != 1 {
+			t.Fatalf("Path should start with node 1, returned: %v", path)
+		}
+
+		if _, ok := path[1].(*graph.Edge); !ok {
+			t.Fatalf("Path should go through an edge, returned: %v", path)
+		}
+	}
+
+	// next test, paths not tracked
+	tr = NewGraphTraversal(g, false)
+
+	tv = tr.V().Has("Value", int64(1)).Out().Path()
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 paths, returned: %v", tv.Values())
+	}
+
+	if path := tv.Values()[0].([]interface{}); len(path) != 1 {
+		t.Fatalf("Should return a path len of 1, returned: %v", path)
 	}
 }
 
-func TestTraversalCount(t *testing.T) {
+func TestTraversalPathThroughSteps(t *testing.T) {
 	g := newTransversalGraph(t)
 
-	tr := NewGraphTraversal(g, false)
+	tr := NewGraphTraversal(g, false).TrackPaths()
+
+	checkPaths := func(values []interface{}, count int) {
+		if len(values) != count {
+			t.Fatalf("Should return %d paths, returned: %v", count, values)
+		}
+		for _, value := range values {
+			if path := value.([]interface{}); len(path) != 2 {
+				t.Fatalf("Should return a path len of 2, returned: %v", path)
+			}
+		}
+	}
 
 	// next test
-	tv := tr.V().Count()
-	if tv.Values()[0] != 4 {
-		t.Fatalf("Should return 4 nodes, returned: %v", tv.Values())
+	checkPaths(tr.V().Has("Value", int64(1)).Out().As("out").Select("out").Path().Values(), 3)
+
+	// next test
+	path := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalV).Path(), nil
+	})
+
+	tv := tr.V().Has("Value", int64(1)).Out().Group("Type", path)
+	groups := tv.Values()[0].(map[string]interface{})
+	checkPaths(groups["intf"].([]interface{}), 1)
+
+	// next test
+	tv = tr.V().Has("Value", int64(1)).Out().Project("path", path)
+	for _, row := range tv.Values() {
+		paths := row.(map[string]interface{})["path"].([]interface{})
+		if len(paths) != 1 || len(paths[0].([]interface{})) != 2 {
+			t.Fatalf("Should return a path len of 2, returned: %v", paths)
+		}
+	}
+
+	// next test
+	edgePath := AnonymousTraversalFunc(func(last GraphTraversalStep) (GraphTraversalStep, error) {
+		return last.(*GraphTraversalE).Path(), nil
+	})
+
+	tv = tr.V().Has("Value", int64(1)).OutE().Group("Direction", edgePath)
+	for _, value := range tv.Values()[0].(map[string]interface{})["Left"].([]interface{}) {
+		if path := value.([]interface{}); len(path) != 2 {
+			t.Fatalf("Should return a path len of 2, returned: %v", path)
+		}
 	}
 }
 
-func TestTraversalShortestPathTo(t *testing.T) {
+func TestTraversalPathParser(t *testing.T) {
 	g := newTransversalGraph(t)
 
-	tr := NewGraphTraversal(g, false)
+	// next traversal test
+	query := `G.V().Has("Value", 1).Out().Has("Value", 2).Out().Path()`
+	res := execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", res.Values())
+	}
 
-	tv := tr.V().Has("Value", int64(1)).ShortestPathTo(graph.Metadata{"Value": int64(3)}, nil)
-	if len(tv.Values()) != 1 {
-		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	path := res.Values()[0].([]interface{})
+	var values []int64
+	for _, n := range path {
+		v, _ := n.(*graph.Node).GetFieldInt64("Value")
+		values = append(values, v)
 	}
 
-	path := tv.Values()[0].([]*graph.Node)
-	if len(path) != 2 {
-		t.Fatalf("Should return a path len of 2, returned: %v", len(path))
+	if !reflect.DeepEqual(values, []int64{1, 2, 3}) {
+		t.Fatalf("Should return the path 1/2/3, returned: %v", values)
 	}
 
-	// next test
-	tv = tr.V().Has("Value", Within(int64(1), int64(2))).ShortestPathTo(graph.Metadata{"Value": int64(3)}, nil)
-	if len(tv.Values()) != 2 {
-		t.Fatalf("Should return 2 paths, returned: %v", tv.Values())
+	// next traversal test
+	query = `G.V().Has("Value", 1).Repeat(Out()).Until(Has("Value", 4)).Path()`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", res.Values())
 	}
 
-	path = tv.Values()[0].([]*graph.Node)
-	if len(path) != 2 {
-		t.Fatalf("Should return a path len of 2, returned: %v", len(path))
+	if path := res.Values()[0].([]interface{}); len(path) != 2 {
+		t.Fatalf("Should return a path len of 2, returned: %v", path)
 	}
 
-	// next test
-	tv = tr.V().Has("Value", int64(1)).ShortestPathTo(graph.Metadata{"Value": int64(3)}, graph.Metadata{"Direction": "Left"})
-	if len(tv.Values()) != 1 {
-		t.Fatalf("Should return 1 path, returned: %v", tv.Values())
+	// next traversal test
+	query = `G.V().Has("Value", 1).Out().Sort().Path()`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 3 {
+		t.Fatalf("Should return 3 paths, returned: %v", res.Values())
 	}
 
-	path = tv.Values()[0].([]*graph.Node)
-	if len(path) != 3 {
-		t.Fatalf("Should return a path len of 3, returned: %v", len(path))
+	for _, value := range res.Values() {
+		if path := value.([]interface{}); len(path) != 2 {
+			t.Fatalf("Should return a path len of 2, returned: %v", path)
+		}
+	}
+
+	// next traversal test
+	query = `G.V().Has("Value", 1).AllPathsTo(Metadata("Value", 3), 2)`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 3 {
+		t.Fatalf("Should return 3 paths, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Value", 1).KShortestPathsTo(Metadata("Value", 3), 1, Metadata("Direction", "Left"))`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 path, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	for _, query = range []string{
+		`G.V().Path("Value")`,
+		`G.V().AllPathsTo(Metadata("Value", 3))`,
+		`G.V().AllPathsTo(3, Metadata("Value", 3))`,
+		`G.V().KShortestPathsTo(Metadata("Value", 3), 2, "Left")`,
+	} {
+		if _, err := NewGremlinTraversalParser().Parse(strings.NewReader(query)); err == nil {
+			t.Fatalf("%s: should return a parsing error", query)
+		}
 	}
 }
 
-func TestTraversalBothV(t *testing.T) {
+func TestTraversalExplainParser(t *testing.T) {
 	g := newTransversalGraph(t)
 
-	tr := NewGraphTraversal(g, false)
+	// next traversal test
+	query := `G.V().Has("Type", "intf").Out().Range(0, 1).Explain()`
+	res := execTraversalQuery(t, g, query)
 
-	// next test
-	tv := tr.E().Has("Name", "e3").BothV()
-	if len(tv.Values()) != 2 {
-		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	var plans []StepPlan
+	for _, value := range res.Values() {
+		plans = append(plans, value.(StepPlan))
+	}
+
+	expected := []StepPlan{
+		{Step: `V("Type", "intf")`, Reduced: []string{`Has("Type", "intf")`}},
+		{Step: "Out()", Reduced: []string{"Range(0, 1)"}},
+	}
+	if !reflect.DeepEqual(plans, expected) {
+		t.Fatalf("Should return the plan %v, returned: %v", expected, plans)
+	}
+
+	// next traversal test
+	query = `G.V().Where(Out().Has("Name", "Node4")).Explain()`
+	res = execTraversalQuery(t, g, query)
+	if plan := res.Values()[1].(StepPlan); plan.Step != `Where(Out().Has("Name", "Node4"))` {
+		t.Fatalf("Should return the Where step, returned: %v", plan)
+	}
+
+	// next traversal test
+	query = `G.V().Has("Type", "intf").Out().Profile()`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 2 {
+		t.Fatalf("Should return 2 step profiles, returned: %v", res.Values())
+	}
+
+	profile := res.Values()[0].(StepProfile)
+	if profile.Step != `V("Type", "intf")` || profile.In != 0 || profile.Out != 2 || profile.PushDown != "" {
+		t.Fatalf("Wrong V step profile, returned: %+v", profile)
+	}
+
+	profile = res.Values()[1].(StepProfile)
+	if profile.Step != "Out()" || profile.In != 2 || profile.Out != 4 {
+		t.Fatalf("Wrong Out step profile, returned: %+v", profile)
+	}
+
+	if _, err := json.Marshal(res); err != nil {
+		t.Fatal(err)
+	}
+
+	// next traversal test
+	ts, err := NewGremlinTraversalParser().Parse(strings.NewReader(`G.V().Profile().Count()`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ts.Exec(g, false); err == nil {
+		t.Fatal("Profile not being the last step should return an error")
+	}
+
+	// next traversal test
+	if _, err := NewGremlinTraversalParser().Parse(strings.NewReader(`G.V().Explain(1)`)); err == nil {
+		t.Fatal("Explain with a parameter should return a parsing error")
 	}
 }
 
-func TestTraversalSubGraph(t *testing.T) {
+func TestTraversalAbort(t *testing.T) {
 	g := newTransversalGraph(t)
 
-	tr := NewGraphTraversal(g, false)
+	tr := NewGraphTraversal(g, false).WithContext(context.Background(), 3)
+	if tv := tr.V(); tv.Error() != ErrTooManyElements {
+		t.Fatalf("Should return ErrTooManyElements, got: %v", tv.Error())
+	}
 
-	tv := tr.E().Has("Direction", "Left").SubGraph().V()
-	if len(tv.Values()) != 3 {
-		t.Fatalf("Should return 3 nodes, returned: %v", tv.Values())
+	// next traversal test
+	tr = NewGraphTraversal(g, false).WithContext(context.Background(), 3)
+	if tv := tr.V("Value", 1).Out().Out(); tv.Error() != nil || len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, got: %v (%v)", tv.Values(), tv.Error())
 	}
 
-	te := tr.E().Has("Direction", "Left").SubGraph().E()
-	if len(te.Values()) != 2 {
-		t.Fatalf("Should return 2 edges, returned: %v", te.Values())
+	// next traversal test
+	tr = NewGraphTraversal(g, false).WithContext(context.Background(), 3)
+	if tv := tr.V("Value", 1).Out().Both(); tv.Error() != ErrTooManyElements {
+		t.Fatalf("Should return ErrTooManyElements, got: %v", tv.Error())
 	}
 
-	tv = tr.V().Has("Type", "intf").SubGraph().V()
-	if len(tv.Values()) != 2 {
-		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	// next traversal test
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	query := `G.V().Has("Value", 1).Out().Out()`
+	ts, err := NewGremlinTraversalParser().Parse(strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
 	}
 
-	te = tr.V().Has("Type", "intf").SubGraph().E()
-	if len(te.Values()) != 1 {
-		t.Fatalf("Should return 1 edge, returned: %v", te.Values())
+	if _, err := ts.ExecContext(ctx, g, false); err != ErrQueryCanceled {
+		t.Fatalf("Should return ErrQueryCanceled, got: %v", err)
+	}
+
+	if _, err := ts.ExecContext(context.Background(), g, false); err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	// next traversal test, the path lookups being aborted too
+	tr = NewGraphTraversal(g, false)
+	tv := tr.V().Has("Value", 1)
+	tr.WithContext(ctx, 0)
+
+	if sp := tv.AllPathsTo(graph.Metadata{"Value": int64(4)}, 0, nil); sp.Error() != ErrQueryCanceled {
+		t.Fatalf("Should return ErrQueryCanceled, got: %v", sp.Error())
+	}
+
+	if sp := tv.KShortestPathsTo(graph.Metadata{"Value": int64(4)}, 3, nil); sp.Error() != ErrQueryCanceled {
+		t.Fatalf("Should return ErrQueryCanceled, got: %v", sp.Error())
+	}
+
+	if sp := tv.ShortestPathTo(graph.Metadata{"Value": int64(4)}, nil); sp.Error() != ErrQueryCanceled {
+		t.Fatalf("Should return ErrQueryCanceled, got: %v", sp.Error())
+	}
+}
+
+func TestTraversalBindings(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	p := NewGremlinTraversalParser()
+
+	query := `G.V().Has("Value", $value).Out().Has("Value", Within($values))`
+	ts, err := p.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	if _, err := ts.Exec(g, false); err == nil {
+		t.Fatalf("Should return an error as the bindings have no value")
+	}
+
+	// next test, with values as decoded from JSON
+	bts, err := ts.Bind(Bindings{"value": float64(1), "values": []interface{}{float64(2), float64(3)}})
+	if err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	res, err := bts.Exec(g, false)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	if len(res.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", res.Values())
+	}
+
+	// next test, the cached sequence being executed with other bindings
+	if cached, err := p.ParseQuery(query); err != nil || cached != ts {
+		t.Fatalf("Should return the cached sequence, got: %v", err)
+	}
+
+	if bts, err = ts.Bind(Bindings{"value": 2, "values": []string{"3"}}); err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	res, err = bts.Exec(g, false)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	if len(res.Values()) != 0 {
+		t.Fatalf("Should return no node, returned: %v", res.Values())
+	}
+
+	// next test, a value containing quotes
+	query = `G.V().Has("Name", $name).Count()`
+	if ts, err = p.ParseQuery(query); err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	if bts, err = ts.Bind(Bindings{"name": `Node4") .V(`}); err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	res, err = bts.Exec(g, false)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	if res.Values()[0] != 0 {
+		t.Fatalf("Should return 0, returned: %v", res.Values())
+	}
+
+	// next test, the number of cached queries is bounded
+	for i := 0; i <= maxCachedQueries; i++ {
+		query = fmt.Sprintf(`G.V().Has("Value", %d)`, i)
+		if _, err = p.ParseQuery(query); err != nil {
+			t.Fatalf("%s: %s", query, err.Error())
+		}
+	}
+
+	if n := p.cache.ItemCount(); n != maxCachedQueries {
+		t.Fatalf("Should cache %d queries, got %d", maxCachedQueries, n)
+	}
+}
+
+// valuesTestStep is an extension step returning its parameters, merged
+// with the ones of the following Has steps
+type valuesTestStep struct {
+	context GremlinTraversalContext
+	values  []interface{}
+}
+
+type valuesTestExtension struct{}
+
+func (e *valuesTestExtension) ScanIdent(s string) (Token, bool) {
+	if s == "VALUES_TEST" {
+		return Token(1000), true
+	}
+	return IDENT, false
+}
+
+func (e *valuesTestExtension) ParseStep(t Token, p GremlinTraversalContext) (GremlinTraversalStep, error) {
+	if t == Token(1000) {
+		return &valuesTestStep{context: p, values: p.Params}, nil
+	}
+	return nil, nil
+}
+
+func (s *valuesTestStep) Exec(last GraphTraversalStep) (GraphTraversalStep, error) {
+	return NewGraphTraversalValue(last.(*GraphTraversalV).GraphTraversal, s.values), nil
+}
+
+func (s *valuesTestStep) Reduce(next GremlinTraversalStep) GremlinTraversalStep {
+	if has, ok := next.(*GremlinTraversalStepHas); ok {
+		s.values = append(s.values, has.Params...)
+		return s
+	}
+	return next
+}
+
+func (s *valuesTestStep) Context() *GremlinTraversalContext {
+	return &s.context
+}
+
+func (s *valuesTestStep) Bind(bindings Bindings) (GremlinTraversalStep, error) {
+	context, err := bindings.BindContext(s.context)
+	if err != nil {
+		return nil, err
+	}
+	return &valuesTestStep{context: context, values: context.Params}, nil
+}
+
+func TestTraversalExtensionBindings(t *testing.T) {
+	g := newTransversalGraph(t)
+
+	p := NewGremlinTraversalParser()
+	p.AddTraversalExtension(&valuesTestExtension{})
+
+	query := `G.V().VALUES_TEST("Value", $value).Has("Name", "Node1")`
+	ts, err := p.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err.Error())
+	}
+
+	// the cached sequence is executed several times, the reduced Has step
+	// must not accumulate
+	for i := 0; i < 2; i++ {
+		bts, err := ts.Bind(Bindings{"value": i})
+		if err != nil {
+			t.Fatalf("%s: %s", query, err.Error())
+		}
+
+		res, err := bts.Exec(g, false)
+		if err != nil {
+			t.Fatalf("%s: %s", query, err.Error())
+		}
+
+		expected := []interface{}{"Value", int64(i), "Name", "Node1"}
+		if values := res.Values(); !reflect.DeepEqual(values, expected) {
+			t.Fatalf("Should return %v, returned: %v", expected, values)
+		}
 	}
 }
 
@@ -594,6 +1602,31 @@ func TestTraversalParser(t *testing.T) {
 		t.Fatalf("Should return 1 path, returned: %v", res.Values())
 	}
 
+	// next traversal test
+	query = `G.V().Has("Value", 2).ShortestPathTo(Metadata("Value", 4), "Bytes", "min")`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 || len(res.Values()[0].([]*graph.Node)) != 3 {
+		t.Fatalf("Should return 1 path of len 3, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	query = `G.V().Has("Value", 1).ShortestPathTo(Metadata("Value", 3), Metadata("Direction", "Left"), "Bytes")`
+	res = execTraversalQuery(t, g, query)
+	if len(res.Values()) != 1 || len(res.Values()[0].([]*graph.Node)) != 3 {
+		t.Fatalf("Should return 1 path of len 3, returned: %v", res.Values())
+	}
+
+	// next traversal test
+	for _, query = range []string{
+		`G.V().ShortestPathTo("Bytes")`,
+		`G.V().ShortestPathTo(Metadata("Value", 3), "Bytes", "max")`,
+		`G.V().ShortestPathTo(Metadata("Value", 3), "Bytes", "sum", "min")`,
+	} {
+		if _, err := NewGremlinTraversalParser().Parse(strings.NewReader(query)); err == nil {
+			t.Fatalf("%s: should return a parsing error", query)
+		}
+	}
+
 	// next traversal test
 	query = `G.V().Has("Type", Ne("intf"))`
 	res = execTraversalQuery(t, g, query)
@@ -23,6 +23,7 @@
 package topology
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -39,6 +40,8 @@ type topologySubscriber struct {
 	graph         *graph.Graph
 	gremlinFilter string
 	ts            *traversal.GremlinTraversalSequence
+	ctx           context.Context
+	cancel        context.CancelFunc
 }
 
 // TopologySubscriberEndpoint sends all the modifications to its subscribers.
@@ -52,9 +55,15 @@ type TopologySubscriberEndpoint struct {
 	subscribers   map[string]*topologySubscriber
 }
 
-func (t *TopologySubscriberEndpoint) getGraph(gremlinQuery string, ts *traversal.GremlinTraversalSequence, lockGraph bool) (*graph.Graph, error) {
-	res, err := ts.Exec(t.Graph, lockGraph)
-	if err != nil {
+// getGraph returns the graph of the filter of a subscriber, the query being
+// canceled with the given context when the subscriber disconnects
+func (t *TopologySubscriberEndpoint) getGraph(ctx context.Context, gremlinQuery string, ts *traversal.GremlinTraversalSequence, lockGraph bool) (*graph.Graph, error) {
+	res, err := ts.ExecContext(ctx, t.Graph, lockGraph)
+	switch err {
+	case nil:
+	case traversal.ErrQueryTimeout, traversal.ErrQueryCanceled, traversal.ErrTooManyElements:
+		return nil, fmt.Errorf("Gremlin query '%s' aborted: %s", gremlinQuery, err)
+	default:
 		return nil, err
 	}
 
@@ -72,12 +81,26 @@ func (t *TopologySubscriberEndpoint) newTopologySubscriber(host string, gremlinF
 		return nil, fmt.Errorf("Invalid Gremlin filter '%s' for client %s", gremlinFilter, host)
 	}
 
-	g, err := t.getGraph(gremlinFilter, ts, lockGraph)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	g, err := t.getGraph(ctx, gremlinFilter, ts, lockGraph)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return &topologySubscriber{graph: g, ts: ts, gremlinFilter: gremlinFilter}, nil
+	return &topologySubscriber{graph: g, ts: ts, gremlinFilter: gremlinFilter, ctx: ctx, cancel: cancel}, nil
+}
+
+// addSubscriber registers the subscriber of the host, canceling the queries
+// of the one it replaces
+func (t *TopologySubscriberEndpoint) addSubscriber(host string, subscriber *topologySubscriber) {
+	t.Lock()
+	if previous, found := t.subscribers[host]; found {
+		previous.cancel()
+	}
+	t.subscribers[host] = subscriber
+	t.Unlock()
 }
 
 // OnConnected called when a subscriber got connected.
@@ -97,14 +120,20 @@ func (t *TopologySubscriberEndpoint) OnConnected(c shttp.WSSpeaker) {
 		}
 
 		logging.GetLogger().Infof("Client %s subscribed with filter %s", host, gremlinFilter)
-		t.subscribers[host] = subscriber
+		t.addSubscriber(host, subscriber)
 	}
 }
 
 // OnDisconnected called when a subscriber got disconnected.
 func (t *TopologySubscriberEndpoint) OnDisconnected(c shttp.WSSpeaker) {
+	host := c.GetRemoteHost()
+
 	t.Lock()
-	delete(t.subscribers, c.GetRemoteHost())
+	if subscriber, found := t.subscribers[host]; found {
+		// abort the query of the subscriber, if running
+		subscriber.cancel()
+		delete(t.subscribers, host)
+	}
 	t.Unlock()
 }
 
@@ -141,9 +170,7 @@ func (t *TopologySubscriberEndpoint) OnWSStructMessage(c shttp.WSSpeaker, msg *s
 
 			logging.GetLogger().Infof("Client %s subscribed with filter %s", host, syncMsg.GremlinFilter)
 			result = subscriber.graph
-			t.Lock()
-			t.subscribers[host] = subscriber
-			t.Unlock()
+			t.addSubscriber(host, subscriber)
 		}
 
 		reply := msg.Reply(result, graph.SyncReplyMsgType, status)
@@ -163,7 +190,7 @@ func (t *TopologySubscriberEndpoint) notifyClients(msg *shttp.WSStructMessage) {
 		t.RUnlock()
 
 		if found {
-			g, err := t.getGraph(subscriber.gremlinFilter, subscriber.ts, false)
+			g, err := t.getGraph(subscriber.ctx, subscriber.gremlinFilter, subscriber.ts, false)
 			if err != nil {
 				logging.GetLogger().Error(err)
 				continue
@@ -27,6 +27,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/google/gopacket/layers"
 	valid "gopkg.in/validator.v2"
@@ -69,6 +70,26 @@ var (
 	LayerKeyModeNotValid = func() error {
 		return valid.TextErr{Err: errors.New("Not a valid layer key mode")}
 	}
+
+	// PositiveIntNotValid validator
+	PositiveIntNotValid = func() error {
+		return valid.TextErr{Err: errors.New("Not a positive integer")}
+	}
+
+	// EvictionPolicyNotValid validator
+	EvictionPolicyNotValid = func() error {
+		return valid.TextErr{Err: errors.New("Not a valid eviction policy")}
+	}
+
+	// SamplingModeNotValid validator
+	SamplingModeNotValid = func() error {
+		return valid.TextErr{Err: errors.New("Not a valid sampling mode")}
+	}
+
+	// DurationNotValid validator
+	DurationNotValid = func() error {
+		return valid.TextErr{Err: errors.New("Not a valid positive duration")}
+	}
 )
 
 func isIP(v interface{}, param string) error {
@@ -103,6 +124,13 @@ func isGremlinExpr(v interface{}, param string) error {
 	return nil
 }
 
+func isOptionalGremlinExpr(v interface{}, param string) error {
+	if query, ok := v.(string); ok && query == "" {
+		return nil
+	}
+	return isGremlinExpr(v, param)
+}
+
 func isBPFFilter(v interface{}, param string) error {
 	bpfFilter, ok := v.(string)
 	if !ok {
@@ -154,6 +182,68 @@ func isValidLayerKeyMode(v interface{}, param string) error {
 	return nil
 }
 
+func isPositiveInt(v interface{}, param string) error {
+	switch i := v.(type) {
+	case int:
+		if i >= 0 {
+			return nil
+		}
+	case int64:
+		if i >= 0 {
+			return nil
+		}
+	}
+	return PositiveIntNotValid()
+}
+
+func isValidEvictionPolicy(v interface{}, param string) error {
+	name, ok := v.(string)
+	if !ok {
+		return EvictionPolicyNotValid()
+	}
+
+	if len(name) == 0 {
+		return nil
+	}
+
+	if _, err := flow.EvictionPolicyByName(name); err != nil {
+		return EvictionPolicyNotValid()
+	}
+	return nil
+}
+
+func isValidSamplingMode(v interface{}, param string) error {
+	name, ok := v.(string)
+	if !ok {
+		return SamplingModeNotValid()
+	}
+
+	if len(name) == 0 {
+		return nil
+	}
+
+	if _, err := flow.SamplingModeByName(name); err != nil {
+		return SamplingModeNotValid()
+	}
+	return nil
+}
+
+func isValidDuration(v interface{}, param string) error {
+	s, ok := v.(string)
+	if !ok {
+		return DurationNotValid()
+	}
+
+	if len(s) == 0 {
+		return nil
+	}
+
+	if d, err := time.ParseDuration(s); err != nil || d < 0 {
+		return DurationNotValid()
+	}
+	return nil
+}
+
 func isValidWorkflow(v interface{}, param string) error {
 	// Check that `v` is valid JS code that returns
 	// a promise
@@ -176,10 +266,15 @@ func Validate(value interface{}) error {
 func init() {
 	skydiveValidator.SetValidationFunc("isIP", isIP)
 	skydiveValidator.SetValidationFunc("isGremlinExpr", isGremlinExpr)
+	skydiveValidator.SetValidationFunc("isOptionalGremlinExpr", isOptionalGremlinExpr)
 	skydiveValidator.SetValidationFunc("isBPFFilter", isBPFFilter)
 	skydiveValidator.SetValidationFunc("isValidCaptureHeaderSize", isValidCaptureHeaderSize)
 	skydiveValidator.SetValidationFunc("isValidRawPacketLimit", isValidRawPacketLimit)
 	skydiveValidator.SetValidationFunc("isValidLayerKeyMode", isValidLayerKeyMode)
+	skydiveValidator.SetValidationFunc("isPositiveInt", isPositiveInt)
+	skydiveValidator.SetValidationFunc("isValidEvictionPolicy", isValidEvictionPolicy)
+	skydiveValidator.SetValidationFunc("isValidSamplingMode", isValidSamplingMode)
 	skydiveValidator.SetValidationFunc("isValidWorkflow", isValidWorkflow)
+	skydiveValidator.SetValidationFunc("isValidDuration", isValidDuration)
 	skydiveValidator.SetTag("valid")
 }